@@ -39,17 +39,22 @@ func parseZipperAddr(opts *serverless.Options) error {
 		return nil
 	}
 
-	splits := strings.Split(url, ":")
-	if len(splits) != 2 {
-		return fmt.Errorf(`the format of url "%s" is incorrect, it should be "host:port", e.g. localhost:9000`, url)
-	}
+	// the url can be a comma-separated list of endpoints.
+	endpoints := []string{}
+	for _, endpoint := range strings.Split(url, ",") {
+		splits := strings.Split(strings.TrimSpace(endpoint), ":")
+		if len(splits) != 2 {
+			return fmt.Errorf(`the format of url "%s" is incorrect, it should be "host:port", e.g. localhost:9000`, url)
+		}
 
-	port, err := strconv.Atoi(splits[1])
-	if err != nil {
-		return fmt.Errorf("%s: invalid port: %s", url, splits[1])
+		port, err := strconv.Atoi(splits[1])
+		if err != nil {
+			return fmt.Errorf("%s: invalid port: %s", url, splits[1])
+		}
+		endpoints = append(endpoints, fmt.Sprintf("%s:%d", splits[0], port))
 	}
 
-	opts.ZipperAddr = fmt.Sprintf("%s:%d", splits[0], port)
+	opts.ZipperAddr = strings.Join(endpoints, ",")
 
 	return nil
 }
@@ -0,0 +1,131 @@
+/*
+Copyright © 2021 Allegro Networks
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/acl"
+	"github.com/yomorun/yomo/core/ylog"
+	"github.com/yomorun/yomo/pkg/log"
+	"github.com/yomorun/yomo/pkg/recording"
+)
+
+var (
+	replayZipperAddr string
+	replayCredential string
+	replayName       string
+	replaySpeed      float64
+	replayTags       []string
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <RECORDING_FILE>",
+	Short: "Replay the data frames recorded by a YoMo-Zipper",
+	Long: `Replay the data frames recorded by a YoMo-Zipper.
+It connects to the zipper as a source and writes the recorded data frames with their tags, metadata and payloads,
+the intervals between the frames are kept and scaled by the speed.`,
+	Example: `  yomo replay recording.yomo --zipper localhost:9000 --speed 2 --tag 0x33`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if replaySpeed < 0 {
+			log.FailureStatusEvent(os.Stdout, "the speed should not be negative")
+			return
+		}
+		filter, err := replayFilter(replayTags)
+		if err != nil {
+			log.FailureStatusEvent(os.Stdout, err.Error())
+			return
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.FailureStatusEvent(os.Stdout, err.Error())
+			return
+		}
+		defer file.Close()
+
+		reader, err := recording.NewReader(file)
+		if err != nil {
+			log.FailureStatusEvent(os.Stdout, err.Error())
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		source := core.NewClient(
+			replayName,
+			replayZipperAddr,
+			core.ClientTypeSource,
+			core.WithCredential(replayCredential),
+			core.WithLogger(ylog.Default()),
+		)
+		if err := source.Connect(ctx); err != nil {
+			log.FailureStatusEvent(os.Stdout, err.Error())
+			return
+		}
+		defer source.Close()
+
+		log.InfoStatusEvent(os.Stdout, "Replaying %s to the zipper %s, press Ctrl+C to stop...", args[0], replayZipperAddr)
+		n, err := recording.Replay(ctx, reader, source, replaySpeed, filter)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.FailureStatusEvent(os.Stdout, "%d frames replayed: %s", n, err.Error())
+			return
+		}
+		log.SuccessStatusEvent(os.Stdout, "%d frames replayed", n)
+	},
+}
+
+// replayFilter returns the filter that matches the records of the tags, it returns nil if the tags are empty.
+func replayFilter(tags []string) (func(recording.Record) bool, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	ranges := make([][2]uint32, 0, len(tags))
+	for _, spec := range tags {
+		min, max, err := acl.ParseTagRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, [2]uint32{min, max})
+	}
+	return func(r recording.Record) bool {
+		for _, t := range ranges {
+			if r.Tag >= t[0] && r.Tag <= t[1] {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVarP(&replayZipperAddr, "zipper", "z", "localhost:9000", "the address of zipper to replay to")
+	replayCmd.Flags().StringVarP(&replayCredential, "credential", "d", "", "the client credential, e.g. token:<TOKEN>")
+	replayCmd.Flags().StringVarP(&replayName, "name", "n", "yomo-replay", "the name of the replaying source")
+	replayCmd.Flags().Float64VarP(&replaySpeed, "speed", "s", 1, "the replay speed, 1 is the original speed, 2 is twice as fast, 0 is as fast as possible")
+	replayCmd.Flags().StringArrayVarP(&replayTags, "tag", "t", nil, "the tag or tag range to replay, e.g. 0x33 or 0x30-0x3f, can be repeated")
+}
@@ -64,13 +64,12 @@ var serveCmd = &cobra.Command{
 		// listening address.
 		listenAddr := fmt.Sprintf("%s:%d", conf.Host, conf.Port)
 
-		options := []yomo.ZipperOption{}
-		tokenString := ""
-		if _, ok := conf.Auth["type"]; ok {
-			if tokenString, ok = conf.Auth["token"]; ok {
-				options = append(options, yomo.WithAuth("token", tokenString))
-			}
+		options, err := yomo.OptionsFromConfig(conf)
+		if err != nil {
+			log.FailureStatusEvent(os.Stdout, err.Error())
+			return
 		}
+		tokenString := conf.Auth["token"]
 		// check llm bridge server config
 		// parse the llm bridge config
 		bridgeConf := conf.Bridge
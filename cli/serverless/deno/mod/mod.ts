@@ -9,12 +9,14 @@ import { loadSync } from "https://deno.land/std/dotenv/mod.ts";
 export class Context {
   tag: number;
   input: Uint8Array;
+  state: State;
   private writer: Writer;
 
-  constructor(tag: number, input: Uint8Array, writer: Writer) {
+  constructor(tag: number, input: Uint8Array, conn: Reader & Writer) {
     this.tag = tag;
     this.input = input;
-    this.writer = writer;
+    this.state = new State(conn);
+    this.writer = conn;
   }
 
   async write(tag: number, data: Uint8Array) {
@@ -23,6 +25,87 @@ export class Context {
   }
 }
 
+// the tag of the state requests, the response is read from the conn.
+const STATE_TAG = 0xFFFFFFFF;
+
+// State is the key-value state of the sfn, it is kept by yomo.
+// The ttl is in milliseconds, zero means never expires.
+export class State {
+  private conn: Reader & Writer;
+
+  constructor(conn: Reader & Writer) {
+    this.conn = conn;
+  }
+
+  async get(key: string): Promise<Uint8Array | null> {
+    const resp = await this.do({ Op: "get", Key: key });
+    return resp.OK ? decodeBase64(resp.Value) : null;
+  }
+
+  async put(key: string, value: Uint8Array, ttl = 0) {
+    await this.do({ Op: "put", Key: key, Value: encodeBase64(value), TTL: ttl });
+  }
+
+  async delete(key: string) {
+    await this.do({ Op: "delete", Key: key });
+  }
+
+  // compareAndSwap sets the value of the key to value if the current value is old,
+  // the null old means that the key does not exist.
+  async compareAndSwap(
+    key: string,
+    old: Uint8Array | null,
+    value: Uint8Array,
+    ttl = 0,
+  ): Promise<boolean> {
+    const resp = await this.do({
+      Op: "cas",
+      Key: key,
+      Old: old == null ? null : encodeBase64(old),
+      Value: encodeBase64(value),
+      TTL: ttl,
+    });
+    return resp.OK;
+  }
+
+  private async do(
+    req: Record<string, unknown>,
+  ): Promise<{ Value: string | null; OK: boolean; Error: string }> {
+    await this.conn.write(numberToBytes(STATE_TAG));
+    await writeData(this.conn, new TextEncoder().encode(JSON.stringify(req)));
+
+    const data = await readData(this.conn);
+    if (data == null) {
+      throw new Error("state: connection closed");
+    }
+    const resp = JSON.parse(new TextDecoder().decode(data));
+    if (resp.Error) {
+      throw new Error(resp.Error);
+    }
+    return resp;
+  }
+}
+
+function encodeBase64(data: Uint8Array): string {
+  let s = "";
+  for (const b of data) {
+    s += String.fromCharCode(b);
+  }
+  return btoa(s);
+}
+
+function decodeBase64(s: string | null): Uint8Array {
+  if (s == null) {
+    return new Uint8Array();
+  }
+  const bin = atob(s);
+  const data = new Uint8Array(bin.length);
+  for (let i = 0; i < bin.length; i++) {
+    data[i] = bin.charCodeAt(i);
+  }
+  return data;
+}
+
 const VARNUM_OPTIONS: VarnumOptions = {
   "dataType": "uint32",
   "endian": "little",
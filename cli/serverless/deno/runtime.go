@@ -14,9 +14,26 @@ import (
 	"github.com/yomorun/yomo"
 	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/pkg/file"
+	"github.com/yomorun/yomo/pkg/state"
 	"github.com/yomorun/yomo/serverless"
 )
 
+// stateTag is the tag of the state requests written by the deno sfn, the response is written back to the conn.
+const stateTag = 0xFFFFFFFF
+
+func handleState(conn net.Conn, s serverless.State, req []byte) error {
+	resp, err := state.Do(s, req)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(conn, binary.LittleEndian, uint32(len(resp)))
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(resp)
+	return err
+}
+
 func listen(path string) (*net.UnixListener, error) {
 	err := file.Remove(path)
 	if err != nil {
@@ -148,6 +165,15 @@ func startSfn(name string, zipperAddr string, credential string, observed []fram
 					return
 				}
 
+				if tag == stateTag {
+					err = handleState(conn, ctx.State(), data)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					continue
+				}
+
 				ctx.Write(tag, data)
 			}
 		},
@@ -23,6 +23,11 @@ const (
 	WasmFuncContextTag      = "yomo_context_tag"
 	WasmFuncContextData     = "yomo_context_data"
 	WasmFuncContextDataSize = "yomo_context_data_size"
+
+	// WasmFuncWriteWithMetadata writes the data with the json encoded metadata
+	WasmFuncWriteWithMetadata = "yomo_write_with_metadata"
+	// WasmFuncContextMetadataKeys copies the json encoded keys of the incoming metadata
+	WasmFuncContextMetadataKeys = "yomo_context_metadata_keys"
 )
 
 // Runtime is the abstract interface for wasm runtime
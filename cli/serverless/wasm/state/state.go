@@ -0,0 +1,17 @@
+// Package state provides the key-value state of the sfn to the wasm guests.
+package state
+
+import (
+	"github.com/yomorun/yomo/pkg/state"
+	"github.com/yomorun/yomo/serverless"
+)
+
+const (
+	// WasmFuncState is the host function that handles the state requests of the guest
+	WasmFuncState = "yomo_state"
+)
+
+// Do handles the json encoded state request and returns the json encoded state response
+func Do(s serverless.State, reqBuf []byte) ([]byte, error) {
+	return state.Do(s, reqBuf)
+}
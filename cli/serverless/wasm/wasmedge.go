@@ -5,6 +5,7 @@ package wasm
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/second-state/WasmEdge-go/wasmedge"
 	wasmhttp "github.com/yomorun/yomo/cli/serverless/wasm/http"
+	wasmstate "github.com/yomorun/yomo/cli/serverless/wasm/state"
 	"github.com/yomorun/yomo/serverless"
 )
 
@@ -61,6 +63,19 @@ func (r *wasmEdgeRuntime) Init(wasmFile string) error {
 	),
 		r.write, nil, 0)
 	r.module.AddFunction(WasmFuncWrite, writeFunc)
+	// write with metadata
+	writeWithMetadataFunc := wasmedge.NewFunction(wasmedge.NewFunctionType(
+		[]wasmedge.ValType{
+			wasmedge.ValType_I32,
+			wasmedge.ValType_I32,
+			wasmedge.ValType_I32,
+			wasmedge.ValType_I32,
+			wasmedge.ValType_I32,
+		},
+		[]wasmedge.ValType{wasmedge.ValType_I32},
+	),
+		r.writeWithMetadata, nil, 0)
+	r.module.AddFunction(WasmFuncWriteWithMetadata, writeWithMetadataFunc)
 	// context tag
 	contextTagFunc := wasmedge.NewFunction(wasmedge.NewFunctionType(
 		[]wasmedge.ValType{},
@@ -79,6 +94,14 @@ func (r *wasmEdgeRuntime) Init(wasmFile string) error {
 		[]wasmedge.ValType{},
 		[]wasmedge.ValType{wasmedge.ValType_I32}), r.contextDataSize, nil, 0)
 	r.module.AddFunction(WasmFuncContextDataSize, contextDataSizeFunc)
+	// context metadata keys
+	contextMetadataKeysFunc := wasmedge.NewFunction(wasmedge.NewFunctionType(
+		[]wasmedge.ValType{
+			wasmedge.ValType_I32,
+			wasmedge.ValType_I32,
+		},
+		[]wasmedge.ValType{wasmedge.ValType_I32}), r.contextMetadataKeys, nil, 0)
+	r.module.AddFunction(WasmFuncContextMetadataKeys, contextMetadataKeysFunc)
 	// http
 	httpSendFunc := wasmedge.NewFunction(
 		wasmedge.NewFunctionType(
@@ -92,6 +115,19 @@ func (r *wasmEdgeRuntime) Init(wasmFile string) error {
 		),
 		r.httpSend, nil, 0)
 	r.module.AddFunction(wasmhttp.WasmFuncHTTPSend, httpSendFunc)
+	// state
+	stateDoFunc := wasmedge.NewFunction(
+		wasmedge.NewFunctionType(
+			[]wasmedge.ValType{
+				wasmedge.ValType_I32,
+				wasmedge.ValType_I32,
+				wasmedge.ValType_I32,
+				wasmedge.ValType_I32,
+			},
+			[]wasmedge.ValType{wasmedge.ValType_I32},
+		),
+		r.stateDo, nil, 0)
+	r.module.AddFunction(wasmstate.WasmFuncState, stateDoFunc)
 
 	err := r.vm.RegisterModule(r.module)
 	if err != nil {
@@ -199,7 +235,25 @@ func (r *wasmEdgeRuntime) contextData(
 	callframe *wasmedge.CallingFrame,
 	params []any,
 ) ([]any, wasmedge.Result) {
-	data := r.serverlessCtx.Data()
+	return r.copyToGuest(callframe, params, r.serverlessCtx.Data())
+}
+
+func (r *wasmEdgeRuntime) contextMetadataKeys(
+	_ any,
+	callframe *wasmedge.CallingFrame,
+	params []any,
+) ([]any, wasmedge.Result) {
+	keys, err := json.Marshal(r.serverlessCtx.MetadataKeys())
+	if err != nil {
+		log.Printf("encode metadata keys: %v\n", err)
+		return []any{0}, wasmedge.Result_Fail
+	}
+	return r.copyToGuest(callframe, params, keys)
+}
+
+// copyToGuest copies the data to the guest buffer of params[0] with the size of params[1],
+// the size of the data is returned, the data is not copied if the buffer is too small.
+func (r *wasmEdgeRuntime) copyToGuest(callframe *wasmedge.CallingFrame, params []any, data []byte) ([]any, wasmedge.Result) {
 	dataLen := int32(len(data))
 	limit := params[1].(int32)
 	if dataLen > limit {
@@ -245,11 +299,64 @@ func (r *wasmEdgeRuntime) write(
 	return []any{0}, wasmedge.Result_Success
 }
 
+func (r *wasmEdgeRuntime) writeWithMetadata(
+	_ any,
+	callframe *wasmedge.CallingFrame,
+	params []any,
+) ([]any, wasmedge.Result) {
+	tag := params[0].(int32)
+	pointer := params[1].(int32)
+	length := params[2].(int32)
+	mdPointer := params[3].(int32)
+	mdLength := params[4].(int32)
+	mem := callframe.GetMemoryByIndex(0)
+	output, err := mem.GetData(uint(pointer), uint(length))
+	if err != nil {
+		return []any{1}, wasmedge.Result_Fail
+	}
+	buf := make([]byte, length)
+	copy(buf, output)
+	mdOutput, err := mem.GetData(uint(mdPointer), uint(mdLength))
+	if err != nil {
+		return []any{1}, wasmedge.Result_Fail
+	}
+	var md map[string]string
+	if err := json.Unmarshal(mdOutput, &md); err != nil {
+		log.Printf("decode metadata: %v\n", err)
+		return []any{1}, wasmedge.Result_Fail
+	}
+	if err := r.serverlessCtx.WriteWithMetadata(uint32(tag), buf, md); err != nil {
+		return []any{2}, wasmedge.Result_Fail
+	}
+	return []any{0}, wasmedge.Result_Success
+}
+
 // httpSend sends http request
 func (r *wasmEdgeRuntime) httpSend(
 	_ any,
 	callframe *wasmedge.CallingFrame,
 	params []any,
+) ([]any, wasmedge.Result) {
+	return r.hostCall(callframe, params, "[HTTP] Send", wasmhttp.Do)
+}
+
+// stateDo handles the state request and returns the response
+func (r *wasmEdgeRuntime) stateDo(
+	_ any,
+	callframe *wasmedge.CallingFrame,
+	params []any,
+) ([]any, wasmedge.Result) {
+	do := func(reqBuf []byte) ([]byte, error) { return wasmstate.Do(r.serverlessCtx.State(), reqBuf) }
+	return r.hostCall(callframe, params, "[State] Do", do)
+}
+
+// hostCall passes the request buffer of the guest to the do function,
+// and writes the response buffer to the memory allocated by the guest.
+func (r *wasmEdgeRuntime) hostCall(
+	callframe *wasmedge.CallingFrame,
+	params []any,
+	name string,
+	do func(reqBuf []byte) ([]byte, error),
 ) ([]any, wasmedge.Result) {
 	reqPtr := params[0].(int32)
 	reqSize := params[1].(int32)
@@ -258,9 +365,9 @@ func (r *wasmEdgeRuntime) httpSend(
 	if err != nil {
 		return []any{1}, wasmedge.Result_Fail
 	}
-	respBuf, err := wasmhttp.Do(reqBuf)
+	respBuf, err := do(reqBuf)
 	if err != nil {
-		log.Printf("[HTTP] Send: %s\n", err)
+		log.Printf("%s: %s\n", name, err)
 		return []any{2}, wasmedge.Result_Fail
 	}
 	respPtr := params[2].(int32)
@@ -268,14 +375,14 @@ func (r *wasmEdgeRuntime) httpSend(
 	// write response
 	allocFn := r.vm.GetActiveModule().FindFunction("yomo_alloc")
 	if allocFn == nil {
-		log.Printf("[HTTP] Send: yomo_alloc not found\n")
+		log.Printf("%s: yomo_alloc not found\n", name)
 		return []any{3}, wasmedge.Result_Fail
 	}
 	// alloc memory
 	dataLen := len(respBuf)
 	allocResult, err := r.vm.Execute("yomo_alloc", int32(dataLen))
 	if err != nil {
-		log.Printf("[HTTP] Send: yomo_alloc error: %s\n", err)
+		log.Printf("%s: yomo_alloc error: %s\n", name, err)
 		return []any{4}, wasmedge.Result_Fail
 	}
 	allocPtr := int32(allocResult[0].(int32))
@@ -283,19 +390,19 @@ func (r *wasmEdgeRuntime) httpSend(
 	allocPtrBuf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(allocPtrBuf, uint32(allocPtr))
 	if err := mem.SetData(allocPtrBuf, uint(respPtr), 4); err != nil {
-		log.Printf("[HTTP] Send: set response pointer error: %s\n", err)
+		log.Printf("%s: set response pointer error: %s\n", name, err)
 		return []any{5}, wasmedge.Result_Fail
 	}
 	// set response size
 	allocSizeBuf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(allocSizeBuf, uint32(dataLen))
 	if err := mem.SetData(allocSizeBuf, uint(respSize), 4); err != nil {
-		log.Printf("[HTTP] Send: set response size error: %s\n", err)
+		log.Printf("%s: set response size error: %s\n", name, err)
 		return []any{5}, wasmedge.Result_Fail
 	}
 	// set response data
 	if err := mem.SetData(respBuf, uint(allocPtr), uint(dataLen)); err != nil {
-		log.Printf("[HTTP] Send: set response data error: %s\n", err)
+		log.Printf("%s: set response data error: %s\n", name, err)
 		return []any{5}, wasmedge.Result_Fail
 	}
 	return []any{0}, wasmedge.Result_Success
@@ -5,6 +5,7 @@ package wasm
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/bytecodealliance/wasmtime-go/v9"
 	wasmhttp "github.com/yomorun/yomo/cli/serverless/wasm/http"
+	wasmstate "github.com/yomorun/yomo/cli/serverless/wasm/state"
 
 	"github.com/yomorun/yomo/serverless"
 )
@@ -76,14 +78,26 @@ func (r *wasmtimeRuntime) Init(wasmFile string) error {
 	if err := r.linker.FuncWrap("env", WasmFuncContextDataSize, r.contextDataSize); err != nil {
 		return fmt.Errorf("linker.FuncWrap: %s %v", WasmFuncContextDataSize, err)
 	}
+	// context metadata keys
+	if err := r.linker.FuncWrap("env", WasmFuncContextMetadataKeys, r.contextMetadataKeys); err != nil {
+		return fmt.Errorf("linker.FuncWrap: %s %v", WasmFuncContextMetadataKeys, err)
+	}
 	// write
 	if err := r.linker.FuncWrap("env", WasmFuncWrite, r.write); err != nil {
 		return fmt.Errorf("linker.FuncWrap: %s %v", WasmFuncWrite, err)
 	}
+	// write with metadata
+	if err := r.linker.FuncWrap("env", WasmFuncWriteWithMetadata, r.writeWithMetadata); err != nil {
+		return fmt.Errorf("linker.FuncWrap: %s %v", WasmFuncWriteWithMetadata, err)
+	}
 	// http
 	if err := r.linker.FuncWrap("env", wasmhttp.WasmFuncHTTPSend, r.httpSend); err != nil {
 		return fmt.Errorf("linker.FuncWrap: %s %v", wasmhttp.WasmFuncHTTPSend, err)
 	}
+	// state
+	if err := r.linker.FuncWrap("env", wasmstate.WasmFuncState, r.stateDo); err != nil {
+		return fmt.Errorf("linker.FuncWrap: %s %v", wasmstate.WasmFuncState, err)
+	}
 	// instantiate
 	instance, err := r.linker.Instantiate(r.store, module)
 	if err != nil {
@@ -158,7 +172,21 @@ func (r *wasmtimeRuntime) contextTag() int32 {
 }
 
 func (r *wasmtimeRuntime) contextData(pointer int32, limit int32) (dataLen int32) {
-	data := r.serverlessCtx.Data()
+	return r.copyToGuest(pointer, limit, r.serverlessCtx.Data())
+}
+
+func (r *wasmtimeRuntime) contextMetadataKeys(pointer int32, limit int32) (dataLen int32) {
+	keys, err := json.Marshal(r.serverlessCtx.MetadataKeys())
+	if err != nil {
+		log.Printf("encode metadata keys: %v\n", err)
+		return 0
+	}
+	return r.copyToGuest(pointer, limit, keys)
+}
+
+// copyToGuest copies the data to the guest buffer, the size of the data is returned,
+// the data is not copied if the buffer is too small.
+func (r *wasmtimeRuntime) copyToGuest(pointer int32, limit int32, data []byte) (dataLen int32) {
 	dataLen = int32(len(data))
 	if dataLen > limit {
 		return
@@ -186,6 +214,22 @@ func (r *wasmtimeRuntime) write(tag int32, pointer int32, length int32) int32 {
 	return 0
 }
 
+func (r *wasmtimeRuntime) writeWithMetadata(tag int32, pointer int32, length int32, mdPointer int32, mdLength int32) int32 {
+	mem := r.memory.UnsafeData(r.store)
+	buf := make([]byte, length)
+	copy(buf, mem[pointer:pointer+length])
+
+	var md map[string]string
+	if err := json.Unmarshal(mem[mdPointer:mdPointer+mdLength], &md); err != nil {
+		log.Printf("decode metadata: %v\n", err)
+		return 1
+	}
+	if err := r.serverlessCtx.WriteWithMetadata(uint32(tag), buf, md); err != nil {
+		return 2
+	}
+	return 0
+}
+
 // httpSend sends a HTTP request and returns the response
 func (r *wasmtimeRuntime) httpSend(
 	caller *wasmtime.Caller,
@@ -193,27 +237,53 @@ func (r *wasmtimeRuntime) httpSend(
 	reqSize int32,
 	respPtr int32,
 	respSize int32,
+) int32 {
+	return r.hostCall(caller, "[HTTP] Send", wasmhttp.Do, reqPtr, reqSize, respPtr, respSize)
+}
+
+// stateDo handles the state request and returns the response
+func (r *wasmtimeRuntime) stateDo(
+	caller *wasmtime.Caller,
+	reqPtr int32,
+	reqSize int32,
+	respPtr int32,
+	respSize int32,
+) int32 {
+	do := func(reqBuf []byte) ([]byte, error) { return wasmstate.Do(r.serverlessCtx.State(), reqBuf) }
+	return r.hostCall(caller, "[State] Do", do, reqPtr, reqSize, respPtr, respSize)
+}
+
+// hostCall passes the request buffer of the guest to the do function,
+// and writes the response buffer to the memory allocated by the guest.
+func (r *wasmtimeRuntime) hostCall(
+	caller *wasmtime.Caller,
+	name string,
+	do func(reqBuf []byte) ([]byte, error),
+	reqPtr int32,
+	reqSize int32,
+	respPtr int32,
+	respSize int32,
 ) int32 {
 	if r.memory == nil {
-		log.Printf("[HTTP] Send: memory is nil\n")
+		log.Printf("%s: memory is nil\n", name)
 		return 1
 	}
 	// request
 	reqBuf := r.memory.UnsafeData(r.store)[reqPtr : reqPtr+reqSize]
-	respBuf, err := wasmhttp.Do(reqBuf)
+	respBuf, err := do(reqBuf)
 	if err != nil {
-		log.Printf("[HTTP] Send: %s\n", err)
+		log.Printf("%s: %s\n", name, err)
 		return 2
 	}
 	// write response
 	allocFn := caller.GetExport("yomo_alloc")
 	if allocFn == nil {
-		log.Printf("[HTTP] Send: yomo_alloc not found\n")
+		log.Printf("%s: yomo_alloc not found\n", name)
 		return 3
 	}
 	allocResult, err := allocFn.Func().Call(r.store, len(respBuf))
 	if err != nil {
-		log.Printf("[HTTP] Send: yomo_alloc error: %s\n", err)
+		log.Printf("%s: yomo_alloc error: %s\n", name, err)
 		return 4
 	}
 	allocPtr32 := allocResult.(int32)
@@ -3,6 +3,7 @@ package wasm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -88,6 +89,10 @@ func (r *wazeroRuntime) Init(wasmFile string) error {
 		NewFunctionBuilder().
 		WithGoModuleFunction(api.GoModuleFunc(r.writeWithTarget), []api.ValueType{i32, i32, i32, i32, i32}, []api.ValueType{i32}).
 		Export(WasmFuncWriteWithTarget).
+		// write with metadata
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(r.writeWithMetadata), []api.ValueType{i32, i32, i32, i32, i32}, []api.ValueType{i32}).
+		Export(WasmFuncWriteWithMetadata).
 		// context tag
 		NewFunctionBuilder().
 		WithGoFunction(api.GoFunc(r.contextTag), []api.ValueType{}, []api.ValueType{i32}).
@@ -99,9 +104,15 @@ func (r *wazeroRuntime) Init(wasmFile string) error {
 		// context data size
 		NewFunctionBuilder().
 		WithGoFunction(api.GoFunc(r.contextDataSize), []api.ValueType{}, []api.ValueType{i32}).
-		Export(WasmFuncContextDataSize)
+		Export(WasmFuncContextDataSize).
+		// context metadata keys
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(r.contextMetadataKeys), []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Export(WasmFuncContextMetadataKeys)
 	// http
 	host.ExportHTTPHostFuncs(builder)
+	// state
+	host.ExportStateHostFuncs(builder, func() serverless.State { return r.serverlessCtx.State() })
 
 	// Instantiate
 	_, err = builder.Instantiate(r.ctx)
@@ -279,14 +290,67 @@ func (r *wazeroRuntime) writeWithTarget(ctx context.Context, m api.Module, stack
 	stack[0] = 0
 }
 
+func (r *wazeroRuntime) writeWithMetadata(ctx context.Context, m api.Module, stack []uint64) {
+	tag := uint32(stack[0])
+
+	pointer := uint32(stack[1])
+	length := uint32(stack[2])
+
+	mdPointer := uint32(stack[3])
+	mdLength := uint32(stack[4])
+
+	output, ok := m.Memory().Read(pointer, length)
+	if !ok {
+		log.Printf("Memory.Read(%d, %d) out of range\n", pointer, length)
+		stack[0] = 1
+		return
+	}
+	buf := make([]byte, length)
+	copy(buf, output)
+
+	mdOutput, ok := m.Memory().Read(mdPointer, mdLength)
+	if !ok {
+		log.Printf("Memory.Read(%d, %d) out of range\n", mdPointer, mdLength)
+		stack[0] = 1
+		return
+	}
+	var md map[string]string
+	if err := json.Unmarshal(mdOutput, &md); err != nil {
+		log.Printf("decode metadata: %v\n", err)
+		stack[0] = 1
+		return
+	}
+
+	if err := r.serverlessCtx.WriteWithMetadata(tag, buf, md); err != nil {
+		stack[0] = 2
+		return
+	}
+	stack[0] = 0
+}
+
 func (r *wazeroRuntime) contextTag(ctx context.Context, stack []uint64) {
 	stack[0] = uint64(r.serverlessCtx.Tag())
 }
 
 func (r *wazeroRuntime) contextData(ctx context.Context, m api.Module, stack []uint64) {
+	r.copyToGuest(m, stack, r.serverlessCtx.Data())
+}
+
+func (r *wazeroRuntime) contextMetadataKeys(ctx context.Context, m api.Module, stack []uint64) {
+	keys, err := json.Marshal(r.serverlessCtx.MetadataKeys())
+	if err != nil {
+		log.Printf("encode metadata keys: %v\n", err)
+		stack[0] = 0
+		return
+	}
+	r.copyToGuest(m, stack, keys)
+}
+
+// copyToGuest copies the data to the guest buffer of stack[0] with the size of stack[1],
+// the size of the data is returned, the data is not copied if the buffer is too small.
+func (r *wazeroRuntime) copyToGuest(m api.Module, stack []uint64, data []byte) {
 	pointer := uint32(stack[0])
 	limit := uint32(stack[1])
-	data := r.serverlessCtx.Data()
 	dataLen := uint32(len(data))
 	if dataLen > limit {
 		stack[0] = uint64(dataLen)
@@ -0,0 +1,59 @@
+package wazero
+
+import (
+	"context"
+	"log"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	wasmstate "github.com/yomorun/yomo/cli/serverless/wasm/state"
+	"github.com/yomorun/yomo/serverless"
+)
+
+// ExportStateHostFuncs exports the state host function, the state is that of the running handler
+func ExportStateHostFuncs(builder wazero.HostModuleBuilder, state func() serverless.State) {
+	builder.
+		NewFunctionBuilder().
+		WithGoModuleFunction(
+			api.GoModuleFunc(func(ctx context.Context, m api.Module, stack []uint64) {
+				StateDo(ctx, m, stack, state())
+			}),
+			[]api.ValueType{
+				api.ValueTypeI32, // reqPtr
+				api.ValueTypeI32, // reqSize
+				api.ValueTypeI32, // respPtr
+				api.ValueTypeI32, // respSize
+			},
+			[]api.ValueType{api.ValueTypeI32}, // ret
+		).
+		Export(wasmstate.WasmFuncState)
+}
+
+// StateDo handles the state request and returns the response
+func StateDo(ctx context.Context, m api.Module, stack []uint64, state serverless.State) {
+	// request
+	reqPtr := uint32(stack[0])
+	reqSize := uint32(stack[1])
+	reqBuf, err := readBuffer(ctx, m, reqPtr, reqSize)
+	if err != nil {
+		log.Printf("[State] Do: get request error: %s\n", err)
+		stack[0] = 1
+		return
+	}
+	// response
+	respBuf, err := wasmstate.Do(state, reqBuf)
+	if err != nil {
+		log.Printf("[State] Do: %s\n", err)
+		stack[0] = 2
+		return
+	}
+	respPtr := uint32(stack[2])
+	respSize := uint32(stack[3])
+	if err := allocateBuffer(ctx, m, respPtr, respSize, respBuf); err != nil {
+		log.Printf("[State] Do: write response error: %s\n", err)
+		stack[0] = 4
+		return
+	}
+	// return
+	stack[0] = 0
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2021 Allegro Networks
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/yomorun/yomo/pkg/admin"
+	"github.com/yomorun/yomo/pkg/log"
+)
+
+var (
+	tapAdminAddr  string
+	tapCredential string
+	tapTags       []string
+	tapSources    []string
+	tapMetadata   []string
+	tapFormat     string
+)
+
+// tapCmd represents the tap command
+var tapCmd = &cobra.Command{
+	Use:   "tap",
+	Short: "Inspect the data frames flowing through a YoMo-Zipper",
+	Long: `Inspect the data frames flowing through a YoMo-Zipper.
+It connects to the admin api of the zipper and prints the matching data frames as they are routed,
+the tap is not a subscriber, so it never affects the routing.`,
+	Example: `  yomo tap --admin http://127.0.0.1:9092 --credential <CREDENTIAL> --tag 0x33 --tag 0x40-0x4f --format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if tapCredential == "" {
+			tapCredential = os.Getenv("YOMO_ADMIN_CREDENTIAL")
+		}
+		if tapFormat != "hex" && tapFormat != "utf8" && tapFormat != "json" {
+			log.FailureStatusEvent(os.Stdout, "unknown format: %s, it should be hex, utf8 or json", tapFormat)
+			return
+		}
+
+		filter := admin.TapFilter{Tags: tapTags, Sources: tapSources, Metadata: map[string]string{}}
+		for _, kv := range tapMetadata {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				log.FailureStatusEvent(os.Stdout, "invalid metadata filter: %s, it should be key=value", kv)
+				return
+			}
+			filter.Metadata[k] = v
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		log.InfoStatusEvent(os.Stdout, "Tapping the zipper %s, press Ctrl+C to stop...", tapAdminAddr)
+		err := admin.Tap(ctx, tapAdminAddr, tapCredential, filter, func(e admin.TapEvent) error {
+			fmt.Fprint(os.Stdout, formatTapEvent(e, tapFormat))
+			return nil
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.FailureStatusEvent(os.Stdout, err.Error())
+		}
+	},
+}
+
+// formatTapEvent formats the event as a header line followed by the payload.
+func formatTapEvent(e admin.TapEvent, format string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s tag=%d(0x%x) from=%s(%s)", e.Time.Format("15:04:05.000"), e.Tag, e.Tag, e.Source, e.SourceType)
+	if len(e.Metadata) > 0 {
+		md, _ := json.Marshal(e.Metadata)
+		fmt.Fprintf(&b, " metadata=%s", md)
+	}
+	if e.Dropped > 0 {
+		fmt.Fprintf(&b, " dropped=%d", e.Dropped)
+	}
+	b.WriteString("\n")
+
+	switch format {
+	case "hex":
+		b.WriteString(hex.Dump(e.Payload))
+	case "json":
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, e.Payload, "", "  "); err == nil {
+			b.Write(indented.Bytes())
+			b.WriteString("\n")
+			break
+		}
+		// the payload is not json, fallback to utf8.
+		fallthrough
+	default:
+		if utf8.Valid(e.Payload) {
+			b.Write(e.Payload)
+			b.WriteString("\n")
+		} else {
+			b.WriteString(hex.Dump(e.Payload))
+		}
+	}
+
+	return b.String()
+}
+
+func init() {
+	rootCmd.AddCommand(tapCmd)
+
+	tapCmd.Flags().StringVarP(&tapAdminAddr, "admin", "a", "http://127.0.0.1:9092", "the address of zipper admin api")
+	tapCmd.Flags().StringVar(&tapCredential, "credential", "", "the admin credential, defaults to the YOMO_ADMIN_CREDENTIAL environment variable")
+	tapCmd.Flags().StringArrayVarP(&tapTags, "tag", "t", nil, "the tag or tag range to tap, e.g. 0x33 or 0x30-0x3f, can be repeated")
+	tapCmd.Flags().StringArrayVarP(&tapSources, "source", "s", nil, "the name of the writer to tap, can be repeated")
+	tapCmd.Flags().StringArrayVarP(&tapMetadata, "metadata", "m", nil, "the metadata key=value that the frames carry, the value * matches any value, can be repeated")
+	tapCmd.Flags().StringVarP(&tapFormat, "format", "f", "utf8", "the payload format: hex, utf8 or json")
+}
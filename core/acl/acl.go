@@ -0,0 +1,190 @@
+// Package acl defines the publish/subscribe access control of tags.
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+// ACL decides whether a connection can publish or subscribe a tag according to the
+// connection metadata, which comes from `Authentication.Authenticate()`.
+type ACL interface {
+	// AllowPublish reports whether the connection can write data frames with the tag.
+	AllowPublish(md metadata.M, tag frame.Tag) bool
+	// AllowSubscribe reports whether the connection can observe the tag.
+	AllowSubscribe(md metadata.M, tag frame.Tag) bool
+}
+
+// Action is the action that the zipper takes when a data frame violates the ACL.
+type Action string
+
+const (
+	// ActionReject drops the data frame and answers the writer with a RejectedFrame.
+	ActionReject Action = "reject"
+	// ActionDrop drops the data frame silently, only an audit log is written.
+	ActionDrop Action = "drop"
+)
+
+// Config is the ACL config, it looks like:
+//
+//	acl:
+//	  action: reject
+//	  rules:
+//	    - match:
+//	        identity: sfn-upper
+//	      publish: [0x34]
+//	      subscribe: ["0x30-0x33"]
+//	    - match: {}
+//	      subscribe: ["*"]
+type Config struct {
+	// Action is the action when a data frame violates the ACL, it is `reject` or `drop`, default is `reject`.
+	Action Action `yaml:"action"`
+	// Rules are the rules of ACL, a tag is allowed if any rule allows it.
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule grants the connections whose metadata matches to publish and subscribe the tags.
+// The tags are written as a single tag (`16` or `0x10`), a tag range (`0x10-0x20`) or `*` for all tags.
+type Rule struct {
+	// Match is the metadata that the connection must carry, the value `*` only requires the key to exist.
+	// An empty Match matches all connections.
+	Match map[string]string `yaml:"match"`
+	// Publish is the tags that the connection can write.
+	Publish []string `yaml:"publish"`
+	// Subscribe is the tags that the connection can observe.
+	Subscribe []string `yaml:"subscribe"`
+}
+
+// New returns an ACL from the rules. Everything is denied unless a rule allows it.
+func New(rules ...Rule) (ACL, error) {
+	a := &defaultACL{rules: make([]rule, 0, len(rules))}
+
+	for _, r := range rules {
+		publish, err := parseTagRanges(r.Publish)
+		if err != nil {
+			return nil, err
+		}
+		subscribe, err := parseTagRanges(r.Subscribe)
+		if err != nil {
+			return nil, err
+		}
+		a.rules = append(a.rules, rule{match: r.Match, publish: publish, subscribe: subscribe})
+	}
+
+	return a, nil
+}
+
+// ParseAction parses the action, the empty string is parsed as ActionReject.
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case "", ActionReject:
+		return ActionReject, nil
+	case ActionDrop:
+		return ActionDrop, nil
+	default:
+		return "", fmt.Errorf("acl: unknown action: %s", s)
+	}
+}
+
+type tagRange struct{ min, max frame.Tag }
+
+type rule struct {
+	match     map[string]string
+	publish   []tagRange
+	subscribe []tagRange
+}
+
+type defaultACL struct {
+	rules []rule
+}
+
+func (a *defaultACL) AllowPublish(md metadata.M, tag frame.Tag) bool {
+	for _, r := range a.rules {
+		if matchMetadata(r.match, md) && containsTag(r.publish, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *defaultACL) AllowSubscribe(md metadata.M, tag frame.Tag) bool {
+	for _, r := range a.rules {
+		if matchMetadata(r.match, md) && containsTag(r.subscribe, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMetadata(match map[string]string, md metadata.M) bool {
+	for k, want := range match {
+		v, ok := md.Get(k)
+		if !ok {
+			return false
+		}
+		if want != "*" && v != want {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTag(ranges []tagRange, tag frame.Tag) bool {
+	for _, r := range ranges {
+		if tag >= r.min && tag <= r.max {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTagRanges(specs []string) ([]tagRange, error) {
+	ranges := make([]tagRange, 0, len(specs))
+	for _, spec := range specs {
+		min, max, err := ParseTagRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, tagRange{min: min, max: max})
+	}
+	return ranges, nil
+}
+
+// ParseTagRange parses a single tag (`16` or `0x10`), a tag range (`0x10-0x20`) or `*` for all tags.
+func ParseTagRange(spec string) (min, max frame.Tag, err error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "*" {
+		return 0, ^frame.Tag(0), nil
+	}
+	minSpec, maxSpec, isRange := strings.Cut(spec, "-")
+	if min, err = parseTag(minSpec); err != nil {
+		return 0, 0, err
+	}
+	max = min
+	if isRange {
+		if max, err = parseTag(maxSpec); err != nil {
+			return 0, 0, err
+		}
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("acl: invalid tag range: %s", spec)
+	}
+	return min, max, nil
+}
+
+func parseTag(s string) (frame.Tag, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("acl: empty tag")
+	}
+	tag, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("acl: invalid tag: %s", s)
+	}
+	return frame.Tag(tag), nil
+}
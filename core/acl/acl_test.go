@@ -0,0 +1,65 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+	"gopkg.in/yaml.v3"
+)
+
+func TestACL(t *testing.T) {
+	var conf Config
+	err := yaml.Unmarshal([]byte(`
+action: drop
+rules:
+  - match:
+      identity: sfn-upper
+    publish: [0x34]
+    subscribe: ["0x30-0x33"]
+  - match:
+      role: "*"
+    subscribe: ["*"]
+  - publish: [1, "16-17"]
+`), &conf)
+	assert.NoError(t, err)
+
+	action, err := ParseAction(string(conf.Action))
+	assert.NoError(t, err)
+	assert.Equal(t, ActionDrop, action)
+
+	acl, err := New(conf.Rules...)
+	assert.NoError(t, err)
+
+	upper := metadata.M{"identity": "sfn-upper"}
+	assert.True(t, acl.AllowPublish(upper, 0x34))
+	assert.False(t, acl.AllowPublish(upper, 0x35))
+	assert.True(t, acl.AllowSubscribe(upper, 0x30))
+	assert.True(t, acl.AllowSubscribe(upper, 0x33))
+	assert.False(t, acl.AllowSubscribe(upper, 0x34))
+
+	admin := metadata.M{"role": "admin"}
+	assert.True(t, acl.AllowSubscribe(admin, 0x1234))
+
+	// the rule without match matches everyone.
+	anonymous := metadata.M{}
+	assert.True(t, acl.AllowPublish(anonymous, 1))
+	assert.True(t, acl.AllowPublish(anonymous, 17))
+	assert.False(t, acl.AllowPublish(anonymous, 18))
+	assert.False(t, acl.AllowSubscribe(anonymous, 1))
+}
+
+func TestNewError(t *testing.T) {
+	_, err := New(Rule{Publish: []string{"0x20-0x10"}})
+	assert.EqualError(t, err, "acl: invalid tag range: 0x20-0x10")
+
+	_, err = New(Rule{Subscribe: []string{"abc"}})
+	assert.EqualError(t, err, "acl: invalid tag: abc")
+
+	_, err = ParseAction("ignore")
+	assert.EqualError(t, err, "acl: unknown action: ignore")
+
+	action, err := ParseAction("")
+	assert.NoError(t, err)
+	assert.Equal(t, ActionReject, action)
+}
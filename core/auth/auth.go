@@ -2,6 +2,7 @@
 package auth
 
 import (
+	"crypto/tls"
 	"errors"
 	"strings"
 
@@ -23,6 +24,14 @@ type Authentication interface {
 	Name() string
 }
 
+// TLSAuthentication is the Authentication that authenticates the client by the TLS connection,
+// such as the verified client certificate of mTLS.
+type TLSAuthentication interface {
+	Authentication
+	// AuthenticateTLS authenticates the client's credential and the TLS connection state.
+	AuthenticateTLS(payload string, state *tls.ConnectionState) (metadata.M, error)
+}
+
 // Register register authentication
 func Register(authentication Authentication) {
 	auths[authentication.Name()] = authentication
@@ -69,6 +78,12 @@ func (c *Credential) Name() string {
 //
 // If `auths` is nil or empty, It returns true, means authentication is not required.
 func Authenticate(auths map[string]Authentication, hf *frame.HandshakeFrame) (metadata.M, error) {
+	return AuthenticateTLS(auths, hf, nil)
+}
+
+// AuthenticateTLS is like Authenticate, but it passes the TLS connection state to the TLSAuthentication.
+// The state is nil if the connection is not established over TLS.
+func AuthenticateTLS(auths map[string]Authentication, hf *frame.HandshakeFrame, state *tls.ConnectionState) (metadata.M, error) {
 	if auths == nil || len(auths) <= 0 {
 		return metadata.M{}, nil
 	}
@@ -82,5 +97,8 @@ func Authenticate(auths map[string]Authentication, hf *frame.HandshakeFrame) (me
 		return metadata.M{}, errors.New("authentication not found: " + hf.AuthName)
 	}
 
+	if ta, ok := auth.(TLSAuthentication); ok {
+		return ta.AuthenticateTLS(hf.AuthPayload, state)
+	}
 	return auth.Authenticate(hf.AuthPayload)
 }
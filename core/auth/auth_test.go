@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/tls"
 	"errors"
 	"testing"
 
@@ -93,6 +94,29 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
+// mockTLSAuth implement `TLSAuthentication` interface,
+// AuthenticateTLS returns the server name of TLS connection state as metadata.
+type mockTLSAuth struct{ mockAuth }
+
+func (auth mockTLSAuth) AuthenticateTLS(payload string, state *tls.ConnectionState) (metadata.M, error) {
+	if state == nil {
+		return metadata.M{}, errors.New("mock tls auth error")
+	}
+	return metadata.M{"server-name": state.ServerName}, nil
+}
+
+func TestAuthenticateTLS(t *testing.T) {
+	auths := map[string]Authentication{"mock": mockTLSAuth{}}
+	hf := &frame.HandshakeFrame{AuthName: "mock"}
+
+	md, err := AuthenticateTLS(auths, hf, &tls.ConnectionState{ServerName: "yomo"})
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"server-name": "yomo"}, md)
+
+	_, err = Authenticate(auths, hf)
+	assert.EqualError(t, err, "mock tls auth error")
+}
+
 func TestNewCredential(t *testing.T) {
 	type args struct {
 		payload string
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// DefaultBatchLinger is the default linger time of the batches of the source.
+const DefaultBatchLinger = 5 * time.Millisecond
+
+// ErrInvalidBatch is returned when the payload of a batch data frame is malformed.
+var ErrInvalidBatch = errors.New("yomo: invalid batch payload")
+
+// EncodeBatch packs the data into the payload of a batch data frame, every data is prefixed by its uvarint length.
+func EncodeBatch(batch [][]byte) []byte {
+	n := 0
+	for _, data := range batch {
+		n += binary.MaxVarintLen64 + len(data)
+	}
+	buf := make([]byte, 0, n)
+	for _, data := range batch {
+		buf = binary.AppendUvarint(buf, uint64(len(data)))
+		buf = append(buf, data...)
+	}
+	return buf
+}
+
+// DecodeBatch unpacks the payload of a batch data frame, the returned data share the memory of the payload.
+func DecodeBatch(payload []byte) ([][]byte, error) {
+	var batch [][]byte
+	for len(payload) > 0 {
+		n, size := binary.Uvarint(payload)
+		if size <= 0 || n > uint64(len(payload)-size) {
+			return nil, ErrInvalidBatch
+		}
+		payload = payload[size:]
+		batch = append(batch, payload[:n:n])
+		payload = payload[n:]
+	}
+	return batch, nil
+}
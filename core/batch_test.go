@@ -0,0 +1,22 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	batch := [][]byte{[]byte("a"), {}, []byte("hello yomo")}
+
+	got, err := DecodeBatch(EncodeBatch(batch))
+	assert.NoError(t, err)
+	assert.Equal(t, batch, got)
+
+	_, err = DecodeBatch([]byte{0x05, 'a'})
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+
+	got, err = DecodeBatch(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -10,28 +10,37 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/invopop/jsonschema"
 	"github.com/yomorun/yomo/ai"
+	"github.com/yomorun/yomo/core/auth"
 	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/pkg/frame-codec/y3codec"
 	"github.com/yomorun/yomo/pkg/id"
 	yquic "github.com/yomorun/yomo/pkg/listener/quic"
 	"github.com/yomorun/yomo/pkg/log"
+	"github.com/yomorun/yomo/serverless"
 )
 
 // Client is the abstraction of a YoMo-Client. a YoMo-Client can be
 // Source, Upstream Zipper or StreamFunction.
 type Client struct {
-	zipperAddr    string
+	zipperAddr    string                 // the zipper endpoint that will be dialed
+	endpoints     []string               // all the zipper endpoints
+	triedAddrs    map[string]struct{}    // the endpoints failed to connect in the current failover cycle
 	name          string                 // name of the client
 	clientID      string                 // id of the client
 	reconnCounter uint                   // counter for reconnection
 	clientType    ClientType             // type of the client
 	processor     func(*frame.DataFrame) // function to invoke when data arrived
+	cronTrigger   func(time.Time)        // function to invoke when the server triggers the cron handler
 	errorfn       func(error)            // function to invoke when error occured
+	state         atomic.Int32           // the ConnectionState of the client
 	wantedTarget  string
 	opts          *clientOptions
 	Logger        *slog.Logger
@@ -46,15 +55,18 @@ type Client struct {
 	reConnect chan struct{}
 
 	wrCh chan frame.Frame
-	rdCh chan readOut
-}
+	// unsubAck receives the UnsubscribeFrame written back by the server.
+	unsubAck chan struct{}
 
-type readOut struct {
-	err   error
-	frame frame.Frame
+	// spoolMu guards connDone, connDone is closed when the connection is broken, it is nil while disconnected,
+	// the data frames are pushed to the spool while it is nil.
+	spoolMu  sync.Mutex
+	connDone chan struct{}
 }
 
 // NewClient creates a new YoMo-Client.
+// The zipperAddr can be a comma-separated list of endpoints, e.g. "zipper-1:9000,zipper-2:9000",
+// the client fails over between them according to the EndpointSelector.
 func NewClient(appName, zipperAddr string, clientType ClientType, opts ...ClientOption) *Client {
 	option := defaultClientOption()
 
@@ -72,8 +84,14 @@ func NewClient(appName, zipperAddr string, clientType ClientType, opts ...Client
 
 	ctx, ctxCancel := context.WithCancelCause(context.Background())
 
+	endpoints := ParseEndpoints(zipperAddr)
+	if len(endpoints) == 0 {
+		endpoints = []string{zipperAddr}
+	}
+
 	return &Client{
-		zipperAddr: zipperAddr,
+		zipperAddr: option.endpointSelector.Select(endpoints),
+		endpoints:  endpoints,
 		name:       appName,
 		clientID:   clientID,
 		processor:  func(df *frame.DataFrame) { logger.Warn("the processor has not been set") },
@@ -86,10 +104,15 @@ func NewClient(appName, zipperAddr string, clientType ClientType, opts ...Client
 		done:      make(chan struct{}),
 		reConnect: make(chan struct{}),
 		wrCh:      make(chan frame.Frame),
-		rdCh:      make(chan readOut),
+		unsubAck:  make(chan struct{}, 1),
 	}
 }
 
+// Connected reports whether the client is connected to zipper.
+func (c *Client) Connected() bool {
+	return c.ConnectionState() == StateConnected
+}
+
 // SetWantedTarget set the wanted target string.
 func (c *Client) SetWantedTarget(target string) {
 	c.wantedTarget = target
@@ -97,8 +120,14 @@ func (c *Client) SetWantedTarget(target string) {
 
 // Connect connect client to server.
 func (c *Client) Connect(ctx context.Context) error {
+	c.setState(StateConnecting)
+	attempt := 0
 CONNECT:
-	fconn, err := c.connect(ctx, c.zipperAddr)
+	if attempt > 0 {
+		c.onReconnecting(attempt)
+	}
+	attempt++
+	fconn, err := c.dial(ctx)
 	reconnect, err := c.handleConnectResult(err, c.opts.reconnect)
 	if err != nil {
 		return err
@@ -117,33 +146,119 @@ func (c *Client) handleConnectResult(err error, alwaysReconnect bool) (reconnect
 	}
 	select {
 	case <-c.ctx.Done():
+		c.setState(StateClosed)
 		close(c.reConnect)
 		return false, err
 	default:
 	}
 	if err == nil {
-		c.Logger.Info("connected to zipper")
+		c.triedAddrs = nil
+		c.opts.reconnectBackOff.Reset()
+		c.Logger.Info("connected to zipper", "endpoint", c.zipperAddr)
+		c.onConnected()
 		return false, nil
 	}
 	if e := new(ErrRejected); errors.As(err, &e) {
 		close(c.reConnect)
 		c.Logger.Info("handshake be rejected", "err", e.Message)
+		c.onRejected(e.Message)
 		return false, err
 	}
 	if e := new(ErrConnectTo); errors.As(err, &e) {
+		// the redirecting endpoint is tried, so the client does not go back to it in the cycle.
+		c.markTried(c.zipperAddr)
 		c.zipperAddr = e.Endpoint
 		c.Logger.Info("connect to new endpoint", "endpoint", e.Endpoint)
+		c.onRedirect(e.Endpoint)
 		return true, nil
 	}
+	// every endpoint should be tried once before backing off.
+	if c.markTried(c.zipperAddr) {
+		failed := c.failover()
+		c.Logger.Error("failed to connect to zipper, failing over", "err", err, "endpoint", failed, "next_endpoint", c.zipperAddr)
+		return true, nil
+	}
+	c.triedAddrs = nil
 	if alwaysReconnect {
-		c.Logger.Error("failed to connect to zipper, trying to reconnect", "err", err)
-		time.Sleep(time.Second)
+		failed := c.failover()
+		wait := c.opts.reconnectBackOff.NextBackOff()
+		c.Logger.Error("failed to connect to zipper, trying to reconnect", "err", err, "endpoint", failed, "wait", wait)
+		c.sleep(wait)
 		return true, nil
 	}
 	c.Logger.Error("cannot connect to zipper", "err", err)
+	c.setState(StateDisconnected)
 	return false, err
 }
 
+// markTried marks the endpoint as tried in the current failover cycle,
+// it reports whether there are endpoints that have not been tried.
+func (c *Client) markTried(addr string) (untried bool) {
+	if c.triedAddrs == nil {
+		c.triedAddrs = make(map[string]struct{}, len(c.endpoints))
+	}
+	c.triedAddrs[addr] = struct{}{}
+
+	return len(c.untriedEndpoints()) > 0
+}
+
+// untriedEndpoints returns the endpoints that have not been tried in the current failover cycle.
+func (c *Client) untriedEndpoints() []string {
+	untried := make([]string, 0, len(c.endpoints))
+	for _, endpoint := range c.endpoints {
+		if _, ok := c.triedAddrs[endpoint]; !ok {
+			untried = append(untried, endpoint)
+		}
+	}
+	return untried
+}
+
+// failover switches to the endpoint picked by the endpoint selector, it returns the failed endpoint.
+// The endpoints that have been tried in the current failover cycle are skipped.
+func (c *Client) failover() (failed string) {
+	failed = c.zipperAddr
+	c.zipperAddr = c.opts.endpointSelector.Select(c.endpoints)
+	if _, ok := c.triedAddrs[c.zipperAddr]; ok {
+		if untried := c.untriedEndpoints(); len(untried) > 0 {
+			c.zipperAddr = c.opts.endpointSelector.Select(untried)
+		}
+	}
+	return failed
+}
+
+// dial connects to the current zipper endpoint and reports the result to the endpoint selector.
+func (c *Client) dial(ctx context.Context) (frame.Conn, error) {
+	addr := c.zipperAddr
+
+	start := time.Now()
+	conn, err := c.connect(ctx, addr)
+
+	// the rejection and redirection are not the failures of the endpoint.
+	if e := new(ErrRejected); errors.As(err, &e) {
+		return conn, err
+	}
+	if e := new(ErrConnectTo); errors.As(err, &e) {
+		return conn, err
+	}
+	c.opts.endpointSelector.Report(addr, time.Since(start), err)
+
+	return conn, err
+}
+
+// sleep pauses the current goroutine for at least the duration d, it returns immediately if the client is closed.
+func (c *Client) sleep(d time.Duration) {
+	if d == backoff.Stop {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-c.ctx.Done():
+	case <-t.C:
+	}
+}
+
 func (c *Client) runBackground(conn frame.Conn) {
 	if closed := c.handleConn(conn); closed {
 		return
@@ -151,24 +266,28 @@ func (c *Client) runBackground(conn frame.Conn) {
 
 	// try reconnect to zipper.
 	var err error
-	for {
-		conn, err = c.connect(c.ctx, c.zipperAddr)
+	for attempt := 1; ; attempt++ {
+		c.onReconnecting(attempt)
+		conn, err = c.dial(c.ctx)
 		reconnect, err := c.handleConnectResult(err, true)
 		if err != nil {
 			return
 		}
 		if reconnect {
-			time.Sleep(time.Second)
 			continue
 		}
 		if closed := c.handleConn(conn); closed {
 			return
 		}
+		attempt = 0
 	}
 }
 
 func (c *Client) handleConn(conn frame.Conn) (closed bool) {
-	if err := c.serveConn(conn); err != nil {
+	err := c.serveSpooledConn(conn)
+	c.onDisconnected(err)
+
+	if err != nil {
 		if c.errorfn != nil {
 			c.errorfn(err)
 		} else {
@@ -185,6 +304,20 @@ func (c *Client) handleConn(conn frame.Conn) (closed bool) {
 	return false
 }
 
+// serveSpooledConn drains the spool before serving the connection if the spool is set.
+func (c *Client) serveSpooledConn(conn frame.Conn) error {
+	if c.opts.spool == nil {
+		return c.serveConn(conn)
+	}
+	if err := c.openSpool(conn); err != nil {
+		_ = conn.CloseWithError(err.Error())
+		return err
+	}
+	defer c.closeSpool()
+
+	return c.serveConn(conn)
+}
+
 func (c *Client) connect(ctx context.Context, addr string) (frame.Conn, error) {
 	conn, err := yquic.DialAddr(ctx, addr, y3codec.Codec(), y3codec.PacketReadWriter(), c.opts.tlsConfig, c.opts.quicConfig)
 	if err != nil {
@@ -195,15 +328,22 @@ func (c *Client) connect(ctx context.Context, addr string) (frame.Conn, error) {
 	clientID := fmt.Sprintf("%s-%d", c.clientID, c.reconnCounter)
 	c.reconnCounter++
 
+	credential, err := c.credential()
+	if err != nil {
+		_ = conn.CloseWithError(err.Error())
+		return nil, err
+	}
+
 	hf := &frame.HandshakeFrame{
 		Name:            c.name,
 		ID:              clientID,
 		ClientType:      byte(c.clientType),
 		ObserveDataTags: c.opts.observeDataTags,
-		AuthName:        c.opts.credential.Name(),
-		AuthPayload:     c.opts.credential.Payload(),
+		AuthName:        credential.Name(),
+		AuthPayload:     credential.Payload(),
 		Version:         Version,
 		WantedTarget:    c.wantedTarget,
+		CronSpec:        c.opts.cronSpec,
 	}
 
 	err = c.handshakeWithDefinition(hf)
@@ -241,6 +381,18 @@ func (c *Client) connect(ctx context.Context, addr string) (frame.Conn, error) {
 	return nil, err
 }
 
+// credential returns the credential for handshake.
+func (c *Client) credential() (*auth.Credential, error) {
+	if c.opts.credentialFunc == nil {
+		return c.opts.credential, nil
+	}
+	payload, err := c.opts.credentialFunc()
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewCredential(payload), nil
+}
+
 func (c *Client) handshakeWithDefinition(hf *frame.HandshakeFrame) error {
 	if c.clientType != ClientTypeStreamFunction {
 		return nil
@@ -306,6 +458,9 @@ func parseAIFunctionParameters(inputModel any) (*ai.FunctionParameters, error) {
 
 // WriteFrame write frame to client.
 func (c *Client) WriteFrame(f frame.Frame) error {
+	if df, ok := f.(*frame.DataFrame); ok && c.opts.spool != nil {
+		return c.spoolWriteFrame(df)
+	}
 	if c.opts.nonBlockWrite {
 		return c.nonBlockWriteFrame(f)
 	}
@@ -334,10 +489,37 @@ func (c *Client) nonBlockWriteFrame(f frame.Frame) error {
 	}
 }
 
+// Unsubscribe stops the zipper routing data frames to the client, it returns after the zipper acknowledges,
+// so the frames written before it have been handled by the zipper. It is not safe to call it concurrently.
+func (c *Client) Unsubscribe(ctx context.Context) error {
+	// drop the ack of the previous unsubscribing that timed out.
+	select {
+	case <-c.unsubAck:
+	default:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return context.Cause(c.ctx)
+	case c.wrCh <- &frame.UnsubscribeFrame{}:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return context.Cause(c.ctx)
+	case <-c.unsubAck:
+		return nil
+	}
+}
+
 // Close close the client.
 func (c *Client) Close() error {
 	// break runBackgroud() for-loop.
 	c.ctxCancel(fmt.Errorf("%s: shutdown", c.clientType.String()))
+	c.setState(StateClosed)
 
 	select {
 	case <-c.done:
@@ -361,14 +543,17 @@ func (c *Client) Wait() {
 }
 
 func (c *Client) serveConn(conn frame.Conn) error {
+	// the frames are handled in the reading goroutine rather than the loop below, the processor may block
+	// while the handlers are busy, and the handlers need the loop to write their frames.
+	rdErr := make(chan error, 1)
 	go func() {
 		for {
 			f, err := conn.ReadFrame()
 			if err != nil {
-				c.rdCh <- readOut{err: err}
+				rdErr <- err
 				return
 			}
-			c.rdCh <- readOut{frame: f}
+			c.safeHandleFrame(f)
 		}
 	}()
 
@@ -381,40 +566,60 @@ func (c *Client) serveConn(conn frame.Conn) error {
 			return err
 		case f := <-c.wrCh:
 			if err := conn.WriteFrame(f); err != nil {
+				c.respool(f)
 				return err
 			}
-		case out := <-c.rdCh:
-			if err := out.err; err != nil {
-				return err
-			}
-			func() {
-				defer func() {
-					if e := recover(); e != nil {
-						const size = 64 << 10
-						buf := make([]byte, size)
-						buf = buf[:runtime.Stack(buf, false)]
-
-						perr := fmt.Errorf("%v", e)
-						c.Logger.Error("stream panic", "err", perr)
-						c.errorfn(fmt.Errorf("yomo: stream panic: %v\n%s", perr, buf))
-					}
-				}()
-				c.handleFrame(out.frame)
-			}()
+		case err := <-rdErr:
+			return err
 		}
 	}
 }
 
+// safeHandleFrame handles the frame, the panic is recovered and reported to the error handler.
+func (c *Client) safeHandleFrame(f frame.Frame) {
+	defer func() {
+		if e := recover(); e != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+
+			perr := fmt.Errorf("%v", e)
+			c.Logger.Error("stream panic", "err", perr)
+			if c.errorfn != nil {
+				c.errorfn(fmt.Errorf("yomo: stream panic: %v\n%s", perr, buf))
+			}
+		}
+	}()
+	c.handleFrame(f)
+}
+
 func (c *Client) handleFrame(f frame.Frame) {
 	switch ff := f.(type) {
+	// cancel the ctx rather than calling c.Close(), because c.Close() waits for serveConn returning.
 	case *frame.GoawayFrame:
 		c.Logger.Error("goaway error", "err", ff.Message)
-		_ = c.Close()
+		c.ctxCancel(fmt.Errorf("%s: goaway: %s", c.clientType.String(), ff.Message))
 	case *frame.RejectedFrame:
 		c.Logger.Error("rejected error", "err", ff.Message)
-		_ = c.Close()
+		c.onRejected(ff.Message)
+		c.ctxCancel(fmt.Errorf("%s: rejected: %s", c.clientType.String(), ff.Message))
 	case *frame.DataFrame:
 		c.processor(ff)
+	case *frame.UnsubscribeFrame:
+		select {
+		case c.unsubAck <- struct{}{}:
+		default:
+		}
+	case *frame.CronTriggerFrame:
+		if c.cronTrigger == nil {
+			c.Logger.Warn("the cron trigger has not been set")
+			return
+		}
+		c.cronTrigger(time.UnixMilli(ff.Time))
+		// ack the trigger, so the server knows that the tick is not missed.
+		if err := c.WriteFrame(ff); err != nil {
+			c.Logger.Warn("failed to ack the cron trigger", "err", err)
+		}
 	default:
 		c.Logger.Warn("received unexpected frame", "frame_type", f.Type().String())
 	}
@@ -425,6 +630,16 @@ func (c *Client) SetDataFrameObserver(fn func(*frame.DataFrame)) {
 	c.processor = fn
 }
 
+// SetCronTrigger sets the function to invoke when the server triggers the cron handler, the tick time is passed to it.
+func (c *Client) SetCronTrigger(fn func(time.Time)) {
+	c.cronTrigger = fn
+}
+
+// SetCronSpec sets the cron spec that is scheduled by the server, it works with WithClusterCron.
+func (c *Client) SetCronSpec(spec string) {
+	c.opts.cronSpec = spec
+}
+
 // SetObserveDataTags set the data tag list that will be observed.
 func (c *Client) SetObserveDataTags(tag ...frame.Tag) {
 	c.opts.observeDataTags = tag
@@ -445,6 +660,38 @@ func (c *Client) Name() string { return c.name }
 // DisableOtelTrace return if disable otel trace.
 func (c *Client) DisableOtelTrace() bool { return c.opts.disableOtelTrace }
 
+// Concurrency returns the number of handler workers and the size of the queue, zero workers means unbounded.
+func (c *Client) Concurrency() (workers, queueSize int) { return c.opts.concurrency, c.opts.queueSize }
+
+// FrameTimeout returns the timeout of the data frames written by the client, zero means no deadline.
+func (c *Client) FrameTimeout() time.Duration { return c.opts.frameTimeout }
+
+// HandlerTimeout returns the timeout of the stream function handlers, zero means no timeout.
+func (c *Client) HandlerTimeout() time.Duration { return c.opts.handlerTimeout }
+
+// RetryPolicy returns the retry policy of the stream function handler.
+func (c *Client) RetryPolicy() RetryPolicy { return c.opts.retryPolicy }
+
+// DeadLetterTag returns the dead-letter tag of the stream function, zero means no dead-letter tag.
+func (c *Client) DeadLetterTag() uint32 { return c.opts.deadLetterTag }
+
+// State returns the key-value state of the stream function, it is nil if it is not set.
+func (c *Client) State() serverless.State { return c.opts.state }
+
+// SerialKey returns the function that returns the serial key of the data, it can be nil.
+func (c *Client) SerialKey() SerialKeyFunc { return c.opts.serialKey }
+
+// ClusterCron reports whether the cron handler is scheduled by the server.
+func (c *Client) ClusterCron() bool { return c.opts.clusterCron }
+
+// Batch returns the size and the linger time of the batches of the source, zero size means no batching.
+func (c *Client) Batch() (size int, linger time.Duration) {
+	if c.opts.batchSize > 0 && c.opts.batchLinger <= 0 {
+		return c.opts.batchSize, DefaultBatchLinger
+	}
+	return c.opts.batchSize, c.opts.batchLinger
+}
+
 // Downstream represents a frame writer that can connect to an addr.
 type Downstream interface {
 	frame.Writer
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/logging"
 	"github.com/quic-go/quic-go/qlog"
@@ -17,6 +18,7 @@ import (
 	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/core/ylog"
 	pkgtls "github.com/yomorun/yomo/pkg/tls"
+	"github.com/yomorun/yomo/serverless"
 )
 
 // ClientOption YoMo client options
@@ -28,13 +30,39 @@ type clientOptions struct {
 	quicConfig      *quic.Config
 	tlsConfig       *tls.Config
 	credential      *auth.Credential
+	credentialFunc  func() (string, error)
 	reconnect       bool
 	nonBlockWrite   bool
 	logger          *slog.Logger
+	// endpoint selection and reconnection
+	endpointSelector EndpointSelector
+	reconnectBackOff backoff.BackOff
 	// ai function
 	aiFunctionInputModel  any
 	aiFunctionDescription string
 	disableOtelTrace      bool
+	// stream function handler concurrency
+	concurrency int
+	queueSize   int
+	serialKey   SerialKeyFunc
+	// deadlines
+	frameTimeout   time.Duration
+	handlerTimeout time.Duration
+	// handler failures
+	retryPolicy   RetryPolicy
+	deadLetterTag uint32
+	// key-value state
+	state serverless.State
+	// the data frames written while disconnected
+	spool Spool
+	// batching of the data written by sources
+	batchSize   int
+	batchLinger time.Duration
+	// connection lifecycle callbacks
+	hooks Hooks
+	// the cron handler is scheduled by the server
+	clusterCron bool
+	cronSpec    string
 }
 
 // DefaultClientQuicConfig be used when the `quicConfig` of client is nil.
@@ -57,6 +85,9 @@ func defaultClientOption() *clientOptions {
 		tlsConfig:       pkgtls.MustCreateClientTLSConfig(),
 		credential:      auth.NewCredential(""),
 		logger:          ylog.Default(),
+		// endpoint selection and reconnection
+		endpointSelector: NewOrderedSelector(),
+		reconnectBackOff: newReconnectBackOff(),
 	}
 
 	return opts
@@ -69,6 +100,14 @@ func WithCredential(payload string) ClientOption {
 	}
 }
 
+// WithCredentialFunc sets the function that returns the client credential, the function is called
+// on every connection, so the credential can be rotated without restarting the client.
+func WithCredentialFunc(fn func() (string, error)) ClientOption {
+	return func(o *clientOptions) {
+		o.credentialFunc = fn
+	}
+}
+
 // WithClientTLSConfig sets tls config for the client.
 func WithClientTLSConfig(tc *tls.Config) ClientOption {
 	return func(o *clientOptions) {
@@ -92,6 +131,26 @@ func WithReConnect() ClientOption {
 	}
 }
 
+// WithEndpointSelector sets the endpoint selector, which decides the zipper endpoint that the client dials
+// when there are multiple endpoints, The default selector is NewOrderedSelector().
+func WithEndpointSelector(selector EndpointSelector) ClientOption {
+	return func(o *clientOptions) {
+		if selector != nil {
+			o.endpointSelector = selector
+		}
+	}
+}
+
+// WithReconnectBackOff sets the backoff between reconnections,
+// The default backoff is an exponential backoff with jitter, starting from 1s up to 30s.
+func WithReconnectBackOff(b backoff.BackOff) ClientOption {
+	return func(o *clientOptions) {
+		if b != nil {
+			o.reconnectBackOff = b
+		}
+	}
+}
+
 // WithNonBlockWrite makes client WriteFrame non-blocking.
 func WithNonBlockWrite() ClientOption {
 	return func(o *clientOptions) {
@@ -121,6 +180,102 @@ func DisableOtelTrace() ClientOption {
 	}
 }
 
+// WithConcurrency bounds the handlers of the stream function to the number of workers.
+// When the queue of the workers is full, the client stops reading data frames,
+// so the zipper is slowed down rather than the frames being dropped.
+func WithConcurrency(workers int) ClientOption {
+	return func(o *clientOptions) {
+		o.concurrency = workers
+	}
+}
+
+// WithQueueSize sets the number of data frames waiting for the workers, it defaults to the number of workers.
+func WithQueueSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		o.queueSize = size
+	}
+}
+
+// WithSerialKey makes the data frames with the same key be handled in order by one worker,
+// the frames with different keys are still handled in parallel. It works with WithConcurrency, the default
+// concurrency is runtime.NumCPU() if the serial key is set.
+func WithSerialKey(fn SerialKeyFunc) ClientOption {
+	return func(o *clientOptions) {
+		o.serialKey = fn
+	}
+}
+
+// WithFrameTimeout sets the deadline of the data frames written by the client to the writing time plus the timeout,
+// the deadline is carried in the frame metadata, the stream functions skip the frames after the deadline.
+func WithFrameTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.frameTimeout = timeout
+	}
+}
+
+// WithHandlerTimeout sets the timeout of the stream function handlers,
+// the context of the handler is done after the timeout or the deadline of the data frame, whichever comes first.
+func WithHandlerTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.handlerTimeout = timeout
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the stream function handler that returns an error.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retryPolicy = p
+	}
+}
+
+// WithDeadLetterTag sets the tag that the data is written to when the stream function handler fails at last,
+// the metadata of the dead-letter data carries the error, the original tag and the number of attempts.
+func WithDeadLetterTag(tag uint32) ClientOption {
+	return func(o *clientOptions) {
+		o.deadLetterTag = tag
+	}
+}
+
+// WithState sets the key-value state of the stream function, the handlers get it by ctx.State().
+func WithState(state serverless.State) ClientOption {
+	return func(o *clientOptions) {
+		o.state = state
+	}
+}
+
+// WithSpool keeps the data frames written while the client is disconnected from the zipper in the spool,
+// rather than blocking the writes, the spooled frames are written in order after reconnecting.
+func WithSpool(spool Spool) ClientOption {
+	return func(o *clientOptions) {
+		o.spool = spool
+	}
+}
+
+// WithBatch packs the data written by the source into one data frame until the data reach the size in bytes,
+// or the linger time passes since the first data is packed, the linger time defaults to DefaultBatchLinger.
+// The stream functions unpack the data frame, so the handlers are called once per data.
+func WithBatch(size int, linger time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.batchSize = size
+		o.batchLinger = linger
+	}
+}
+
+// WithHooks sets the callbacks of the connection lifecycle of the client.
+func WithHooks(hooks Hooks) ClientOption {
+	return func(o *clientOptions) {
+		o.hooks = hooks
+	}
+}
+
+// WithClusterCron makes the server schedule the cron handler of the stream function, the server triggers
+// one of the stream functions with the same name on each tick, rather than every stream function runs its own cron.
+func WithClusterCron() ClientOption {
+	return func(o *clientOptions) {
+		o.clusterCron = true
+	}
+}
+
 // qlog helps developers to debug quic protocol.
 // See more: https://github.com/quic-go/quic-go?tab=readme-ov-file#quic-event-logging-using-qlog
 func qlogTraceEnabled() bool {
@@ -41,6 +41,7 @@ type Connection struct {
 	metadata        metadata.M
 	observeDataTags []uint32
 	fconn           frame.Conn
+	cronSpec        string // the cron spec of the stream function whose cron handler is scheduled by the server
 	Logger          *slog.Logger
 }
 
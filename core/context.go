@@ -25,6 +25,8 @@ type Context struct {
 	Keys map[string]any
 	// Using Logger to log in connection handler scope, Logger is frame-level logger.
 	Logger *slog.Logger
+	// err is the error from handling the frame, it is reported to the Observer.
+	err error
 }
 
 // Set is used to store a new key/value pair exclusively for this context.
@@ -50,6 +52,12 @@ func (c *Context) Get(key string) (any, bool) {
 	return value, ok
 }
 
+// SetError sets the error of handling the frame, it is reported to the Observer.
+// The frame middlewares use it to report the frames that they do not pass on.
+func (c *Context) SetError(err error) {
+	c.err = err
+}
+
 // newContext returns a new YoMo context that implements the standard library `context.Context` interface.
 // The YoMo context is used to manage the lifecycle of a connection and provides a way to pass data and metadata
 // between connection processing functions. The lifecycle of the context is equal to the lifecycle of the connection
@@ -60,8 +68,19 @@ func newContext(conn *Connection, df *frame.DataFrame) (c *Context, err error) {
 		return nil, err
 	}
 
+	// the namespace of the data frame is decided by the connection rather than the writer,
+	// except that the data frames forwarded by the mesh zipper keep their namespace. The client type
+	// is declared by the client itself, so the zipper is trusted only if it has a mesh credential.
+	mesh := conn.ClientType() == ClientTypeUpstreamZipper && metadata.IsMesh(conn.Metadata())
+	if !mesh {
+		delete(fmd, metadata.NamespaceKey)
+	}
+
 	// merge connection metadata.
 	conn.Metadata().Range(func(k, v string) bool {
+		if k == metadata.MeshKey || (k == metadata.NamespaceKey && mesh) {
+			return true
+		}
 		fmd.Set(k, v)
 		return true
 	})
@@ -108,5 +127,6 @@ func (c *Context) reset() {
 	c.Frame = nil
 	c.FrameMetadata = nil
 	c.Logger = nil
+	c.err = nil
 	clear(c.Keys)
 }
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+// CronMissedPolicy decides how the server handles the schedule ticks of a cron handler that are missed
+// because no instance of the stream function is connected.
+type CronMissedPolicy int
+
+const (
+	// CronSkipMissed skips the missed ticks, the cron handler is triggered on the next tick.
+	CronSkipMissed CronMissedPolicy = iota
+	// CronFireOnceMissed triggers the cron handler once as soon as an instance connects after the ticks are missed,
+	// the trigger carries the time of the last missed tick.
+	CronFireOnceMissed
+)
+
+// String returns the name of the policy.
+func (p CronMissedPolicy) String() string {
+	switch p {
+	case CronSkipMissed:
+		return "skip"
+	case CronFireOnceMissed:
+		return "fire_once"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseCronMissedPolicy parses the policy from its name, the empty name is CronSkipMissed.
+func ParseCronMissedPolicy(s string) (CronMissedPolicy, error) {
+	switch s {
+	case "", "skip":
+		return CronSkipMissed, nil
+	case "fire_once":
+		return CronFireOnceMissed, nil
+	default:
+		return CronSkipMissed, fmt.Errorf("cron: unknown missed ticks policy: %s", s)
+	}
+}
+
+// cronJobKey identifies the cron handler, the instances of a stream function share the cron handler.
+type cronJobKey struct {
+	namespace string
+	name      string
+	spec      string
+}
+
+// cronJob is a cron handler scheduled by the server.
+type cronJob struct {
+	entry cron.EntryID
+	// conns are the instances in the order they connected, the first one is triggered on each tick,
+	// the next one takes over when it disconnects.
+	conns []*Connection
+	// missed is the time of the last missed tick, zero means no tick is missed.
+	missed time.Time
+	// pending is the tick written to the instance that has not acked it, the tick is missed if the instance
+	// disconnects before acking, because a dead peer still accepts the writing until the idle timeout.
+	pending cronPending
+}
+
+// cronPending is the tick written to the instance and waiting for its ack.
+type cronPending struct {
+	connID uint64
+	tick   time.Time
+}
+
+// cronScheduler schedules the cron handlers of the stream functions that register their cron spec in
+// the handshake, each tick triggers exactly one instance of the stream function, so the cron handler
+// runs once in the cluster no matter how many instances are running.
+type cronScheduler struct {
+	policy CronMissedPolicy
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	cron  *cron.Cron
+	jobs  map[cronJobKey]*cronJob
+	conns map[uint64]cronJobKey
+}
+
+func newCronScheduler(policy CronMissedPolicy, logger *slog.Logger) *cronScheduler {
+	c := &cronScheduler{
+		policy: policy,
+		logger: logger,
+		cron:   cron.New(),
+		jobs:   make(map[cronJobKey]*cronJob),
+		conns:  make(map[uint64]cronJobKey),
+	}
+	c.cron.Start()
+
+	return c
+}
+
+// validateCronSpec returns an error if the cron spec can not be scheduled.
+func validateCronSpec(spec string) error {
+	_, err := cron.ParseStandard(spec)
+	return err
+}
+
+// add adds the connection as an instance of the cron handler, the cron handler is scheduled
+// when its first instance is added.
+func (c *cronScheduler) add(conn *Connection, spec string) error {
+	key := cronJobKey{namespace: metadata.GetNamespace(conn.Metadata()), name: conn.Name(), spec: spec}
+
+	c.mu.Lock()
+	job, ok := c.jobs[key]
+	if !ok {
+		job = &cronJob{}
+		entry, err := c.cron.AddFunc(spec, func() { c.fire(key, time.Now()) })
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		job.entry = entry
+		c.jobs[key] = job
+	}
+	job.conns = append(job.conns, conn)
+	c.conns[conn.ID()] = key
+
+	conn.Logger.Info("cron handler registered", "cron_spec", spec, "instances", len(job.conns))
+
+	var missed time.Time
+	if c.policy == CronFireOnceMissed {
+		missed = job.missed
+	}
+	c.mu.Unlock()
+
+	if !missed.IsZero() {
+		c.trigger(key, missed)
+	}
+	return nil
+}
+
+// remove removes the connection from the instances of its cron handler, the cron handler is unscheduled
+// when it has no instance, unless the missed ticks are fired once the instance connects again.
+// The tick that the instance has not acked is missed, because a dead peer still accepts the writing
+// until the idle timeout.
+func (c *cronScheduler) remove(connID uint64) {
+	c.mu.Lock()
+	key, ok := c.conns[connID]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.conns, connID)
+
+	job := c.jobs[key]
+	for i, conn := range job.conns {
+		if conn.ID() == connID {
+			job.conns = append(job.conns[:i], job.conns[i+1:]...)
+			break
+		}
+	}
+	if job.pending.connID == connID && !job.pending.tick.IsZero() {
+		c.miss(key, job, job.pending.tick, "the instance disconnected before acking the trigger")
+	}
+
+	var missed time.Time
+	switch {
+	case len(job.conns) == 0 && c.policy == CronSkipMissed:
+		c.cron.Remove(job.entry)
+		delete(c.jobs, key)
+	case len(job.conns) > 0 && c.policy == CronFireOnceMissed:
+		missed = job.missed
+	}
+	c.mu.Unlock()
+
+	if !missed.IsZero() {
+		c.trigger(key, missed)
+	}
+}
+
+// ack marks the tick as handled by the instance, the instance echoes the trigger once it receives it.
+func (c *cronScheduler) ack(connID uint64, tick time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.conns[connID]
+	if !ok {
+		return
+	}
+	if job := c.jobs[key]; job.pending.connID == connID && job.pending.tick.Equal(tick) {
+		job.pending = cronPending{}
+	}
+}
+
+// fire triggers the cron handler on the schedule tick.
+func (c *cronScheduler) fire(key cronJobKey, t time.Time) {
+	c.trigger(key, t)
+}
+
+// trigger writes the trigger to the first instance of the cron handler, it fails over to the next instances
+// if the writing fails. The tick is missed if no instance can be triggered. The trigger is written without
+// the lock, so an instance that is slow to write does not block the scheduler.
+func (c *cronScheduler) trigger(key cronJobKey, t time.Time) {
+	c.mu.Lock()
+	job, ok := c.jobs[key]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	conns := slices.Clone(job.conns)
+	c.mu.Unlock()
+
+	for _, conn := range conns {
+		if err := conn.FrameConn().WriteFrame(&frame.CronTriggerFrame{Time: t.UnixMilli()}); err != nil {
+			conn.Logger.Warn("failed to trigger cron handler, failing over", "err", err, "cron_spec", key.spec)
+			continue
+		}
+
+		c.mu.Lock()
+		// the instance may be removed during the writing, then the tick can not be acked.
+		if _, ok := c.conns[conn.ID()]; !ok {
+			c.mu.Unlock()
+			continue
+		}
+		job.missed = time.Time{}
+		job.pending = cronPending{connID: conn.ID(), tick: t}
+		c.mu.Unlock()
+
+		conn.Logger.Debug("cron handler triggered", "cron_spec", key.spec, "tick", t)
+		return
+	}
+
+	c.mu.Lock()
+	c.miss(key, job, t, "no instance to trigger cron handler")
+	c.mu.Unlock()
+}
+
+// miss records the tick as missed, it must be called with the lock held.
+func (c *cronScheduler) miss(key cronJobKey, job *cronJob, t time.Time, reason string) {
+	job.missed = t
+	job.pending = cronPending{}
+	c.logger.Warn(reason+", the tick is missed",
+		"sfn_name", key.name, "namespace", key.namespace, "cron_spec", key.spec, "tick", t, "policy", c.policy.String())
+}
+
+// close stops scheduling the cron handlers.
+func (c *cronScheduler) close() {
+	<-c.cron.Stop().Done()
+}
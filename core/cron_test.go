@@ -0,0 +1,159 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+// triggeredConn records the cron triggers written to it.
+type triggeredConn struct {
+	frame.Conn
+	err      error
+	triggers []int64
+}
+
+func (c *triggeredConn) WriteFrame(f frame.Frame) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.triggers = append(c.triggers, f.(*frame.CronTriggerFrame).Time)
+	return nil
+}
+
+func newCronConn(id uint64, name string) (*Connection, *triggeredConn) {
+	fconn := &triggeredConn{}
+	conn := NewConnection(id, name, name, ClientTypeStreamFunction, metadata.M{}, nil, fconn, discardingLogger)
+	return conn, fconn
+}
+
+func TestCronScheduler(t *testing.T) {
+	c := newCronScheduler(CronSkipMissed, discardingLogger)
+	defer c.close()
+
+	conn1, fconn1 := newCronConn(1, "sfn-cron")
+	conn2, fconn2 := newCronConn(2, "sfn-cron")
+	other, fother := newCronConn(3, "sfn-other")
+	assert.NoError(t, c.add(conn1, "@hourly"))
+	assert.NoError(t, c.add(conn2, "@hourly"))
+	assert.NoError(t, c.add(other, "@hourly"))
+	assert.Len(t, c.jobs, 2)
+
+	key := cronJobKey{name: "sfn-cron", spec: "@hourly"}
+
+	// only the first instance is triggered.
+	c.fire(key, time.UnixMilli(1))
+	assert.Equal(t, []int64{1}, fconn1.triggers)
+	assert.Empty(t, fconn2.triggers)
+	assert.Empty(t, fother.triggers)
+
+	// fail over to the next instance if the writing fails.
+	fconn1.err = errors.New("broken")
+	c.fire(key, time.UnixMilli(2))
+	assert.Equal(t, []int64{2}, fconn2.triggers)
+
+	// the next instance takes over after the first one disconnects.
+	c.remove(conn1.ID())
+	fconn1.err = nil
+	c.fire(key, time.UnixMilli(3))
+	assert.Equal(t, []int64{1}, fconn1.triggers)
+	assert.Equal(t, []int64{2, 3}, fconn2.triggers)
+
+	// the cron handler is unscheduled without instances.
+	c.remove(conn2.ID())
+	assert.Len(t, c.jobs, 1)
+	assert.Len(t, c.cron.Entries(), 1)
+
+	// the missed ticks are skipped.
+	conn4, fconn4 := newCronConn(4, "sfn-cron")
+	assert.NoError(t, c.add(conn4, "@hourly"))
+	assert.Empty(t, fconn4.triggers)
+
+	assert.Error(t, c.add(conn1, "every hour"))
+}
+
+func TestCronFireOnceMissed(t *testing.T) {
+	c := newCronScheduler(CronFireOnceMissed, discardingLogger)
+	defer c.close()
+
+	key := cronJobKey{name: "sfn-cron", spec: "@hourly"}
+
+	conn1, _ := newCronConn(1, "sfn-cron")
+	assert.NoError(t, c.add(conn1, "@hourly"))
+	c.remove(conn1.ID())
+
+	// the cron handler is kept scheduled without instances, so the missed ticks are recorded.
+	c.fire(key, time.UnixMilli(1))
+	c.fire(key, time.UnixMilli(2))
+
+	conn2, fconn2 := newCronConn(2, "sfn-cron")
+	assert.NoError(t, c.add(conn2, "@hourly"))
+	assert.Equal(t, []int64{2}, fconn2.triggers)
+
+	conn3, fconn3 := newCronConn(3, "sfn-cron")
+	assert.NoError(t, c.add(conn3, "@hourly"))
+	assert.Empty(t, fconn3.triggers)
+}
+
+func TestCronUnackedTick(t *testing.T) {
+	key := cronJobKey{name: "sfn-cron", spec: "@hourly"}
+
+	t.Run("fire once", func(t *testing.T) {
+		c := newCronScheduler(CronFireOnceMissed, discardingLogger)
+		defer c.close()
+
+		conn1, fconn1 := newCronConn(1, "sfn-cron")
+		conn2, fconn2 := newCronConn(2, "sfn-cron")
+		conn3, fconn3 := newCronConn(3, "sfn-cron")
+		assert.NoError(t, c.add(conn1, "@hourly"))
+		assert.NoError(t, c.add(conn2, "@hourly"))
+		assert.NoError(t, c.add(conn3, "@hourly"))
+
+		// the acked tick is not fired again.
+		c.fire(key, time.UnixMilli(1))
+		c.ack(conn1.ID(), time.UnixMilli(1))
+		c.remove(conn1.ID())
+		assert.Equal(t, []int64{1}, fconn1.triggers)
+		assert.Empty(t, fconn2.triggers)
+
+		// the instance disconnects before acking, so the tick is fired on the next instance.
+		c.fire(key, time.UnixMilli(2))
+		c.remove(conn2.ID())
+		assert.Equal(t, []int64{2}, fconn2.triggers)
+		assert.Equal(t, []int64{2}, fconn3.triggers)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		c := newCronScheduler(CronSkipMissed, discardingLogger)
+		defer c.close()
+
+		conn1, _ := newCronConn(1, "sfn-cron")
+		conn2, fconn2 := newCronConn(2, "sfn-cron")
+		assert.NoError(t, c.add(conn1, "@hourly"))
+		assert.NoError(t, c.add(conn2, "@hourly"))
+
+		c.fire(key, time.UnixMilli(1))
+		c.remove(conn1.ID())
+		assert.Empty(t, fconn2.triggers)
+		assert.Equal(t, time.UnixMilli(1), c.jobs[key].missed)
+	})
+}
+
+func TestParseCronMissedPolicy(t *testing.T) {
+	for _, policy := range []CronMissedPolicy{CronSkipMissed, CronFireOnceMissed} {
+		got, err := ParseCronMissedPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, got)
+	}
+
+	got, err := ParseCronMissedPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, CronSkipMissed, got)
+
+	_, err = ParseCronMissedPolicy("fire_all")
+	assert.Error(t, err)
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// EndpointSelector decides which zipper endpoint the client dials next.
+// The client reports the result of every dial to the selector, so the selector
+// can fail over to another endpoint when the current one is unreachable.
+type EndpointSelector interface {
+	// Select returns the endpoint that will be dialed next.
+	Select(endpoints []string) string
+	// Report reports the result of dialing the endpoint, rtt is the time spent on dialing and handshaking.
+	// The err is nil if the connection has been established.
+	Report(endpoint string, rtt time.Duration, err error)
+}
+
+// ParseEndpoints splits a comma-separated zipper address into endpoints,
+// e.g. "zipper-1:9000,zipper-2:9000".
+func ParseEndpoints(addr string) []string {
+	endpoints := []string{}
+	for _, endpoint := range strings.Split(addr, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// NewOrderedSelector returns an EndpointSelector that dials endpoints in the given order,
+// it sticks to an endpoint until dialing it fails, then moves to the next one.
+func NewOrderedSelector() EndpointSelector {
+	return &orderedSelector{}
+}
+
+type orderedSelector struct {
+	mu      sync.Mutex
+	current int
+}
+
+func (s *orderedSelector) Select(endpoints []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(endpoints) == 0 {
+		return ""
+	}
+	return endpoints[s.current%len(endpoints)]
+}
+
+func (s *orderedSelector) Report(_ string, _ time.Duration, err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.current++
+	s.mu.Unlock()
+}
+
+// NewRandomSelector returns an EndpointSelector that dials a random endpoint,
+// the endpoint that failed last time will be skipped if there are other endpoints.
+func NewRandomSelector() EndpointSelector {
+	return &randomSelector{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+type randomSelector struct {
+	mu         sync.Mutex
+	rand       *rand.Rand
+	lastFailed string
+}
+
+func (s *randomSelector) Select(endpoints []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidates := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint != s.lastFailed {
+			candidates = append(candidates, endpoint)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = endpoints
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[s.rand.Intn(len(candidates))]
+}
+
+func (s *randomSelector) Report(endpoint string, _ time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.lastFailed = endpoint
+	} else if s.lastFailed == endpoint {
+		s.lastFailed = ""
+	}
+}
+
+// NewLatencySelector returns an EndpointSelector that dials the endpoint with the lowest handshake latency.
+// Endpoints that have never been dialed are tried first, and endpoints that failed
+// within the cooldown are skipped unless all endpoints have failed.
+func NewLatencySelector(cooldown time.Duration) EndpointSelector {
+	return &latencySelector{
+		cooldown: cooldown,
+		stats:    make(map[string]*endpointStat),
+	}
+}
+
+type endpointStat struct {
+	rtt      time.Duration
+	failedAt time.Time
+}
+
+type latencySelector struct {
+	mu       sync.Mutex
+	cooldown time.Duration
+	stats    map[string]*endpointStat
+}
+
+func (s *latencySelector) Select(endpoints []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		selected string
+		minRTT   time.Duration = -1
+	)
+	for _, endpoint := range s.available(endpoints) {
+		stat, ok := s.stats[endpoint]
+		if !ok || stat.rtt == 0 {
+			return endpoint
+		}
+		if minRTT < 0 || stat.rtt < minRTT {
+			selected, minRTT = endpoint, stat.rtt
+		}
+	}
+	return selected
+}
+
+// available returns the endpoints that are not in cooldown.
+func (s *latencySelector) available(endpoints []string) []string {
+	result := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		stat, ok := s.stats[endpoint]
+		if ok && !stat.failedAt.IsZero() && time.Since(stat.failedAt) < s.cooldown {
+			continue
+		}
+		result = append(result, endpoint)
+	}
+	if len(result) == 0 {
+		return endpoints
+	}
+	return result
+}
+
+func (s *latencySelector) Report(endpoint string, rtt time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, ok := s.stats[endpoint]
+	if !ok {
+		stat = &endpointStat{}
+		s.stats[endpoint] = stat
+	}
+	if err != nil {
+		stat.failedAt = time.Now()
+		return
+	}
+	stat.failedAt = time.Time{}
+	// smooth the rtt, the latest rtt takes 1/4 weight.
+	if stat.rtt == 0 {
+		stat.rtt = rtt
+	} else {
+		stat.rtt = (stat.rtt*3 + rtt) / 4
+	}
+}
+
+// newReconnectBackOff returns the default backoff for reconnecting, it is an exponential
+// backoff with jitter that never stops.
+func newReconnectBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Second
+	b.MaxInterval = 30 * time.Second
+	b.MaxElapsedTime = 0
+	b.Reset()
+	return b
+}
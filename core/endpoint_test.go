@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	assert.Equal(t, []string{"a:9000"}, ParseEndpoints("a:9000"))
+	assert.Equal(t, []string{"a:9000", "b:9000"}, ParseEndpoints("a:9000, b:9000,"))
+	assert.Equal(t, []string{}, ParseEndpoints(""))
+}
+
+func TestOrderedSelector(t *testing.T) {
+	endpoints := []string{"a", "b", "c"}
+	s := NewOrderedSelector()
+
+	assert.Equal(t, "a", s.Select(endpoints))
+
+	s.Report("a", time.Millisecond, nil)
+	assert.Equal(t, "a", s.Select(endpoints))
+
+	s.Report("a", 0, errors.New("dial failed"))
+	assert.Equal(t, "b", s.Select(endpoints))
+
+	s.Report("b", 0, errors.New("dial failed"))
+	s.Report("c", 0, errors.New("dial failed"))
+	assert.Equal(t, "a", s.Select(endpoints))
+}
+
+func TestRandomSelector(t *testing.T) {
+	endpoints := []string{"a", "b"}
+	s := NewRandomSelector()
+
+	s.Report("a", 0, errors.New("dial failed"))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "b", s.Select(endpoints))
+	}
+
+	s.Report("a", time.Millisecond, nil)
+	assert.Contains(t, endpoints, s.Select(endpoints))
+
+	// the only endpoint is always selected.
+	s.Report("a", 0, errors.New("dial failed"))
+	assert.Equal(t, "a", s.Select([]string{"a"}))
+}
+
+func TestLatencySelector(t *testing.T) {
+	endpoints := []string{"a", "b", "c"}
+	s := NewLatencySelector(time.Minute)
+
+	// the endpoints never be dialed are selected first.
+	assert.Equal(t, "a", s.Select(endpoints))
+	s.Report("a", 30*time.Millisecond, nil)
+	assert.Equal(t, "b", s.Select(endpoints))
+	s.Report("b", 10*time.Millisecond, nil)
+	assert.Equal(t, "c", s.Select(endpoints))
+	s.Report("c", 20*time.Millisecond, nil)
+
+	assert.Equal(t, "b", s.Select(endpoints))
+
+	// the failed endpoint is in cooldown.
+	s.Report("b", 0, errors.New("dial failed"))
+	assert.Equal(t, "c", s.Select(endpoints))
+
+	// all endpoints are in cooldown.
+	s.Report("a", 0, errors.New("dial failed"))
+	s.Report("c", 0, errors.New("dial failed"))
+	assert.Equal(t, "b", s.Select(endpoints))
+}
+
+func TestClientFailover(t *testing.T) {
+	t.Parallel()
+
+	const failoverAddr = "127.0.0.1:19995"
+
+	go func() {
+		srv := NewServer("zipper", WithServerLogger(discardingLogger))
+		srv.ListenAndServe(context.TODO(), failoverAddr)
+	}()
+
+	source := NewClient(
+		"source",
+		"127.0.0.1:19994,"+failoverAddr,
+		ClientTypeSource,
+		WithLogger(discardingLogger),
+		WithReConnect(),
+		WithReconnectBackOff(backoff.NewConstantBackOff(100*time.Millisecond)),
+	)
+	assert.Equal(t, "127.0.0.1:19994", source.zipperAddr)
+
+	err := source.Connect(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, failoverAddr, source.zipperAddr)
+
+	assert.NoError(t, source.Close())
+}
+
+// firstSelector always selects the first endpoint and records the reported endpoints.
+type firstSelector struct {
+	reported []string
+}
+
+func (s *firstSelector) Select(endpoints []string) string { return endpoints[0] }
+
+func (s *firstSelector) Report(endpoint string, _ time.Duration, _ error) {
+	s.reported = append(s.reported, endpoint)
+}
+
+func TestClientFailoverCycle(t *testing.T) {
+	t.Parallel()
+
+	selector := &firstSelector{}
+	source := NewClient(
+		"source",
+		"127.0.0.1:19976,127.0.0.1:19977,127.0.0.1:19978",
+		ClientTypeSource,
+		WithLogger(discardingLogger),
+		WithEndpointSelector(selector),
+		WithClientQuicConfig(&quic.Config{HandshakeIdleTimeout: 200 * time.Millisecond}),
+	)
+
+	// every endpoint is tried once even if the selector picks the failed endpoint again.
+	err := source.Connect(context.TODO())
+	assert.Error(t, err)
+	assert.Equal(t, []string{"127.0.0.1:19976", "127.0.0.1:19977", "127.0.0.1:19978"}, selector.reported)
+}
@@ -65,6 +65,9 @@ type HandshakeFrame struct {
 	FunctionDefinition []byte
 	// WantedTarget represents the target that accepts the data frames that carrying the same target.
 	WantedTarget string
+	// CronSpec is the cron spec of the stream function whose cron handler is scheduled by the server,
+	// the server writes a CronTriggerFrame to one of the stream functions with the same name on each tick.
+	CronSpec string
 }
 
 // Type returns the type of HandshakeFrame.
@@ -104,6 +107,23 @@ type ConnectToFrame struct {
 // Type returns the type of ConnectToFrame.
 func (f *ConnectToFrame) Type() Type { return TypeConnectToFrame }
 
+// UnsubscribeFrame is used by stream function to stop the server routing data frames to it.
+// The server writes it back after unsubscribing, so the frames written before it have been handled by the server.
+type UnsubscribeFrame struct{}
+
+// Type returns the type of UnsubscribeFrame.
+func (f *UnsubscribeFrame) Type() Type { return TypeUnsubscribeFrame }
+
+// CronTriggerFrame is used by server to trigger the cron handler of a stream function,
+// the stream function acks the trigger by writing it back.
+type CronTriggerFrame struct {
+	// Time is the unix milliseconds of the schedule tick.
+	Time int64
+}
+
+// Type returns the type of CronTriggerFrame.
+func (f *CronTriggerFrame) Type() Type { return TypeCronTriggerFrame }
+
 const (
 	TypeDataFrame         Type = 0x3F // TypeDataFrame is the type of DataFrame.
 	TypeHandshakeFrame    Type = 0x31 // TypeHandshakeFrame is the type of HandshakeFrame.
@@ -111,6 +131,8 @@ const (
 	TypeRejectedFrame     Type = 0x39 // TypeRejectedFrame is the type of RejectedFrame.
 	TypeGoawayFrame       Type = 0x2E // TypeGoawayFrame is the type of GoawayFrame.
 	TypeConnectToFrame    Type = 0x3E // TypeConnectToFrame is the type of ConnectToFrame.
+	TypeUnsubscribeFrame  Type = 0x2A // TypeUnsubscribeFrame is the type of UnsubscribeFrame.
+	TypeCronTriggerFrame  Type = 0x2B // TypeCronTriggerFrame is the type of CronTriggerFrame.
 )
 
 var frameTypeStringMap = map[Type]string{
@@ -120,6 +142,8 @@ var frameTypeStringMap = map[Type]string{
 	TypeRejectedFrame:     "RejectedFrame",
 	TypeGoawayFrame:       "GoawayFrame",
 	TypeConnectToFrame:    "ConnectToFrame",
+	TypeUnsubscribeFrame:  "UnsubscribeFrame",
+	TypeCronTriggerFrame:  "CronTriggerFrame",
 }
 
 // String returns a human-readable string which represents the frame type.
@@ -139,6 +163,8 @@ var frameTypeNewFuncMap = map[Type]func() Frame{
 	TypeRejectedFrame:     func() Frame { return new(RejectedFrame) },
 	TypeGoawayFrame:       func() Frame { return new(GoawayFrame) },
 	TypeConnectToFrame:    func() Frame { return new(ConnectToFrame) },
+	TypeUnsubscribeFrame:  func() Frame { return new(UnsubscribeFrame) },
+	TypeCronTriggerFrame:  func() Frame { return new(CronTriggerFrame) },
 }
 
 // NewFrame creates a new frame from Type.
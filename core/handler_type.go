@@ -11,5 +11,12 @@ type CronHandler func(ctx serverless.CronContext)
 // AsyncHandler is the request-response mode (asnyc).
 type AsyncHandler func(ctx serverless.Context)
 
+// AsyncHandlerE is the request-response mode (asnyc) that returns an error,
+// the handler is retried by the retry policy and the data is written to the dead-letter tag if it fails at last.
+type AsyncHandlerE func(ctx serverless.Context) error
+
 // PipeHandler is the bidirectional stream mode (blocking).
 type PipeHandler func(in <-chan []byte, out chan<- *frame.DataFrame)
+
+// SerialKeyFunc returns the key of the data, the data with the same key are handled in order.
+type SerialKeyFunc func(ctx serverless.Context) string
@@ -0,0 +1,111 @@
+package core
+
+// ConnectionState is the state of the connection between the client and the zipper.
+type ConnectionState int32
+
+// The states of the connection.
+const (
+	// StateDisconnected means the client has not connected, or it has given up connecting.
+	StateDisconnected ConnectionState = iota
+	// StateConnecting means the client is connecting for the first time.
+	StateConnecting
+	// StateConnected means the client is connected.
+	StateConnected
+	// StateReconnecting means the client is reconnecting after a failed dial or a lost connection.
+	StateReconnecting
+	// StateClosed means the client is closed, or it is rejected by the zipper.
+	StateClosed
+)
+
+// String returns the name of the state.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "Disconnected"
+	case StateConnecting:
+		return "Connecting"
+	case StateConnected:
+		return "Connected"
+	case StateReconnecting:
+		return "Reconnecting"
+	case StateClosed:
+		return "Closed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Hooks are the callbacks of the connection lifecycle of the client, the nil hooks are skipped.
+// They are called synchronously in the goroutine that manages the connection, so they should return quickly.
+type Hooks struct {
+	// OnConnected is called when the client is connected to the endpoint.
+	OnConnected func(endpoint string)
+	// OnDisconnected is called when the connection is lost or closed, with the error that breaks it.
+	OnDisconnected func(err error)
+	// OnReconnecting is called before every dial after a failed dial or a lost connection, the attempt starts from 1.
+	OnReconnecting func(attempt int)
+	// OnRejected is called when the client is rejected by the zipper, e.g. the authentication fails,
+	// the client does not reconnect after that.
+	OnRejected func(reason string)
+	// OnRedirect is called when the zipper redirects the client to another endpoint.
+	OnRedirect func(endpoint string)
+}
+
+// setState sets the state of the connection, the closed state is never left.
+func (c *Client) setState(s ConnectionState) {
+	for {
+		old := c.state.Load()
+		if ConnectionState(old) == StateClosed {
+			return
+		}
+		if c.state.CompareAndSwap(old, int32(s)) {
+			return
+		}
+	}
+}
+
+// ConnectionState returns the state of the connection between the client and the zipper.
+func (c *Client) ConnectionState() ConnectionState {
+	return ConnectionState(c.state.Load())
+}
+
+func (c *Client) onConnected() {
+	c.setState(StateConnected)
+	if fn := c.opts.hooks.OnConnected; fn != nil {
+		fn(c.zipperAddr)
+	}
+}
+
+func (c *Client) onDisconnected(err error) {
+	if c.ctx.Err() != nil {
+		c.setState(StateClosed)
+	} else {
+		c.setState(StateDisconnected)
+	}
+	if fn := c.opts.hooks.OnDisconnected; fn != nil {
+		fn(err)
+	}
+}
+
+func (c *Client) onReconnecting(attempt int) {
+	if c.ctx.Err() != nil {
+		return
+	}
+	c.setState(StateReconnecting)
+	if fn := c.opts.hooks.OnReconnecting; fn != nil {
+		fn(attempt)
+	}
+}
+
+func (c *Client) onRejected(reason string) {
+	c.setState(StateClosed)
+	if fn := c.opts.hooks.OnRejected; fn != nil {
+		fn(reason)
+	}
+}
+
+func (c *Client) onRedirect(endpoint string) {
+	if fn := c.opts.hooks.OnRedirect; fn != nil {
+		fn(endpoint)
+	}
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHooks records the calls of the hooks.
+type recordingHooks struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recordingHooks) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *recordingHooks) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.calls...)
+}
+
+func (r *recordingHooks) hooks() Hooks {
+	return Hooks{
+		OnConnected:    func(endpoint string) { r.record("connected " + endpoint) },
+		OnDisconnected: func(err error) { r.record("disconnected") },
+		OnReconnecting: func(attempt int) { r.record("reconnecting") },
+		OnRejected:     func(reason string) { r.record("rejected") },
+		OnRedirect:     func(endpoint string) { r.record("redirect " + endpoint) },
+	}
+}
+
+func TestClientHooks(t *testing.T) {
+	t.Parallel()
+
+	const hooksAddr = "127.0.0.1:19985"
+
+	server := NewServer("zipper", WithServerLogger(discardingLogger), WithAuth("token", "auth-token"))
+	go server.ListenAndServe(context.TODO(), hooksAddr)
+	defer server.Close()
+
+	t.Run("rejected", func(t *testing.T) {
+		r := &recordingHooks{}
+		source := NewClient("source", hooksAddr, ClientTypeSource,
+			WithCredential("token:wrong-token"), WithLogger(discardingLogger), WithHooks(r.hooks()))
+		assert.Equal(t, StateDisconnected, source.ConnectionState())
+
+		assert.Error(t, source.Connect(context.TODO()))
+		assert.Equal(t, []string{"rejected"}, r.Calls())
+		assert.Equal(t, StateClosed, source.ConnectionState())
+	})
+
+	t.Run("connected and closed", func(t *testing.T) {
+		r := &recordingHooks{}
+		source := NewClient("source", hooksAddr, ClientTypeSource,
+			WithCredential("token:auth-token"), WithLogger(discardingLogger), WithHooks(r.hooks()))
+
+		assert.NoError(t, source.Connect(context.TODO()))
+		assert.Equal(t, StateConnected, source.ConnectionState())
+		assert.True(t, source.Connected())
+
+		assert.NoError(t, source.Close())
+		assert.Equal(t, StateClosed, source.ConnectionState())
+		assert.Eventually(t, func() bool {
+			calls := r.Calls()
+			return len(calls) == 2 && calls[0] == "connected "+hooksAddr && calls[1] == "disconnected"
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestConnectionStateString(t *testing.T) {
+	assert.Equal(t, "Connected", StateConnected.String())
+	assert.Equal(t, "Reconnecting", StateReconnecting.String())
+	assert.Equal(t, "Unknown", ConnectionState(100).String())
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/yomorun/yomo/core/metadata"
 )
 
@@ -23,3 +26,21 @@ func GetTIDFromMetadata(m metadata.M) string {
 func SetMetadataTarget(m metadata.M, target string) {
 	m.Set(metadata.TargetKey, target)
 }
+
+// SetMetadataDeadline sets the deadline in metadata, it is stored as unix milliseconds.
+func SetMetadataDeadline(m metadata.M, deadline time.Time) {
+	m.Set(metadata.DeadlineKey, strconv.FormatInt(deadline.UnixMilli(), 10))
+}
+
+// GetDeadlineFromMetadata gets the deadline from metadata, it returns false if there is no valid deadline.
+func GetDeadlineFromMetadata(m metadata.M) (time.Time, bool) {
+	v, ok := m.Get(metadata.DeadlineKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
@@ -2,6 +2,9 @@
 package metadata
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/vmihailenco/msgpack/v5"
 )
 
@@ -89,8 +92,58 @@ const (
 	// the keys for tracing.
 	TraceIDKey = "yomo-trace-id"
 	SpanIDKey  = "yomo-span-id"
+	// TraceParentKey is the W3C trace context, it joins the traces from HTTP frontends.
+	TraceParentKey = "traceparent"
+
+	// SerializerKey is the name of the serializer of the typed data, it is not reserved,
+	// so the data encoded by other producers can carry it.
+	SerializerKey = "serializer"
+
+	// DeadlineKey is the deadline of the data, the data is not handled after the deadline.
+	DeadlineKey = "yomo-deadline"
+
+	// the keys of the data written to the dead-letter tag, when the stream function handler fails.
+	DeadLetterErrorKey    = "yomo-dead-letter-error"
+	DeadLetterTagKey      = "yomo-dead-letter-tag"
+	DeadLetterSfnKey      = "yomo-dead-letter-sfn"
+	DeadLetterAttemptsKey = "yomo-dead-letter-attempts"
+
+	// BatchKey is the number of the data packed in the batch data frame, the frame without it is not a batch.
+	BatchKey = "yomo-batch"
 
 	// the keys for target system working.
 	TargetKey       = "yomo-target"
 	WantedTargetKey = "yomo-wanted-target"
+
+	// the key for tenant isolation, it is set by authentication.
+	NamespaceKey = "yomo-namespace"
+	// MeshKey marks the connection authenticated by a mesh credential, it is set by authentication,
+	// only the zippers connected with it are trusted to forward the data frames of other namespaces.
+	MeshKey = "yomo-mesh"
 )
+
+// GetNamespace returns the namespace of the metadata, the empty string is the default namespace.
+func GetNamespace(m M) string {
+	ns, _ := m.Get(NamespaceKey)
+	return ns
+}
+
+// IsMesh reports whether the metadata is of the connection authenticated by a mesh credential.
+func IsMesh(m M) bool {
+	v, _ := m.Get(MeshKey)
+	return v == "true"
+}
+
+// ReservedKeyPrefix is the prefix of the metadata keys reserved by yomo.
+const ReservedKeyPrefix = "yomo-"
+
+// ErrReservedKey is returned when write a reserved metadata key.
+var ErrReservedKey = errors.New("the metadata keys prefixed with yomo- are reserved; please do not write them")
+
+// IsReservedKey returns error when write a reserved metadata key.
+func IsReservedKey(k string) error {
+	if strings.HasPrefix(k, ReservedKeyPrefix) {
+		return ErrReservedKey
+	}
+	return nil
+}
@@ -92,3 +92,9 @@ func Test(t *testing.T) {
 		})
 	})
 }
+
+func TestIsReservedKey(t *testing.T) {
+	assert.NoError(t, IsReservedKey("user-key"))
+	assert.ErrorIs(t, IsReservedKey(TargetKey), ErrReservedKey)
+	assert.ErrorIs(t, IsReservedKey("yomo-custom"), ErrReservedKey)
+}
@@ -2,9 +2,12 @@ package core
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/core/ylog"
 )
 
 func TestMetadata(t *testing.T) {
@@ -16,4 +19,56 @@ func TestMetadata(t *testing.T) {
 	assert.Equal(t, "target", v)
 
 	assert.Equal(t, "tid", GetTIDFromMetadata(md))
+
+	_, ok = GetDeadlineFromMetadata(md)
+	assert.False(t, ok)
+
+	deadline := time.UnixMilli(time.Now().Add(time.Second).UnixMilli())
+	SetMetadataDeadline(md, deadline)
+	got, ok := GetDeadlineFromMetadata(md)
+	assert.True(t, ok)
+	assert.Equal(t, deadline, got)
+
+	md.Set(metadata.DeadlineKey, "invalid")
+	_, ok = GetDeadlineFromMetadata(md)
+	assert.False(t, ok)
+}
+
+func TestContextNamespace(t *testing.T) {
+	spoofed, _ := metadata.M{metadata.NamespaceKey: "tenant-b"}.Encode()
+
+	t.Run("from connection", func(t *testing.T) {
+		conn := NewConnection(1, "source", "id", ClientTypeSource, metadata.M{metadata.NamespaceKey: "tenant-a"}, nil, nil, ylog.Default())
+		c, err := newContext(conn, &frame.DataFrame{Tag: 1, Metadata: spoofed})
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant-a", metadata.GetNamespace(c.FrameMetadata))
+		c.Release()
+	})
+
+	t.Run("default namespace", func(t *testing.T) {
+		conn := NewConnection(2, "source", "id", ClientTypeSource, metadata.M{}, nil, nil, ylog.Default())
+		c, err := newContext(conn, &frame.DataFrame{Tag: 1, Metadata: spoofed})
+		assert.NoError(t, err)
+		assert.Equal(t, "", metadata.GetNamespace(c.FrameMetadata))
+		c.Release()
+	})
+
+	t.Run("forwarded by upstream zipper", func(t *testing.T) {
+		md := metadata.M{metadata.NamespaceKey: "mesh", metadata.MeshKey: "true"}
+		conn := NewConnection(3, "zipper", "id", ClientTypeUpstreamZipper, md, nil, nil, ylog.Default())
+		c, err := newContext(conn, &frame.DataFrame{Tag: 1, Metadata: spoofed})
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant-b", metadata.GetNamespace(c.FrameMetadata))
+		_, ok := c.FrameMetadata.Get(metadata.MeshKey)
+		assert.False(t, ok)
+		c.Release()
+	})
+
+	t.Run("upstream zipper without mesh credential", func(t *testing.T) {
+		conn := NewConnection(4, "zipper", "id", ClientTypeUpstreamZipper, metadata.M{metadata.NamespaceKey: "tenant-a"}, nil, nil, ylog.Default())
+		c, err := newContext(conn, &frame.DataFrame{Tag: 1, Metadata: spoofed})
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant-a", metadata.GetNamespace(c.FrameMetadata))
+		c.Release()
+	})
 }
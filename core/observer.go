@@ -0,0 +1,95 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yomorun/yomo/core/frame"
+)
+
+// The reasons of handshake failures that are reported to the Observer.
+const (
+	HandshakeFailedVersion        = "version"
+	HandshakeFailedAuthentication = "authentication"
+	HandshakeFailedAuthorization  = "authorization"
+	HandshakeFailedConnection     = "connection"
+	HandshakeFailedRoute          = "route"
+	HandshakeFailedCron           = "cron"
+	HandshakeFailedUnexpected     = "unexpected_frame"
+)
+
+// ErrFrameDenied is reported to the Observer when the data frame violates the ACL.
+var ErrFrameDenied = errors.New("yomo: frame denied by acl")
+
+// Observer observes the connections and the data frames of the server, it is used to collect metrics.
+// The methods are called synchronously in the hot path, so they should return quickly.
+type Observer interface {
+	// ConnOpened is called after the connection has finished the handshake.
+	ConnOpened(conn ConnectionInfo)
+	// ConnClosed is called after the connection has been closed.
+	ConnClosed(conn ConnectionInfo)
+	// HandshakeFailed is called when the handshake fails, the reason is one of the HandshakeFailedXXX constants.
+	HandshakeFailed(clientType ClientType, reason string)
+	// FrameReceived is called after the data frame written by the connection has been handled,
+	// the elapsed is the time spent in routing the frame, the err is not nil if the frame is not handled.
+	FrameReceived(from ConnectionInfo, tag frame.Tag, size int, elapsed time.Duration, err error)
+	// FrameRouted is called after the data frame has been written to the stream function connection.
+	FrameRouted(to ConnectionInfo, tag frame.Tag, size int, err error)
+	// FrameForwarded is called after the data frame has been written to the downstream zipper.
+	FrameForwarded(downstream string, tag frame.Tag, size int, err error)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) ConnOpened(ConnectionInfo)                                          {}
+func (nopObserver) ConnClosed(ConnectionInfo)                                          {}
+func (nopObserver) HandshakeFailed(ClientType, string)                                 {}
+func (nopObserver) FrameReceived(ConnectionInfo, frame.Tag, int, time.Duration, error) {}
+func (nopObserver) FrameRouted(ConnectionInfo, frame.Tag, int, error)                  {}
+func (nopObserver) FrameForwarded(string, frame.Tag, int, error)                       {}
+
+// MultiObserver returns an Observer that reports to all the observers in order.
+func MultiObserver(observers ...Observer) Observer {
+	if len(observers) == 1 {
+		return observers[0]
+	}
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) ConnOpened(conn ConnectionInfo) {
+	for _, o := range m {
+		o.ConnOpened(conn)
+	}
+}
+
+func (m multiObserver) ConnClosed(conn ConnectionInfo) {
+	for _, o := range m {
+		o.ConnClosed(conn)
+	}
+}
+
+func (m multiObserver) HandshakeFailed(clientType ClientType, reason string) {
+	for _, o := range m {
+		o.HandshakeFailed(clientType, reason)
+	}
+}
+
+func (m multiObserver) FrameReceived(from ConnectionInfo, tag frame.Tag, size int, elapsed time.Duration, err error) {
+	for _, o := range m {
+		o.FrameReceived(from, tag, size, elapsed, err)
+	}
+}
+
+func (m multiObserver) FrameRouted(to ConnectionInfo, tag frame.Tag, size int, err error) {
+	for _, o := range m {
+		o.FrameRouted(to, tag, size, err)
+	}
+}
+
+func (m multiObserver) FrameForwarded(downstream string, tag frame.Tag, size int, err error) {
+	for _, o := range m {
+		o.FrameForwarded(downstream, tag, size, err)
+	}
+}
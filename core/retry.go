@@ -0,0 +1,48 @@
+package core
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// RetryPolicy decides how the stream function handler is retried when it returns an error.
+type RetryPolicy struct {
+	// MaxAttempts is the number of invocations of the handler for a data frame, including the first one,
+	// the handler is not retried if it is less than 2.
+	MaxAttempts int
+	// NewBackOff returns the backoff between the attempts of a data frame, it is called for every data frame.
+	// The default backoff is an exponential backoff with jitter, starting from 100ms up to 10s.
+	NewBackOff func() backoff.BackOff
+	// Retryable reports whether the handler should be retried for the error, all errors are retryable if it is nil.
+	Retryable func(err error) bool
+}
+
+// BackOff returns the backoff of the policy, it is the default backoff if NewBackOff is not set.
+func (p RetryPolicy) BackOff() backoff.BackOff {
+	if p.NewBackOff != nil {
+		return p.NewBackOff()
+	}
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 100 * time.Millisecond
+	b.MaxInterval = 10 * time.Second
+	b.MaxElapsedTime = 0
+	b.Reset()
+	return b
+}
+
+// NextDelay returns the delay before the next attempt, it returns false if the handler should not be retried,
+// the attempt is the number of invocations that have been made.
+func (p RetryPolicy) NextDelay(b backoff.BackOff, attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if p.Retryable != nil && !p.Retryable(err) {
+		return 0, false
+	}
+	d := b.NextBackOff()
+	if d == backoff.Stop {
+		return 0, false
+	}
+	return d, true
+}
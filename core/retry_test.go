@@ -0,0 +1,37 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	errPermanent := errors.New("permanent")
+
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		NewBackOff:  func() backoff.BackOff { return backoff.NewConstantBackOff(time.Millisecond) },
+		Retryable:   func(err error) bool { return !errors.Is(err, errPermanent) },
+	}
+	b := p.BackOff()
+
+	d, ok := p.NextDelay(b, 1, errors.New("temporary"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond, d)
+
+	_, ok = p.NextDelay(b, 3, errors.New("temporary"))
+	assert.False(t, ok)
+
+	_, ok = p.NextDelay(b, 1, errPermanent)
+	assert.False(t, ok)
+
+	_, ok = RetryPolicy{}.NextDelay(RetryPolicy{}.BackOff(), 1, errors.New("temporary"))
+	assert.False(t, ok)
+
+	_, ok = RetryPolicy{MaxAttempts: 2}.NextDelay(&backoff.StopBackOff{}, 1, errors.New("temporary"))
+	assert.False(t, ok)
+}
@@ -21,6 +21,12 @@ type Router interface {
 	Release()
 }
 
+// Snapshotter is implemented by the Router that can list its route rules.
+type Snapshotter interface {
+	// Snapshot returns the route rules, the key is the data tag and the value is the ID list of connections.
+	Snapshot() map[uint32][]uint64
+}
+
 type defaultRouter struct {
 	// mu protects data.
 	mu sync.RWMutex
@@ -28,17 +34,23 @@ type defaultRouter struct {
 	// targets stores the mapping between connID and the target string that conn wanted.
 	targets map[uint64]string
 
+	// namespaces stores the mapping between connID and the namespace of conn,
+	// the conns in the default namespace are not stored.
+	namespaces map[uint64]string
+
 	// data stores tag and connID connection.
 	// The key is frame tag, The value is connID connection.
 	data map[frame.Tag]map[uint64]struct{}
 }
 
 // Default provides a default implementation of `router`,
-// It routes data according to observed tag and metadata.
+// It routes data according to observed tag and metadata,
+// the data is only routed to the connections in the same namespace as the data.
 func Default() Router {
 	return &defaultRouter{
-		targets: make(map[uint64]string),
-		data:    make(map[frame.Tag]map[uint64]struct{}),
+		targets:    make(map[uint64]string),
+		namespaces: make(map[uint64]string),
+		data:       make(map[frame.Tag]map[uint64]struct{}),
 	}
 }
 
@@ -51,6 +63,10 @@ func (r *defaultRouter) Add(connID uint64, observeDataTags []uint32, md metadata
 		r.targets[connID] = target
 	}
 
+	if ns := metadata.GetNamespace(md); ns != "" {
+		r.namespaces[connID] = ns
+	}
+
 	for _, tag := range observeDataTags {
 		conns := r.data[tag]
 		if conns == nil {
@@ -68,10 +84,14 @@ func (r *defaultRouter) Route(dataTag uint32, md metadata.M) []uint64 {
 	defer r.mu.RUnlock()
 
 	target, existed := md.Get(metadata.TargetKey)
+	ns := metadata.GetNamespace(md)
 
 	var connID []uint64
 	if conns, ok := r.data[dataTag]; ok {
 		for k := range conns {
+			if r.namespaces[k] != ns {
+				continue
+			}
 			if existed {
 				if wt, ok := r.targets[k]; ok && wt == target {
 					connID = append(connID, k)
@@ -85,11 +105,30 @@ func (r *defaultRouter) Route(dataTag uint32, md metadata.M) []uint64 {
 	return connID
 }
 
+func (r *defaultRouter) Snapshot() map[uint32][]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[uint32][]uint64, len(r.data))
+	for tag, conns := range r.data {
+		if len(conns) == 0 {
+			continue
+		}
+		ids := make([]uint64, 0, len(conns))
+		for id := range conns {
+			ids = append(ids, id)
+		}
+		result[tag] = ids
+	}
+	return result
+}
+
 func (r *defaultRouter) Remove(connID uint64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	delete(r.targets, connID)
+	delete(r.namespaces, connID)
 
 	for _, conns := range r.data {
 		delete(conns, connID)
@@ -101,5 +140,6 @@ func (r *defaultRouter) Release() {
 	defer r.mu.Unlock()
 
 	clear(r.targets)
+	clear(r.namespaces)
 	clear(r.data)
 }
@@ -22,6 +22,9 @@ func TestRouter(t *testing.T) {
 	ids := router.Route(1, nil)
 	assert.ElementsMatch(t, []uint64{1, 2, 3}, ids)
 
+	snapshot := router.(Snapshotter).Snapshot()
+	assert.ElementsMatch(t, []uint64{1, 2, 3}, snapshot[1])
+
 	router.Remove(1)
 
 	ids = router.Route(1, nil)
@@ -56,3 +59,30 @@ func TestTargetRouter(t *testing.T) {
 	ids = router.Route(1, nil)
 	assert.Equal(t, []uint64(nil), ids)
 }
+
+func TestNamespaceRouter(t *testing.T) {
+	router := Default()
+
+	err := router.Add(1, []uint32{1}, metadata.M{metadata.NamespaceKey: "tenant-a"})
+	assert.NoError(t, err)
+
+	err = router.Add(2, []uint32{1}, metadata.M{metadata.NamespaceKey: "tenant-b"})
+	assert.NoError(t, err)
+
+	err = router.Add(3, []uint32{1}, metadata.M{})
+	assert.NoError(t, err)
+
+	ids := router.Route(1, metadata.M{metadata.NamespaceKey: "tenant-a"})
+	assert.ElementsMatch(t, []uint64{1}, ids)
+
+	ids = router.Route(1, metadata.M{metadata.NamespaceKey: "tenant-b"})
+	assert.ElementsMatch(t, []uint64{2}, ids)
+
+	ids = router.Route(1, metadata.M{})
+	assert.ElementsMatch(t, []uint64{3}, ids)
+
+	router.Remove(1)
+
+	ids = router.Route(1, metadata.M{metadata.NamespaceKey: "tenant-a"})
+	assert.Equal(t, []uint64(nil), ids)
+}
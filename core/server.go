@@ -2,25 +2,34 @@ package core
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/yomorun/yomo/ai"
+	"github.com/yomorun/yomo/core/acl"
 	"github.com/yomorun/yomo/core/auth"
 	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/core/metadata"
 	"github.com/yomorun/yomo/core/router"
 
-	// authentication implements, Currently, only token authentication is implemented
+	// authentication implements, Currently, token, jwt and mtls authentication are implemented
 	_ "github.com/yomorun/yomo/pkg/auth"
 	"github.com/yomorun/yomo/pkg/frame-codec/y3codec"
 	yquic "github.com/yomorun/yomo/pkg/listener/quic"
 	pkgtls "github.com/yomorun/yomo/pkg/tls"
+	ytrace "github.com/yomorun/yomo/pkg/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrServerClosed is returned by the Server's Serve and ListenAndServe methods after a call to Shutdown or Close.
@@ -58,6 +67,11 @@ type Server struct {
 	listener             frame.Listener
 	logger               *slog.Logger
 	versionNegotiateFunc VersionNegotiateFunc
+	taps                 taps
+	tracer               trace.Tracer
+	cron                 *cronScheduler
+	closers              []io.Closer
+	closeOnce            sync.Once
 }
 
 // NewServer create a Server instance.
@@ -84,6 +98,7 @@ func NewServer(name string, opts ...ServerOption) *Server {
 		packetReadWriter:     y3codec.PacketReadWriter(),
 		opts:                 options,
 		versionNegotiateFunc: options.versionNegotiateFunc,
+		cron:                 newCronScheduler(options.cronMissedPolicy, logger),
 	}
 
 	if s.router == nil {
@@ -95,6 +110,10 @@ func NewServer(name string, opts ...ServerOption) *Server {
 	if s.versionNegotiateFunc == nil {
 		s.versionNegotiateFunc = DefaultVersionNegotiateFunc
 	}
+	if s.opts.tracerProvider == nil {
+		s.opts.tracerProvider = otel.GetTracerProvider()
+	}
+	s.tracer = s.opts.tracerProvider.Tracer("yomo-zipper")
 
 	// work with middleware.
 	s.connHandler = composeConnHandler(s.handleConn, s.opts.connMiddlewares...)
@@ -142,6 +161,7 @@ func (s *Server) Serve(ctx context.Context, conn net.PacketConn) error {
 		"zipper_addr", conn.LocalAddr().String(), "pid", os.Getpid(), "quic", s.opts.quicConfig.Versions, "auth_name", s.authNames())
 
 	defer closeServer(s.downstreams, s.connector, s.listener, s.router)
+	defer s.cron.close()
 
 	for {
 		fconn, err := s.listener.Accept(s.ctx)
@@ -167,10 +187,21 @@ func (s *Server) handleFrameConn(fconn frame.Conn, logger *slog.Logger) {
 	// ack handshake
 	_ = fconn.WriteFrame(&frame.HandshakeAckFrame{})
 
+	// the cron handler is added after the ack, so the client reads the ack before the triggers.
+	if conn.cronSpec != "" {
+		if err := s.cron.add(conn, conn.cronSpec); err != nil {
+			conn.Logger.Error("failed to schedule cron handler", "err", err, "cron_spec", conn.cronSpec)
+		}
+	}
+
+	s.opts.observer.ConnOpened(conn)
+	defer s.opts.observer.ConnClosed(conn)
+
 	s.connHandler(conn) // s.handleConn(conn) with middlewares
 
 	if conn.ClientType() == ClientTypeStreamFunction {
 		s.router.Remove(conn.ID())
+		s.cron.remove(conn.ID())
 	}
 	_ = s.connector.Remove(conn.ID())
 }
@@ -208,9 +239,11 @@ func (s *Server) handshake(fconn frame.Conn) (*Connection, error) {
 	case frame.TypeHandshakeFrame:
 
 		hf := first.(*frame.HandshakeFrame)
+		clientType := ClientType(hf.ClientType)
 
 		// 1. version negotiation
 		if err := s.versionNegotiateFunc(hf.Version, Version); err != nil {
+			s.opts.observer.HandshakeFailed(clientType, HandshakeFailedVersion)
 			if se := new(ErrConnectTo); errors.As(err, &se) {
 				return nil, connectToNewEndpoint(fconn, se)
 			}
@@ -218,28 +251,46 @@ func (s *Server) handshake(fconn frame.Conn) (*Connection, error) {
 		}
 
 		// 2. authentication
-		md, err := s.authenticate(hf)
+		md, err := s.authenticate(hf, tlsConnectionState(fconn))
 		if err != nil {
+			s.opts.observer.HandshakeFailed(clientType, HandshakeFailedAuthentication)
 			return nil, rejectHandshake(fconn, err)
 		}
 
-		// 3. create connection
+		// 3. authorization
+		if err := s.authorizeSubscribe(hf, md); err != nil {
+			s.opts.observer.HandshakeFailed(clientType, HandshakeFailedAuthorization)
+			return nil, rejectHandshake(fconn, err)
+		}
+
+		// 4. check the cron spec, the cron handler is scheduled after the handshake is acked
+		if hf.CronSpec != "" {
+			if err := validateCronSpec(hf.CronSpec); err != nil {
+				s.opts.observer.HandshakeFailed(clientType, HandshakeFailedCron)
+				return nil, rejectHandshake(fconn, fmt.Errorf("yomo: invalid cron spec %q: %w", hf.CronSpec, err))
+			}
+		}
+
+		// 5. create connection
 		conn, err := s.createConnection(hf, md, fconn)
 		if err != nil {
+			s.opts.observer.HandshakeFailed(clientType, HandshakeFailedConnection)
 			return nil, rejectHandshake(fconn, err)
 		}
 
-		// 4. store function definition to metadata
+		// 6. store function definition to metadata
 		if hf.FunctionDefinition != nil {
 			conn.Metadata().Set(ai.FunctionDefinitionKey, string(hf.FunctionDefinition))
 		}
 
-		// 5. add route rules
+		// 7. add route rules
 		if err := s.addSfnRouteRule(conn.ID(), hf, conn.Metadata()); err != nil {
+			s.opts.observer.HandshakeFailed(clientType, HandshakeFailedRoute)
 			return nil, rejectHandshake(fconn, err)
 		}
 		return conn, nil
 	default:
+		s.opts.observer.HandshakeFailed(ClientType(0), HandshakeFailedUnexpected)
 		err = fmt.Errorf("yomo: handshake read unexpected frame, read: %s", first.Type().String())
 		return nil, rejectHandshake(fconn, err)
 	}
@@ -262,9 +313,31 @@ func (s *Server) handleConn(conn *Connection) {
 				return
 			}
 
-			s.frameHandler(c) // s.handleFrame(c) with middlewares
+			var (
+				tag   = c.Frame.Tag
+				size  = len(c.Frame.Payload)
+				start = time.Now()
+			)
+			if s.authorizePublish(c) {
+				s.frameHandler(c) // s.handleFrame(c) with middlewares
+			} else {
+				c.err = ErrFrameDenied
+			}
+			s.opts.observer.FrameReceived(conn, tag, size, time.Since(start), c.err)
 
 			c.Release()
+		case frame.TypeCronTriggerFrame:
+			// the stream function acks the trigger by echoing it.
+			s.cron.ack(conn.ID(), time.UnixMilli(f.(*frame.CronTriggerFrame).Time))
+		case frame.TypeUnsubscribeFrame:
+			// the connection is kept, so the stream function can still write the results of in-flight frames.
+			s.router.Remove(conn.ID())
+			s.cron.remove(conn.ID())
+			conn.Logger.Info("client unsubscribed")
+			if err := conn.FrameConn().WriteFrame(f); err != nil {
+				conn.Logger.Info("failed to ack unsubscribe", "err", err)
+				return
+			}
 		default:
 			conn.Logger.Info("unexpected frame", "type", f.Type().String())
 			return
@@ -272,8 +345,8 @@ func (s *Server) handleConn(conn *Connection) {
 	}
 }
 
-func (s *Server) authenticate(hf *frame.HandshakeFrame) (metadata.M, error) {
-	md, err := auth.Authenticate(s.opts.auths, hf)
+func (s *Server) authenticate(hf *frame.HandshakeFrame, state *tls.ConnectionState) (metadata.M, error) {
+	md, err := auth.AuthenticateTLS(s.opts.auths, hf, state)
 	if err != nil {
 		s.logger.Warn(
 			"authentication failed",
@@ -287,6 +360,59 @@ func (s *Server) authenticate(hf *frame.HandshakeFrame) (metadata.M, error) {
 	return md, nil
 }
 
+// authorizeSubscribe checks whether the client can observe all the tags it wants to observe.
+func (s *Server) authorizeSubscribe(hf *frame.HandshakeFrame, md metadata.M) error {
+	if s.opts.acl == nil {
+		return nil
+	}
+	for _, tag := range hf.ObserveDataTags {
+		if !s.opts.acl.AllowSubscribe(md, tag) {
+			s.logger.Warn(
+				"acl: permission denied", "audit", "acl", "operation", "subscribe", "tag", tag,
+				"client_type", ClientType(hf.ClientType).String(), "client_name", hf.Name, "metadata", md,
+			)
+			return fmt.Errorf("acl: permission denied to observe tag %d", tag)
+		}
+	}
+	return nil
+}
+
+// authorizePublish checks whether the connection can write the data frame, it returns false if the data
+// frame violates the ACL, the frame is dropped, and the writer is answered with a RejectedFrame if the
+// acl action is reject.
+func (s *Server) authorizePublish(c *Context) bool {
+	if s.opts.acl == nil {
+		return true
+	}
+	tag := c.Frame.Tag
+	if s.opts.acl.AllowPublish(c.Connection.Metadata(), tag) {
+		return true
+	}
+
+	c.Logger.Warn(
+		"acl: permission denied", "audit", "acl", "operation", "publish", "tag", tag, "action", s.opts.aclAction,
+		"client_type", c.Connection.ClientType().String(), "metadata", c.Connection.Metadata(),
+	)
+	if s.opts.aclAction != acl.ActionDrop {
+		rf := &frame.RejectedFrame{Message: fmt.Sprintf("acl: permission denied to write tag %d", tag)}
+		if err := c.Connection.FrameConn().WriteFrame(rf); err != nil {
+			c.Logger.Error("failed to write rejected frame", "err", err)
+		}
+	}
+	return false
+}
+
+// tlsConnectionState returns the TLS connection state of the frame conn,
+// It returns nil if the frame conn is not established over TLS.
+func tlsConnectionState(fconn frame.Conn) *tls.ConnectionState {
+	tc, ok := fconn.(interface{ ConnectionState() tls.ConnectionState })
+	if !ok {
+		return nil
+	}
+	state := tc.ConnectionState()
+	return &state
+}
+
 func (s *Server) createConnection(hf *frame.HandshakeFrame, md metadata.M, fconn frame.Conn) (*Connection, error) {
 	if hf.WantedTarget != "" {
 		md.Set(metadata.WantedTargetKey, hf.WantedTarget)
@@ -301,6 +427,9 @@ func (s *Server) createConnection(hf *frame.HandshakeFrame, md metadata.M, fconn
 		fconn,
 		s.logger,
 	)
+	if ClientType(hf.ClientType) == ClientTypeStreamFunction {
+		conn.cronSpec = hf.CronSpec
+	}
 
 	return conn, s.connector.Store(conn.ID(), conn)
 }
@@ -313,20 +442,41 @@ func (s *Server) addSfnRouteRule(connID uint64, hf *frame.HandshakeFrame, md met
 }
 
 func (s *Server) handleFrame(c *Context) {
+	// continue the trace from the metadata, the routing span becomes the parent of the spans
+	// of the stream functions and the downstream zippers.
+	ctx, span := s.tracer.Start(
+		ytrace.NewContextWithMetadata(c.FrameMetadata),
+		"zipper.route",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("zipper_name", s.name),
+			attribute.Int("tag", int(c.Frame.Tag)),
+			attribute.Int("data_length", len(c.Frame.Payload)),
+			attribute.String("from_name", c.Connection.Name()),
+			attribute.String("from_client_type", c.Connection.ClientType().String()),
+		),
+	)
+	defer span.End()
+	ytrace.InjectMetadata(c.FrameMetadata, span.SpanContext())
+
 	// routing data frame.
-	if err := s.routingDataFrame(c); err != nil {
+	if err := s.routingDataFrame(ctx, c); err != nil {
+		c.err = err
+		recordSpanError(span, err)
 		c.CloseWithError(fmt.Sprintf("handle dataFrame err: %v", err))
 		return
 	}
 
 	// dispatch to downstream.
-	if err := s.dispatchToDownstreams(c); err != nil {
+	if err := s.dispatchToDownstreams(ctx, c); err != nil {
+		c.err = err
+		recordSpanError(span, err)
 		c.CloseWithError(fmt.Sprintf("dispatch to downstream err: %v", err))
 		return
 	}
 }
 
-func (s *Server) routingDataFrame(c *Context) error {
+func (s *Server) routingDataFrame(ctx context.Context, c *Context) error {
 	dataFrame := c.Frame
 	dataLength := len(dataFrame.Payload)
 
@@ -335,7 +485,7 @@ func (s *Server) routingDataFrame(c *Context) error {
 
 	mdBytes, err := c.FrameMetadata.Encode()
 	if err != nil {
-		c.Logger.Error("encode metadata error", "err", err)
+		c.Logger.ErrorContext(ctx, "encode metadata error", "err", err)
 		return err
 	}
 	dataFrame.Metadata = mdBytes
@@ -343,9 +493,10 @@ func (s *Server) routingDataFrame(c *Context) error {
 	// find stream function ids from the router.
 	connIDs := s.router.Route(dataFrame.Tag, c.FrameMetadata)
 	if len(connIDs) == 0 {
-		c.Logger.Info("no observed", "tag", dataFrame.Tag, "data_length", dataLength)
+		c.Logger.InfoContext(ctx, "no observed", "tag", dataFrame.Tag, "data_length", dataLength)
 	}
-	c.Logger.Debug("connector snapshot", "tag", dataFrame.Tag, "sfn_conn_ids", connIDs, "connector", s.connector.Snapshot())
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("fan_out", len(connIDs)))
+	c.Logger.DebugContext(ctx, "connector snapshot", "tag", dataFrame.Tag, "sfn_conn_ids", connIDs, "connector", s.connector.Snapshot())
 
 	for _, toID := range connIDs {
 		conn, ok, err := s.connector.Get(toID)
@@ -353,53 +504,77 @@ func (s *Server) routingDataFrame(c *Context) error {
 			continue
 		}
 		if !ok {
-			c.Logger.Error("can't find forward conn", "to_id", toID)
+			c.Logger.ErrorContext(ctx, "can't find forward conn", "to_id", toID)
+			continue
+		}
+		if ns := metadata.GetNamespace(c.FrameMetadata); metadata.GetNamespace(conn.Metadata()) != ns {
+			c.Logger.WarnContext(ctx, "skip the conn in other namespace", "to_id", toID, "to_name", conn.Name(), "namespace", ns)
 			continue
 		}
 
 		// write data frame to conn
-		if err := conn.FrameConn().WriteFrame(dataFrame); err != nil {
-			c.Logger.Error(
+		_, span := s.tracer.Start(
+			ctx,
+			"zipper.deliver",
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(attribute.String("to_name", conn.Name()), attribute.Int64("to_id", int64(toID))),
+		)
+		err = conn.FrameConn().WriteFrame(dataFrame)
+		endSpan(span, err)
+		s.opts.observer.FrameRouted(conn, dataFrame.Tag, dataLength, err)
+		if err != nil {
+			c.Logger.ErrorContext(ctx,
 				"failed to route data", "err", err,
 				"tag", dataFrame.Tag, "data_length", dataLength, "to_id", toID, "to_name", conn.Name(),
 			)
 		} else {
-			c.Logger.Info(
+			c.Logger.InfoContext(ctx,
 				"data routing",
 				"tag", dataFrame.Tag, "data_length", dataLength, "to_id", toID, "to_name", conn.Name(),
 			)
 		}
 	}
 
+	s.taps.call(c.Connection, dataFrame, c.FrameMetadata)
+
 	return nil
 }
 
 // dispatch every DataFrames to all downstreams
-func (s *Server) dispatchToDownstreams(c *Context) error {
+func (s *Server) dispatchToDownstreams(ctx context.Context, c *Context) error {
 	dataFrame := c.Frame
 	if c.Connection.ClientType() == ClientTypeUpstreamZipper {
-		c.Logger.Debug("ignored client", "client_type", c.Connection.ClientType().String())
+		c.Logger.DebugContext(ctx, "ignored client", "client_type", c.Connection.ClientType().String())
 		// loop protection
 		return nil
 	}
 
 	mdBytes, err := c.FrameMetadata.Encode()
 	if err != nil {
-		c.Logger.Error("failed to dispatch to downstream", "err", err)
+		c.Logger.ErrorContext(ctx, "failed to dispatch to downstream", "err", err)
 		return err
 	}
 	dataFrame.Metadata = mdBytes
 
 	for _, ds := range s.downstreams {
-		if err = ds.WriteFrame(dataFrame); err != nil {
-			c.Logger.Error(
+		_, span := s.tracer.Start(
+			ctx,
+			"zipper.forward",
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(attribute.String("downstream_name", ds.LocalName()), attribute.String("downstream_id", ds.ID())),
+		)
+		err = ds.WriteFrame(dataFrame)
+		endSpan(span, err)
+		s.opts.observer.FrameForwarded(ds.LocalName(), dataFrame.Tag, len(dataFrame.Payload), err)
+		if err != nil {
+			c.Logger.ErrorContext(ctx,
 				"failed to dispatch to downstream",
 				"err", err,
 				"tag", dataFrame.Tag, "data_length", len(dataFrame.Payload),
 				"downstream_id", ds.ID(), "downstream_name", ds.LocalName(),
 			)
 		} else {
-			c.Logger.Info(
+			c.Logger.InfoContext(ctx,
 				"dispatching to downstream",
 				"tag", dataFrame.Tag, "data_length", len(dataFrame.Payload),
 				"downstream_id", ds.ID(), "downstream_name", ds.LocalName(),
@@ -410,6 +585,19 @@ func (s *Server) dispatchToDownstreams(c *Context) error {
 	return nil
 }
 
+// endSpan ends the span, and records the error if it is not nil.
+func endSpan(span trace.Span, err error) {
+	recordSpanError(span, err)
+	span.End()
+}
+
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
 func closeServer(downstreams map[string]Downstream, connector Connector, listener frame.Listener, router router.Router) error {
 	for _, ds := range downstreams {
 		ds.Close()
@@ -451,6 +639,23 @@ func (s *Server) Downstreams() map[string]string {
 	return snapshotOfDownstream
 }
 
+// DownstreamStatus returns whether the downstream servers are connected, the key is the name of downstream.
+// The downstream that does not report its status is regarded as connected.
+func (s *Server) DownstreamStatus() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make(map[string]bool, len(s.downstreams))
+	for _, client := range s.downstreams {
+		connected := true
+		if c, ok := client.(interface{ Connected() bool }); ok {
+			connected = c.Connected()
+		}
+		status[client.LocalName()] = connected
+	}
+	return status
+}
+
 // AddDownstreamServer add a downstream server to this server. all the DataFrames will be
 // dispatch to all the downstreams.
 func (s *Server) AddDownstreamServer(c Downstream) {
@@ -459,14 +664,44 @@ func (s *Server) AddDownstreamServer(c Downstream) {
 	s.mu.Unlock()
 }
 
+// Connector returns the connector of server.
+func (s *Server) Connector() Connector {
+	return s.connector
+}
+
+// Router returns the router of server.
+func (s *Server) Router() router.Router {
+	return s.router
+}
+
 // Logger returns the logger of server.
 func (s *Server) Logger() *slog.Logger {
 	return s.logger
 }
 
+// AddCloser adds the closer that is closed when the server is closed, such as the recorder of the taps.
+func (s *Server) AddCloser(c io.Closer) {
+	s.mu.Lock()
+	s.closers = append(s.closers, c)
+	s.mu.Unlock()
+}
+
 // Close will shutdown the server.
 func (s *Server) Close() error {
 	s.ctxCancel()
+
+	s.closeOnce.Do(func() {
+		// the closers run without the lock, they may read the server, e.g. flushing the downstream metrics.
+		s.mu.Lock()
+		closers := s.closers
+		s.mu.Unlock()
+
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				s.logger.Error("failed to close", "err", err)
+			}
+		}
+	})
 	return nil
 }
 
@@ -6,9 +6,11 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go"
+	"github.com/yomorun/yomo/core/acl"
 	"github.com/yomorun/yomo/core/auth"
 	"github.com/yomorun/yomo/core/router"
 	"github.com/yomorun/yomo/core/ylog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DefaultQuicConfig be used when `quicConfig` is nil.
@@ -32,12 +34,17 @@ type serverOptions struct {
 	quicConfig           *quic.Config
 	tlsConfig            *tls.Config
 	auths                map[string]auth.Authentication
+	acl                  acl.ACL
+	aclAction            acl.Action
 	logger               *slog.Logger
 	connector            Connector
 	versionNegotiateFunc VersionNegotiateFunc
 	router               router.Router
 	connMiddlewares      []ConnMiddleware
 	frameMiddlewares     []FrameMiddleware
+	observer             Observer
+	tracerProvider       trace.TracerProvider
+	cronMissedPolicy     CronMissedPolicy
 }
 
 func defaultServerOptions() *serverOptions {
@@ -48,6 +55,7 @@ func defaultServerOptions() *serverOptions {
 		tlsConfig:  nil,
 		auths:      map[string]auth.Authentication{},
 		logger:     logger,
+		observer:   nopObserver{},
 	}
 	return opts
 }
@@ -65,6 +73,38 @@ func WithAuth(name string, args ...string) ServerOption {
 	}
 }
 
+// WithACL sets the publish/subscribe access control of tags for the server,
+// the action decides how to handle the data frames that violate the ACL.
+func WithACL(a acl.ACL, action acl.Action) ServerOption {
+	return func(o *serverOptions) {
+		o.acl = a
+		o.aclAction = action
+	}
+}
+
+// WithObserver sets the observer for the server, it is used to collect metrics.
+func WithObserver(observer Observer) ServerOption {
+	return func(o *serverOptions) {
+		o.observer = observer
+	}
+}
+
+// WithTracerProvider sets the otel tracer provider for the server, the server traces the routing,
+// fan-out and mesh forwarding of data frames. The global tracer provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) ServerOption {
+	return func(o *serverOptions) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithCronMissedPolicy sets how the server handles the missed ticks of the cron handlers it schedules,
+// the ticks are missed when no instance of the stream function is connected. The default is CronSkipMissed.
+func WithCronMissedPolicy(policy CronMissedPolicy) ServerOption {
+	return func(o *serverOptions) {
+		o.cronMissedPolicy = policy
+	}
+}
+
 // WithServerTLSConfig sets the TLS configuration for the server.
 func WithServerTLSConfig(tc *tls.Config) ServerOption {
 	return func(o *serverOptions) {
@@ -1,11 +1,25 @@
 package core
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/acl"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/core/router"
 	_ "github.com/yomorun/yomo/pkg/auth"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestRejectHandshake(t *testing.T) {
@@ -73,3 +87,282 @@ func TestConnectToNewEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestServerMTLSAuth(t *testing.T) {
+	t.Parallel()
+
+	const mtlsAddr = "127.0.0.1:19993"
+
+	pool := x509.NewCertPool()
+	ca, err := os.ReadFile("../test/tls/ca.crt")
+	assert.NoError(t, err)
+	assert.True(t, pool.AppendCertsFromPEM(ca))
+
+	serverCert, err := tls.LoadX509KeyPair("../test/tls/server.crt", "../test/tls/server.key")
+	assert.NoError(t, err)
+	clientCert, err := tls.LoadX509KeyPair("../test/tls/client.crt", "../test/tls/client.key")
+	assert.NoError(t, err)
+
+	mdCh := make(chan metadata.M, 1)
+	server := NewServer("zipper",
+		WithAuth("mtls", "allow=YoMo Client"),
+		WithServerLogger(discardingLogger),
+		WithServerTLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientCAs:    pool,
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+			NextProtos:   []string{"yomo"},
+		}),
+		WithConnMiddleware(func(next ConnHandler) ConnHandler {
+			return func(c *Connection) {
+				mdCh <- c.Metadata().Clone()
+				next(c)
+			}
+		}),
+	)
+	go server.ListenAndServe(context.TODO(), mtlsAddr)
+	defer server.Close()
+
+	// the client without certificate will be rejected.
+	source := NewClient("source", mtlsAddr, ClientTypeSource,
+		WithCredential("mtls:"),
+		WithLogger(discardingLogger),
+		WithClientTLSConfig(&tls.Config{RootCAs: pool, ServerName: "localhost", NextProtos: []string{"yomo"}}),
+	)
+	err = source.Connect(context.TODO())
+	assert.EqualError(t, err, "mtls: no verified client certificate")
+
+	source = NewClient("source", mtlsAddr, ClientTypeSource,
+		WithCredential("mtls:"),
+		WithLogger(discardingLogger),
+		WithClientTLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{clientCert},
+			RootCAs:      pool,
+			ServerName:   "localhost",
+			NextProtos:   []string{"yomo"},
+		}),
+	)
+	err = source.Connect(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"identity": "YoMo Client"}, <-mdCh)
+
+	assert.NoError(t, source.Close())
+}
+
+func TestServerACL(t *testing.T) {
+	t.Parallel()
+
+	const aclAddr = "127.0.0.1:19992"
+
+	a, err := acl.New(acl.Rule{Publish: []string{"0x10"}, Subscribe: []string{"0x10"}})
+	assert.NoError(t, err)
+
+	server := NewServer("zipper", WithServerLogger(discardingLogger), WithACL(a, acl.ActionReject))
+	go server.ListenAndServe(context.TODO(), aclAddr)
+	defer server.Close()
+
+	// observing the tag that is not allowed will be rejected.
+	sfn := createTestStreamFunction("sfn", aclAddr, 0x11)
+	err = sfn.Connect(context.TODO())
+	assert.EqualError(t, err, "acl: permission denied to observe tag 17")
+
+	received := make(chan *frame.DataFrame, 1)
+	sfn = createTestStreamFunction("sfn", aclAddr, 0x10)
+	sfn.SetDataFrameObserver(func(df *frame.DataFrame) { received <- df })
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	source := NewClient("source", aclAddr, ClientTypeSource, WithLogger(discardingLogger))
+	assert.NoError(t, source.Connect(context.TODO()))
+
+	assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x10, Payload: []byte("allowed")}))
+	df := <-received
+	assert.Equal(t, []byte("allowed"), df.Payload)
+
+	// writing the tag that is not allowed will be rejected, and the source will be closed.
+	assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x12, Payload: []byte("denied")}))
+	assert.True(t, checkClientExited(source, time.Second))
+	assert.Empty(t, received)
+}
+
+func TestServerObserver(t *testing.T) {
+	t.Parallel()
+
+	const observerAddr = "127.0.0.1:19991"
+
+	observer := &recordingObserver{}
+	server := NewServer("zipper", WithServerLogger(discardingLogger), WithAuth("token", "auth-token"), WithObserver(observer))
+	go server.ListenAndServe(context.TODO(), observerAddr)
+	defer server.Close()
+
+	// the handshake fails because of the wrong credential.
+	source := NewClient("source", observerAddr, ClientTypeSource, WithCredential("token:wrong-token"), WithLogger(discardingLogger))
+	assert.Error(t, source.Connect(context.TODO()))
+
+	received := make(chan *frame.DataFrame, 1)
+	sfn := createTestStreamFunction("sfn", observerAddr, 0x10)
+	sfn.SetDataFrameObserver(func(df *frame.DataFrame) { received <- df })
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	source = NewClient("source", observerAddr, ClientTypeSource, WithCredential("token:auth-token"), WithLogger(discardingLogger))
+	assert.NoError(t, source.Connect(context.TODO()))
+	defer source.Close()
+
+	assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x10, Payload: []byte("hello")}))
+	<-received
+
+	assert.Eventually(t, func() bool {
+		return observer.has("handshake_failed Source authentication") &&
+			observer.has("conn_opened StreamFunction sfn") &&
+			observer.has("conn_opened Source source") &&
+			observer.has("frame_received source 16 5 <nil>") &&
+			observer.has("frame_routed sfn 16 5 <nil>")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServerUnsubscribe(t *testing.T) {
+	t.Parallel()
+
+	const unsubscribeAddr = "127.0.0.1:19984"
+
+	server := NewServer("zipper", WithServerLogger(discardingLogger))
+	go server.ListenAndServe(context.TODO(), unsubscribeAddr)
+	defer server.Close()
+
+	sfn := createTestStreamFunction("sfn", unsubscribeAddr, 0x10)
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	routes := func() []uint64 {
+		return server.Router().(router.Snapshotter).Snapshot()[0x10]
+	}
+	assert.Eventually(t, func() bool { return len(routes()) == 1 }, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, sfn.Unsubscribe(ctx))
+	assert.Empty(t, routes())
+
+	// the connection is kept, so the sfn still writes data.
+	assert.True(t, sfn.Connected())
+	assert.NoError(t, sfn.Unsubscribe(ctx))
+}
+
+func TestServerCloser(t *testing.T) {
+	server := NewServer("zipper", WithServerLogger(discardingLogger))
+
+	var closed int
+	server.AddCloser(closerFunc(func() error {
+		closed++
+		return errors.New("close error")
+	}))
+
+	assert.NoError(t, server.Close())
+	assert.NoError(t, server.Close())
+	assert.Equal(t, 1, closed)
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestServerTracing(t *testing.T) {
+	t.Parallel()
+
+	const (
+		tracingAddr = "127.0.0.1:19988"
+		traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID      = "00f067aa0ba902b7"
+	)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	server := NewServer("zipper", WithServerLogger(discardingLogger), WithTracerProvider(tp))
+	go server.ListenAndServe(context.TODO(), tracingAddr)
+	defer server.Close()
+
+	received := make(chan *frame.DataFrame, 1)
+	sfn := createTestStreamFunction("sfn", tracingAddr, 0x10)
+	sfn.SetDataFrameObserver(func(df *frame.DataFrame) { received <- df })
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	source := NewClient("source", tracingAddr, ClientTypeSource, WithLogger(discardingLogger))
+	assert.NoError(t, source.Connect(context.TODO()))
+	defer source.Close()
+
+	// the trace is started by a HTTP frontend.
+	md, _ := metadata.M{metadata.TraceParentKey: "00-" + traceID + "-" + spanID + "-01"}.Encode()
+	assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x10, Metadata: md, Payload: []byte("hello")}))
+
+	df := <-received
+
+	// the routing span ends after the frame is delivered.
+	var route, deliver sdktrace.ReadOnlySpan
+	assert.Eventually(t, func() bool {
+		for _, span := range recorder.Ended() {
+			switch span.Name() {
+			case "zipper.route":
+				route = span
+			case "zipper.deliver":
+				deliver = span
+			}
+		}
+		return route != nil && deliver != nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, traceID, route.Parent().TraceID().String())
+	assert.Equal(t, spanID, route.Parent().SpanID().String())
+	assert.Equal(t, route.SpanContext().SpanID(), deliver.Parent().SpanID())
+
+	// the stream function continues the trace from the routing span.
+	got, err := metadata.Decode(df.Metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, traceID, got[metadata.TraceIDKey])
+	assert.Equal(t, route.SpanContext().SpanID().String(), got[metadata.SpanIDKey])
+	assert.Equal(t, "00-"+traceID+"-"+route.SpanContext().SpanID().String()+"-01", got[metadata.TraceParentKey])
+}
+
+// recordingObserver records the events as strings.
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, args...))
+}
+
+func (o *recordingObserver) has(event string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return slices.Contains(o.events, event)
+}
+
+func (o *recordingObserver) ConnOpened(conn ConnectionInfo) {
+	o.record("conn_opened %s %s", conn.ClientType(), conn.Name())
+}
+
+func (o *recordingObserver) ConnClosed(conn ConnectionInfo) {
+	o.record("conn_closed %s %s", conn.ClientType(), conn.Name())
+}
+
+func (o *recordingObserver) HandshakeFailed(clientType ClientType, reason string) {
+	o.record("handshake_failed %s %s", clientType, reason)
+}
+
+func (o *recordingObserver) FrameReceived(from ConnectionInfo, tag frame.Tag, size int, _ time.Duration, err error) {
+	o.record("frame_received %s %d %d %v", from.Name(), tag, size, err)
+}
+
+func (o *recordingObserver) FrameRouted(to ConnectionInfo, tag frame.Tag, size int, err error) {
+	o.record("frame_routed %s %d %d %v", to.Name(), tag, size, err)
+}
+
+func (o *recordingObserver) FrameForwarded(downstream string, tag frame.Tag, size int, err error) {
+	o.record("frame_forwarded %s %d %d %v", downstream, tag, size, err)
+}
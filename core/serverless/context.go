@@ -2,14 +2,20 @@
 package serverless
 
 import (
+	"context"
+	"sort"
+
 	"github.com/yomorun/yomo/ai"
 	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/serverless"
 )
 
 // Context sfn handler context
 type Context struct {
+	ctx    context.Context
 	writer frame.Writer
+	state  serverless.State
 	tag    uint32
 	md     metadata.M
 	data   []byte
@@ -17,15 +23,27 @@ type Context struct {
 }
 
 // NewContext creates a new serverless Context
-func NewContext(writer frame.Writer, tag uint32, md metadata.M, data []byte) *Context {
+func NewContext(ctx context.Context, writer frame.Writer, state serverless.State, tag uint32, md metadata.M, data []byte) *Context {
 	return &Context{
+		ctx:    ctx,
 		writer: writer,
+		state:  state,
 		tag:    tag,
 		md:     md,
 		data:   data,
 	}
 }
 
+// Context returns the context of the handler.
+func (c *Context) Context() context.Context {
+	return c.ctx
+}
+
+// State returns the key-value state of the sfn.
+func (c *Context) State() serverless.State {
+	return c.state
+}
+
 // Tag returns the tag of the data frame
 func (c *Context) Tag() uint32 {
 	return c.tag
@@ -41,40 +59,77 @@ func (c *Context) Metadata(key string) (string, bool) {
 	return c.md.Get(key)
 }
 
+// MetadataKeys returns the sorted keys of the metadata of the data frame
+func (c *Context) MetadataKeys() []string {
+	keys := make([]string, 0, len(c.md))
+	for k := range c.md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Write writes the data
 func (c *Context) Write(tag uint32, data []byte) error {
-	if data == nil {
-		return nil
+	return write(c.writer, tag, data, untypedMetadata(c.md, ""))
+}
+
+// WriteWithTarget writes the data with specified target
+func (c *Context) WriteWithTarget(tag uint32, data []byte, target string) error {
+	return write(c.writer, tag, data, untypedMetadata(c.md, target))
+}
+
+// untypedMetadata returns the metadata of the untyped data with the target, the serializer of the typed
+// input is not passed on, because the untyped data is not encoded by it.
+func untypedMetadata(md metadata.M, target string) metadata.M {
+	if _, ok := md.Get(metadata.SerializerKey); !ok && target == "" {
+		return md
 	}
-	if err := frame.IsReservedTag(tag); err != nil {
-		return err
+	md = md.Clone()
+	delete(md, metadata.SerializerKey)
+	if target != "" {
+		md.Set(metadata.TargetKey, target)
 	}
-	mdBytes, err := c.md.Encode()
+	return md
+}
+
+// WriteWithMetadata writes the data with the metadata added to the metadata of the data frame,
+// the metadata only applies to this write.
+func (c *Context) WriteWithMetadata(tag uint32, data []byte, md map[string]string) error {
+	merged, err := mergeMetadata(c.md, md)
 	if err != nil {
 		return err
 	}
+	return write(c.writer, tag, data, merged)
+}
 
-	dataFrame := &frame.DataFrame{
-		Tag:      tag,
-		Metadata: mdBytes,
-		Payload:  data,
+// mergeMetadata returns a copy of the metadata with the user metadata, the user metadata must not have reserved keys.
+// The serializer of the metadata is dropped, the typed writes set it in the user metadata.
+func mergeMetadata(md metadata.M, user map[string]string) (metadata.M, error) {
+	for k := range user {
+		if err := metadata.IsReservedKey(k); err != nil {
+			return nil, err
+		}
 	}
-
-	return c.writer.WriteFrame(dataFrame)
+	merged := md.Clone()
+	if merged == nil {
+		merged = metadata.M{}
+	}
+	delete(merged, metadata.SerializerKey)
+	for k, v := range user {
+		merged.Set(k, v)
+	}
+	return merged, nil
 }
 
-func (c *Context) WriteWithTarget(tag uint32, data []byte, target string) error {
+func write(writer frame.Writer, tag uint32, data []byte, md metadata.M) error {
 	if data == nil {
 		return nil
 	}
 	if err := frame.IsReservedTag(tag); err != nil {
 		return err
 	}
-	if target != "" {
-		c.md.Set(metadata.TargetKey, target)
-	}
-
-	mdBytes, err := c.md.Encode()
+	mdBytes, err := md.Encode()
 	if err != nil {
 		return err
 	}
@@ -85,5 +140,5 @@ func (c *Context) WriteWithTarget(tag uint32, data []byte, target string) error
 		Payload:  data,
 	}
 
-	return c.writer.WriteFrame(dataFrame)
+	return writer.WriteFrame(dataFrame)
 }
@@ -0,0 +1,67 @@
+package serverless
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+type frameRecorder struct {
+	frames []*frame.DataFrame
+}
+
+func (r *frameRecorder) WriteFrame(f frame.Frame) error {
+	r.frames = append(r.frames, f.(*frame.DataFrame))
+	return nil
+}
+
+func (r *frameRecorder) metadata(t *testing.T, i int) metadata.M {
+	md, err := metadata.Decode(r.frames[i].Metadata)
+	assert.NoError(t, err)
+	return md
+}
+
+func TestContextWriteMetadata(t *testing.T) {
+	md := metadata.M{"b": "2", "a": "1"}
+	w := &frameRecorder{}
+	c := NewContext(context.Background(), w, nil, 0x10, md, []byte("data"))
+
+	assert.Equal(t, []string{"a", "b"}, c.MetadataKeys())
+
+	assert.NoError(t, c.WriteWithTarget(0x11, []byte("target"), "t1"))
+	assert.NoError(t, c.WriteWithMetadata(0x11, []byte("metadata"), map[string]string{"c": "3", "a": "0"}))
+	assert.NoError(t, c.Write(0x11, []byte("plain")))
+
+	assert.ErrorIs(t, c.WriteWithMetadata(0x11, []byte("reserved"), map[string]string{metadata.TargetKey: "t2"}), metadata.ErrReservedKey)
+
+	assert.Len(t, w.frames, 3)
+	assert.Equal(t, metadata.M{"a": "1", "b": "2", metadata.TargetKey: "t1"}, w.metadata(t, 0))
+	assert.Equal(t, metadata.M{"a": "0", "b": "2", "c": "3"}, w.metadata(t, 1))
+	// the outgoing metadata of the previous writes must not bleed into the later writes.
+	assert.Equal(t, metadata.M{"a": "1", "b": "2"}, w.metadata(t, 2))
+	assert.Equal(t, []string{"a", "b"}, c.MetadataKeys())
+}
+
+func TestContextWriteUntyped(t *testing.T) {
+	// the input is typed, so it carries the serializer.
+	md := metadata.M{"a": "1", metadata.SerializerKey: "msgpack"}
+	w := &frameRecorder{}
+	c := NewContext(context.Background(), w, nil, 0x10, md, []byte("data"))
+
+	assert.NoError(t, c.Write(0x11, []byte("plain")))
+	assert.NoError(t, c.WriteWithTarget(0x11, []byte("target"), "t1"))
+	assert.NoError(t, c.WriteWithMetadata(0x11, []byte("metadata"), map[string]string{"c": "3"}))
+	assert.NoError(t, c.WriteWithMetadata(0x11, []byte("typed"), map[string]string{metadata.SerializerKey: "json"}))
+
+	assert.Len(t, w.frames, 4)
+	assert.Equal(t, metadata.M{"a": "1"}, w.metadata(t, 0))
+	assert.Equal(t, metadata.M{"a": "1", metadata.TargetKey: "t1"}, w.metadata(t, 1))
+	assert.Equal(t, metadata.M{"a": "1", "c": "3"}, w.metadata(t, 2))
+	assert.Equal(t, metadata.M{"a": "1", metadata.SerializerKey: "json"}, w.metadata(t, 3))
+	// the metadata of the input is kept.
+	v, _ := c.Metadata(metadata.SerializerKey)
+	assert.Equal(t, "msgpack", v)
+}
@@ -1,69 +1,61 @@
 package serverless
 
 import (
+	"context"
+
 	"github.com/yomorun/yomo/core/frame"
 	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/serverless"
 )
 
 // CronContext sfn cron handler context
 type CronContext struct {
-	writer  frame.Writer
-	md      metadata.M
-	mdBytes []byte
+	ctx    context.Context
+	writer frame.Writer
+	state  serverless.State
+	md     metadata.M
 }
 
 // NewCronContext creates a new serverless CronContext
-func NewCronContext(writer frame.Writer, md metadata.M) *CronContext {
-	mdBytes, _ := md.Encode()
-
+func NewCronContext(ctx context.Context, writer frame.Writer, state serverless.State, md metadata.M) *CronContext {
 	return &CronContext{
-		writer:  writer,
-		md:      md,
-		mdBytes: mdBytes,
+		ctx:    ctx,
+		writer: writer,
+		state:  state,
+		md:     md,
 	}
 }
 
+// Context returns the context of the handler.
+func (c *CronContext) Context() context.Context {
+	return c.ctx
+}
+
+// State returns the key-value state of the sfn.
+func (c *CronContext) State() serverless.State {
+	return c.state
+}
+
 // Write writes the data to next sfn instance.
 func (c *CronContext) Write(tag uint32, data []byte) error {
-	if data == nil {
-		return nil
-	}
-	if err := frame.IsReservedTag(tag); err != nil {
-		return err
-	}
-	dataFrame := &frame.DataFrame{
-		Tag:      tag,
-		Metadata: c.mdBytes,
-		Payload:  data,
-	}
-
-	return c.writer.WriteFrame(dataFrame)
+	return write(c.writer, tag, data, c.md)
 }
 
 // WriteWithTarget writes the data to next sfn instance with specified target.
 func (c *CronContext) WriteWithTarget(tag uint32, data []byte, target string) error {
-	if data == nil {
-		return nil
-	}
-	if err := frame.IsReservedTag(tag); err != nil {
-		return err
+	md := c.md
+	if target != "" {
+		md = md.Clone()
+		md.Set(metadata.TargetKey, target)
 	}
-	if target == "" {
-		return c.Write(tag, data)
-	}
-
-	c.md.Set(metadata.TargetKey, target)
+	return write(c.writer, tag, data, md)
+}
 
-	mdBytes, err := c.md.Encode()
+// WriteWithMetadata writes the data to next sfn instance with the metadata, the metadata only applies to this write.
+func (c *CronContext) WriteWithMetadata(tag uint32, data []byte, md map[string]string) error {
+	merged, err := mergeMetadata(c.md, md)
 	if err != nil {
 		return err
 	}
-
-	dataFrame := &frame.DataFrame{
-		Tag:      tag,
-		Metadata: mdBytes,
-		Payload:  data,
-	}
-
-	return c.writer.WriteFrame(dataFrame)
+	return write(c.writer, tag, data, merged)
 }
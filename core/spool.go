@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+
+	"github.com/yomorun/yomo/core/frame"
+)
+
+// Spool keeps the data frames written while the client is disconnected from the zipper,
+// the frames are written to the zipper in the order they are pushed after reconnecting.
+type Spool interface {
+	// Push appends the data frame to the spool.
+	Push(f *frame.DataFrame) error
+	// PushFront puts the data frame back to the head of the spool, it is called after the frame fails to be written,
+	// so the frame is still written before the frames pushed after it.
+	PushFront(f *frame.DataFrame) error
+	// Front returns the oldest data frame in the spool, ok is false if the spool is empty.
+	Front() (f *frame.DataFrame, ok bool, err error)
+	// Pop removes the oldest data frame from the spool, it is called after the frame is written.
+	Pop() error
+}
+
+// drainSpool writes the spooled data frames to the connection, a frame is removed from the spool
+// only after it is written, so the frames are not lost if the connection breaks while draining.
+func (c *Client) drainSpool(conn frame.Conn) error {
+	var n int
+	for {
+		f, ok, err := c.opts.spool.Front()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if n > 0 {
+				c.Logger.Info("drained the spool", "frames", n)
+			}
+			return nil
+		}
+		if err := conn.WriteFrame(f); err != nil {
+			return err
+		}
+		if err := c.opts.spool.Pop(); err != nil {
+			return err
+		}
+		n++
+	}
+}
+
+// spoolWriteFrame writes the data frame to the connection, or pushes it to the spool if the client is disconnected.
+func (c *Client) spoolWriteFrame(f *frame.DataFrame) error {
+	for {
+		c.spoolMu.Lock()
+		connDone := c.connDone
+		if connDone == nil {
+			err := c.opts.spool.Push(f)
+			c.spoolMu.Unlock()
+			return err
+		}
+		c.spoolMu.Unlock()
+
+		select {
+		case <-c.ctx.Done():
+			return context.Cause(c.ctx)
+		case c.wrCh <- f:
+			return nil
+		case <-connDone:
+			// the connection is broken before the frame is written, so spool it.
+		}
+	}
+}
+
+// respool puts the data frame that fails to be written back to the head of the spool if the spool is set,
+// so it is written first after reconnecting rather than lost.
+func (c *Client) respool(f frame.Frame) {
+	df, ok := f.(*frame.DataFrame)
+	if !ok || c.opts.spool == nil {
+		return
+	}
+
+	c.spoolMu.Lock()
+	defer c.spoolMu.Unlock()
+
+	if err := c.opts.spool.PushFront(df); err != nil {
+		c.Logger.Error("failed to spool the data frame", "err", err, "tag", df.Tag)
+	}
+}
+
+// openSpool drains the spool to the connection, and then the frames are written to the connection directly.
+// The writes are blocked while draining, so the frames keep in order.
+func (c *Client) openSpool(conn frame.Conn) error {
+	c.spoolMu.Lock()
+	defer c.spoolMu.Unlock()
+
+	if err := c.drainSpool(conn); err != nil {
+		return err
+	}
+	c.connDone = make(chan struct{})
+	return nil
+}
+
+// closeSpool makes the frames be written to the spool after the connection is broken.
+func (c *Client) closeSpool() {
+	c.spoolMu.Lock()
+	defer c.spoolMu.Unlock()
+
+	if c.connDone != nil {
+		close(c.connDone)
+		c.connDone = nil
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/frame"
+)
+
+type memorySpool struct {
+	mu     sync.Mutex
+	frames []*frame.DataFrame
+}
+
+func (s *memorySpool) Push(f *frame.DataFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames = append(s.frames, f)
+	return nil
+}
+
+func (s *memorySpool) PushFront(f *frame.DataFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames = append([]*frame.DataFrame{f}, s.frames...)
+	return nil
+}
+
+func (s *memorySpool) Front() (*frame.DataFrame, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.frames) == 0 {
+		return nil, false, nil
+	}
+	return s.frames[0], true, nil
+}
+
+func (s *memorySpool) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames = s.frames[1:]
+	return nil
+}
+
+// writtenConn records the frames written to it.
+type writtenConn struct {
+	frame.Conn
+	written []frame.Tag
+}
+
+func (c *writtenConn) WriteFrame(f frame.Frame) error {
+	c.written = append(c.written, f.(*frame.DataFrame).Tag)
+	return nil
+}
+
+// brokenConn fails to write, its reading is blocked until it is closed.
+type brokenConn struct {
+	frame.Conn
+	closed chan struct{}
+}
+
+func (c *brokenConn) ReadFrame() (frame.Frame, error) {
+	<-c.closed
+	return nil, io.EOF
+}
+
+func (c *brokenConn) WriteFrame(frame.Frame) error {
+	return errors.New("broken")
+}
+
+func TestSpool(t *testing.T) {
+	spool := &memorySpool{}
+	client := NewClient("source", testaddr, ClientTypeSource, WithLogger(discardingLogger), WithSpool(spool))
+
+	// the frames are spooled while disconnected.
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, client.WriteFrame(&frame.DataFrame{Tag: frame.Tag(i)}))
+	}
+	assert.Len(t, spool.frames, 3)
+
+	// the spooled frames are drained in order after connecting.
+	conn := &writtenConn{}
+	assert.NoError(t, client.openSpool(conn))
+	assert.Equal(t, []frame.Tag{1, 2, 3}, conn.written)
+	assert.Empty(t, spool.frames)
+
+	// the frames are written to the connection while connected.
+	go func() { _ = client.WriteFrame(&frame.DataFrame{Tag: 4}) }()
+	f := <-client.wrCh
+	assert.Equal(t, frame.Tag(4), f.(*frame.DataFrame).Tag)
+
+	// the frames are spooled again after the connection is broken.
+	client.closeSpool()
+	assert.NoError(t, client.WriteFrame(&frame.DataFrame{Tag: 5}))
+	assert.Len(t, spool.frames, 1)
+
+	// the frame that fails to be written is spooled again, before the frames spooled after it.
+	broken := &brokenConn{closed: make(chan struct{})}
+	defer close(broken.closed)
+	assert.NoError(t, client.openSpool(&writtenConn{}))
+	assert.NoError(t, spool.Push(&frame.DataFrame{Tag: 7}))
+	go func() { _ = client.WriteFrame(&frame.DataFrame{Tag: 6}) }()
+	assert.Error(t, client.serveConn(broken))
+	client.closeSpool()
+	assert.NoError(t, client.WriteFrame(&frame.DataFrame{Tag: 8}))
+
+	conn = &writtenConn{}
+	assert.NoError(t, client.openSpool(conn))
+	assert.Equal(t, []frame.Tag{6, 7, 8}, conn.written)
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+// TapFunc receives the data frames routed by the server, it is used to inspect the frames.
+// It is called synchronously in the hot path, so it should return quickly, and the frame and the metadata
+// must not be modified or retained after it returns.
+type TapFunc func(from ConnectionInfo, df *frame.DataFrame, md metadata.M)
+
+// taps holds the TapFuncs of the server, the taps are not subscribers, so they never affect the routing.
+type taps struct {
+	mu   sync.RWMutex
+	next uint64
+	fns  map[uint64]TapFunc
+}
+
+func (t *taps) add(fn TapFunc) (remove func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.fns == nil {
+		t.fns = make(map[uint64]TapFunc)
+	}
+	id := t.next
+	t.next++
+	t.fns[id] = fn
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.fns, id)
+	}
+}
+
+func (t *taps) call(from ConnectionInfo, df *frame.DataFrame, md metadata.M) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, fn := range t.fns {
+		fn(from, df, md)
+	}
+}
+
+// Tap registers the TapFunc that receives the data frames routed by the server,
+// it returns the function that unregisters the TapFunc.
+func (s *Server) Tap(fn TapFunc) (untap func()) {
+	return s.taps.add(fn)
+}
@@ -10,11 +10,13 @@
 package ylog
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"log/slog"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"github.com/caarlos0/env/v6"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -126,6 +128,35 @@ func parseToWriter(conf Config, path string, defaultWriter io.Writer) io.Writer
 	}
 }
 
+// levelOverride overrides the levels of the loggers created by ylog, it is nil if the level is not overridden.
+var levelOverride atomic.Pointer[slog.Level]
+
+// SetLevel changes the level of all the loggers created by ylog at runtime,
+// the level can be one of `debug`, `info`, `warn`, `error`, the empty level restores the configured levels.
+func SetLevel(level string) error {
+	if level == "" {
+		levelOverride.Store(nil)
+		return nil
+	}
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("ylog: unknown level: %s", level)
+	}
+	l := parseToSlogLevel(level)
+	levelOverride.Store(&l)
+	return nil
+}
+
+// GetLevel returns the level set by SetLevel, it returns the empty string if the level is not overridden.
+func GetLevel() string {
+	l := levelOverride.Load()
+	if l == nil {
+		return ""
+	}
+	return strings.ToLower(l.String())
+}
+
 func parseToSlogLevel(stringLevel string) slog.Level {
 	level := slog.LevelDebug
 	switch strings.ToLower(stringLevel) {
@@ -1,6 +1,7 @@
 package ylog
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
@@ -48,3 +49,27 @@ func TestLogger(t *testing.T) {
 	os.Remove(output)
 	os.Remove(errOutput)
 }
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel("")
+
+	logger := slog.New(NewHandlerFromConfig(Config{Level: "info", Output: "/dev/null"}))
+	ctx := context.Background()
+
+	assert.False(t, logger.Enabled(ctx, slog.LevelDebug))
+	assert.Equal(t, "", GetLevel())
+
+	assert.NoError(t, SetLevel("debug"))
+	assert.True(t, logger.Enabled(ctx, slog.LevelDebug))
+	assert.Equal(t, "debug", GetLevel())
+
+	assert.NoError(t, SetLevel("error"))
+	assert.False(t, logger.Enabled(ctx, slog.LevelWarn))
+
+	assert.EqualError(t, SetLevel("verbose"), "ylog: unknown level: verbose")
+	assert.Equal(t, "error", GetLevel())
+
+	assert.NoError(t, SetLevel(""))
+	assert.False(t, logger.Enabled(ctx, slog.LevelDebug))
+	assert.True(t, logger.Enabled(ctx, slog.LevelInfo))
+}
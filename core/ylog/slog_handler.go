@@ -77,6 +77,9 @@ func NewHandlerFromConfig(conf Config) slog.Handler {
 }
 
 func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if l := levelOverride.Load(); l != nil {
+		return level >= *l
+	}
 	return h.Handler.Enabled(ctx, level)
 }
 
@@ -2,11 +2,18 @@ package yomo
 
 import (
 	"crypto/tls"
+	"io"
 	"log/slog"
+	"time"
 
 	"github.com/quic-go/quic-go"
 	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/acl"
 	"github.com/yomorun/yomo/core/router"
+	"github.com/yomorun/yomo/pkg/config"
+	"github.com/yomorun/yomo/pkg/ratelimit"
+	"github.com/yomorun/yomo/pkg/serializer"
+	"github.com/yomorun/yomo/serverless"
 )
 
 type (
@@ -33,6 +40,30 @@ var (
 
 	// WithSourceReConnect makes source Connect until success, unless authentication fails.
 	WithSourceReConnect = func() SourceOption { return SourceOption(core.WithReConnect()) }
+
+	// WithSourceEndpointSelector sets the selector that picks one of the zipper endpoints for the Source.
+	WithSourceEndpointSelector = func(s core.EndpointSelector) SourceOption {
+		return SourceOption(core.WithEndpointSelector(s))
+	}
+
+	// WithSourceFrameTimeout sets the deadline of the data written by the Source to the writing time plus the timeout,
+	// the stream functions skip the data after the deadline.
+	WithSourceFrameTimeout = func(timeout time.Duration) SourceOption {
+		return SourceOption(core.WithFrameTimeout(timeout))
+	}
+
+	// WithSourceSpool keeps the data written while the Source is disconnected from the zipper in the spool,
+	// e.g. `spool.NewDisk(path, maxBytes, maxAge)`, the data is written in order after reconnecting.
+	WithSourceSpool = func(s core.Spool) SourceOption { return SourceOption(core.WithSpool(s)) }
+
+	// WithSourceBatch packs the data written by the Source into one data frame until the data reach the size in bytes,
+	// or the linger time passes, the stream functions still handle the data one by one.
+	WithSourceBatch = func(size int, linger time.Duration) SourceOption {
+		return SourceOption(core.WithBatch(size, linger))
+	}
+
+	// WithSourceHooks sets the callbacks of the connection lifecycle of the Source.
+	WithSourceHooks = func(hooks ConnectionHooks) SourceOption { return SourceOption(core.WithHooks(hooks)) }
 )
 
 // Sfn Options.
@@ -52,21 +83,109 @@ var (
 	// WithSfnReConnect makes sfn Connect until success, unless authentication fails.
 	WithSfnReConnect = func() SfnOption { return SfnOption(core.WithReConnect()) }
 
+	// WithSfnEndpointSelector sets the selector that picks one of the zipper endpoints for the Sfn.
+	WithSfnEndpointSelector = func(s core.EndpointSelector) SfnOption {
+		return SfnOption(core.WithEndpointSelector(s))
+	}
+
 	// WithSfnAIFunctionDefinition sets AI function definition for the Sfn.
 	WithSfnAIFunctionDefinition = func(description string, inputModel any) SfnOption {
 		return SfnOption(core.WithAIFunctionDefinition(description, inputModel))
 	}
 
+	// WithSfnConcurrency bounds the handlers of the Sfn to n workers, the data frames wait in a queue of n by default.
+	// When the queue is full, the Sfn stops reading from the zipper until a worker is free, no data frame is dropped.
+	WithSfnConcurrency = func(n int) SfnOption { return SfnOption(core.WithConcurrency(n)) }
+
+	// WithSfnQueueSize sets the number of data frames waiting for the workers set by WithSfnConcurrency.
+	WithSfnQueueSize = func(size int) SfnOption { return SfnOption(core.WithQueueSize(size)) }
+
+	// WithSfnSerialKey makes the data with the same key be handled in order, it works with WithSfnConcurrency,
+	// e.g. the data of one device are handled in order while the data of different devices are handled in parallel.
+	// Without WithSfnConcurrency, the handlers run on runtime.NumCPU() workers.
+	WithSfnSerialKey = func(fn func(ctx serverless.Context) string) SfnOption {
+		return SfnOption(core.WithSerialKey(fn))
+	}
+
+	// WithSfnHandlerTimeout sets the timeout of the Sfn handlers, ctx.Context() is done after the timeout,
+	// or the deadline of the data, whichever comes first.
+	WithSfnHandlerTimeout = func(timeout time.Duration) SfnOption { return SfnOption(core.WithHandlerTimeout(timeout)) }
+
+	// WithSfnRetryPolicy sets the retry policy of the Sfn handler set by SetHandlerE.
+	WithSfnRetryPolicy = func(p RetryPolicy) SfnOption { return SfnOption(core.WithRetryPolicy(p)) }
+
+	// WithSfnDeadLetterTag writes the data to the tag when the Sfn handler fails at last or panics,
+	// the error details are carried in the metadata.
+	WithSfnDeadLetterTag = func(tag uint32) SfnOption { return SfnOption(core.WithDeadLetterTag(tag)) }
+
+	// WithSfnState sets the key-value state of the Sfn, e.g. `state.NewDisk(path)` keeps the state across restarts,
+	// the state is in memory by default.
+	WithSfnState = func(s serverless.State) SfnOption { return SfnOption(core.WithState(s)) }
+
+	// WithSfnClusterCron makes the zipper schedule the cron handler of the Sfn, on each tick the zipper triggers
+	// only one of the Sfn instances with the same name, and fails over to another instance when it disconnects.
+	WithSfnClusterCron = func() SfnOption { return SfnOption(core.WithClusterCron()) }
+
+	// WithSfnHooks sets the callbacks of the connection lifecycle of the Sfn.
+	WithSfnHooks = func(hooks ConnectionHooks) SfnOption { return SfnOption(core.WithHooks(hooks)) }
+
 	// DisableOtelTrace determines whether to disable otel trace.
 	DisableOtelTrace = func() SfnOption { return SfnOption(core.DisableOtelTrace()) }
 )
 
+// HandlerOption is option for the handler registered by StreamFunction.Handle.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	concurrency int
+	queueSize   int
+	serializer  serializer.Serializer
+}
+
+// Handler Options.
+var (
+	// WithHandlerConcurrency bounds the handler to n workers of its own, the data wait in a queue of n by default.
+	// The handlers without it share the workers set by WithSfnConcurrency.
+	WithHandlerConcurrency = func(n int) HandlerOption {
+		return func(o *handlerOptions) { o.concurrency = n }
+	}
+
+	// WithHandlerQueueSize sets the number of data waiting for the workers set by WithHandlerConcurrency.
+	WithHandlerQueueSize = func(size int) HandlerOption {
+		return func(o *handlerOptions) { o.queueSize = size }
+	}
+
+	// WithHandlerSerializer sets the serializer of the handler, Decode, WriteTyped and TypedHandler use it
+	// in the handler when their serializer is nil.
+	WithHandlerSerializer = func(s serializer.Serializer) HandlerOption {
+		return func(o *handlerOptions) { o.serializer = s }
+	}
+)
+
+// RetryPolicy decides how the Sfn handler is retried when it returns an error.
+type RetryPolicy = core.RetryPolicy
+
+// ConnectionHooks are the callbacks of the connection lifecycle of the Source and the Sfn,
+// e.g. switching to local buffering when disconnected, or updating the health endpoint.
+type ConnectionHooks = core.Hooks
+
+// ConnectionState is the state of the connection between the Source or the Sfn and the zipper.
+type ConnectionState = core.ConnectionState
+
 // ClientOption is option for the upstream Zipper.
 type ClientOption = core.ClientOption
 
 type zipperOptions struct {
 	serverOption []core.ServerOption
 	clientOption []ClientOption
+	metricsAddr  string
+	adminAddr    string
+	adminCred    string
+	recordFile   string
+	recordTags   []string
+	telemetry    *config.Telemetry
+	logger       *slog.Logger
+	closers      []io.Closer
 }
 
 // ZipperOption is option for the Zipper.
@@ -80,6 +199,55 @@ var (
 		}
 	}
 
+	// WithACL sets the publish/subscribe access control of tags for the zipper,
+	// the action decides how to handle the data frames that violate the ACL.
+	WithACL = func(a acl.ACL, action acl.Action) ZipperOption {
+		return func(zo *zipperOptions) {
+			zo.serverOption = append(zo.serverOption, core.WithACL(a, action))
+		}
+	}
+
+	// WithMetrics serves the prometheus metrics of the zipper on the `/metrics` path of the address.
+	WithMetrics = func(addr string) ZipperOption {
+		return func(zo *zipperOptions) {
+			zo.metricsAddr = addr
+		}
+	}
+
+	// WithAdmin serves the admin http api of the zipper on the address,
+	// the requests must carry the credential in the `Authorization: Bearer <credential>` header.
+	WithAdmin = func(addr, credential string) ZipperOption {
+		return func(zo *zipperOptions) {
+			zo.adminAddr = addr
+			zo.adminCred = credential
+		}
+	}
+
+	// WithRecording records the data frames of the tags into the file, the tags are tags or tag ranges,
+	// e.g. `0x10` and `0x10-0x20`, all the tags are recorded if the tags are empty.
+	WithRecording = func(file string, tags ...string) ZipperOption {
+		return func(zo *zipperOptions) {
+			zo.recordFile = file
+			zo.recordTags = tags
+		}
+	}
+
+	// WithTelemetry exports the traces, metrics and logs of the zipper to an OpenTelemetry collector by OTLP,
+	// the logs of the zipper carry the trace_id and span_id of the data frames.
+	WithTelemetry = func(conf config.Telemetry) ZipperOption {
+		return func(zo *zipperOptions) {
+			zo.telemetry = &conf
+		}
+	}
+
+	// WithCronMissedPolicy sets how the zipper handles the ticks of the cron handlers it schedules that are missed
+	// because no instance of the stream function is connected, the missed ticks are skipped by default.
+	WithCronMissedPolicy = func(policy core.CronMissedPolicy) ZipperOption {
+		return func(zo *zipperOptions) {
+			zo.serverOption = append(zo.serverOption, core.WithCronMissedPolicy(policy))
+		}
+	}
+
 	// WithZipperTLSConfig sets the TLS configuration for the zipper.
 	WithZipperTLSConfig = func(tc *tls.Config) ZipperOption {
 		return func(zo *zipperOptions) {
@@ -98,6 +266,7 @@ var (
 	WithZipperLogger = func(l *slog.Logger) ZipperOption {
 		return func(zo *zipperOptions) {
 			zo.serverOption = append(zo.serverOption, core.WithServerLogger(l))
+			zo.logger = l
 		}
 	}
 
@@ -136,6 +305,15 @@ var (
 		}
 	}
 
+	// WithRateLimiter limits the data frames written to the zipper, the limiter is closed with the zipper,
+	// so the quota usage is saved.
+	WithRateLimiter = func(l *ratelimit.Limiter) ZipperOption {
+		return func(o *zipperOptions) {
+			o.serverOption = append(o.serverOption, core.WithFrameMiddleware(l.Middleware()))
+			o.closers = append(o.closers, l)
+		}
+	}
+
 	// WithZipperFrameMiddleware sets frame middleware for the zipper.
 	WithZipperFrameMiddleware = func(mw ...core.FrameMiddleware) ZipperOption {
 		return func(o *zipperOptions) {
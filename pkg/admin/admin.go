@@ -0,0 +1,242 @@
+// Package admin provides the admin http api of zipper, it is used to inspect and control the running zipper.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/core/router"
+	"github.com/yomorun/yomo/core/ylog"
+)
+
+// EvictGracePeriod is the period that the evicted client has to close the connection by itself,
+// the zipper closes the connection after the period.
+var EvictGracePeriod = time.Second
+
+// Connection is a connection of zipper in the admin api.
+type Connection struct {
+	ID         uint64     `json:"id"`
+	ClientID   string     `json:"client_id"`
+	Name       string     `json:"name"`
+	ClientType string     `json:"client_type"`
+	Tags       []uint32   `json:"tags"`
+	Target     string     `json:"target,omitempty"`
+	RemoteAddr string     `json:"remote_addr,omitempty"`
+	Metadata   metadata.M `json:"metadata"`
+}
+
+// Downstream is a downstream zipper of mesh in the admin api.
+type Downstream struct {
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	Connected bool   `json:"connected"`
+}
+
+// LogLevel is the log level in the admin api, the empty level means the configured levels are used.
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// NewHandler returns the http handler of admin api, the requests must carry the credential
+// in the `Authorization: Bearer <credential>` header. The api is:
+//
+//	GET  /connections                    lists the connections.
+//	POST /connections/evict?id=<id>      evicts the connection by sending a GoawayFrame, the optional
+//	                                     `message` query is the message of GoawayFrame.
+//	GET  /routes                         lists the route rules, the key is the tag.
+//	GET  /downstreams                    lists the downstream zippers of mesh.
+//	GET  /log/level                      returns the log level.
+//	PUT  /log/level                      changes the log level, the body is `{"level": "debug"}`.
+//	GET  /tap                            streams the routed data frames as JSON lines, see TapFilter for the query.
+func NewHandler(server *core.Server, credential string) http.Handler {
+	h := &handler{server: server}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/connections", h.method(http.MethodGet, h.listConnections))
+	mux.HandleFunc("/connections/evict", h.method(http.MethodPost, h.evictConnection))
+	mux.HandleFunc("/routes", h.method(http.MethodGet, h.listRoutes))
+	mux.HandleFunc("/downstreams", h.method(http.MethodGet, h.listDownstreams))
+	mux.HandleFunc("/log/level", h.logLevel)
+	mux.HandleFunc("/tap", h.method(http.MethodGet, h.tap))
+
+	return requireCredential(credential, mux)
+}
+
+// NewServer returns the http server that serves the admin api on the address.
+// It has no write timeout, because the tap streams the data frames until the client goes away.
+func NewServer(addr string, server *core.Server, credential string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           NewHandler(server, credential),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// ListenAndServe serves the admin api on the address.
+func ListenAndServe(addr string, server *core.Server, credential string) error {
+	return NewServer(addr, server, credential).ListenAndServe()
+}
+
+type handler struct {
+	server *core.Server
+}
+
+func (h *handler) listConnections(w http.ResponseWriter, _ *http.Request) {
+	conns, err := h.server.Connector().Find(func(core.ConnectionInfo) bool { return true })
+	if err != nil {
+		respondError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	result := make([]Connection, 0, len(conns))
+	for _, conn := range conns {
+		result = append(result, connectionOf(conn))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	respond(w, http.StatusOK, result)
+}
+
+func (h *handler) evictConnection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid connection id: %s", r.URL.Query().Get("id")))
+		return
+	}
+	message := r.URL.Query().Get("message")
+	if message == "" {
+		message = "evicted by admin"
+	}
+
+	conn, ok, err := h.server.Connector().Get(id)
+	if err != nil {
+		respondError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if !ok {
+		respondError(w, http.StatusNotFound, fmt.Errorf("connection not found: %d", id))
+		return
+	}
+
+	if err := conn.FrameConn().WriteFrame(&frame.GoawayFrame{Message: message}); err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	time.AfterFunc(EvictGracePeriod, func() { _ = conn.FrameConn().CloseWithError(message) })
+
+	h.server.Logger().Info("connection evicted by admin", "conn_id", id, "name", conn.Name(), "message", message)
+
+	respond(w, http.StatusOK, connectionOf(conn))
+}
+
+func (h *handler) listRoutes(w http.ResponseWriter, _ *http.Request) {
+	s, ok := h.server.Router().(router.Snapshotter)
+	if !ok {
+		respondError(w, http.StatusNotImplemented, fmt.Errorf("the router does not support listing routes"))
+		return
+	}
+	routes := s.Snapshot()
+	for _, ids := range routes {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	respond(w, http.StatusOK, routes)
+}
+
+func (h *handler) listDownstreams(w http.ResponseWriter, _ *http.Request) {
+	status := h.server.DownstreamStatus()
+
+	result := make([]Downstream, 0, len(status))
+	for name, id := range h.server.Downstreams() {
+		result = append(result, Downstream{Name: name, ID: id, Connected: status[name]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	respond(w, http.StatusOK, result)
+}
+
+func (h *handler) logLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		respond(w, http.StatusOK, LogLevel{Level: ylog.GetLevel()})
+	case http.MethodPut:
+		var level LogLevel
+		if err := json.NewDecoder(r.Body).Decode(&level); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := ylog.SetLevel(level.Level); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.server.Logger().Info("log level changed by admin", "level", level.Level)
+		respond(w, http.StatusOK, LogLevel{Level: ylog.GetLevel()})
+	default:
+		respondError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+	}
+}
+
+func (h *handler) method(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			respondError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func connectionOf(conn *core.Connection) Connection {
+	c := Connection{
+		ID:         conn.ID(),
+		ClientID:   conn.ClientID(),
+		Name:       conn.Name(),
+		ClientType: conn.ClientType().String(),
+		Tags:       conn.ObserveDataTags(),
+		Metadata:   conn.Metadata().Clone(),
+	}
+	if c.Tags == nil {
+		c.Tags = []uint32{}
+	}
+	if c.Metadata == nil {
+		c.Metadata = metadata.M{}
+	}
+	c.Target, _ = conn.Metadata().Get(metadata.WantedTargetKey)
+	if fconn := conn.FrameConn(); fconn != nil && fconn.RemoteAddr() != nil {
+		c.RemoteAddr = fconn.RemoteAddr().String()
+	}
+	return c
+}
+
+// requireCredential rejects the requests that do not carry the credential.
+// All the requests are rejected if the credential is empty.
+func requireCredential(credential string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || credential == "" || subtle.ConstantTimeCompare([]byte(got), []byte(credential)) != 1 {
+			respondError(w, http.StatusUnauthorized, fmt.Errorf("invalid admin credential"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func respond(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func respondError(w http.ResponseWriter, code int, err error) {
+	respond(w, code, map[string]string{"error": err.Error()})
+}
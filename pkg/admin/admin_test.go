@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/ylog"
+)
+
+var discardingLogger = ylog.NewFromConfig(ylog.Config{Output: "/dev/null", ErrorOutput: "/dev/null"})
+
+func TestAdmin(t *testing.T) {
+	const (
+		zipperAddr = "127.0.0.1:19990"
+		credential = "admin-credential"
+	)
+
+	server := core.NewServer("zipper", core.WithServerLogger(discardingLogger))
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	sfn := core.NewClient("sfn", zipperAddr, core.ClientTypeStreamFunction, core.WithLogger(discardingLogger))
+	sfn.SetObserveDataTags(0x10)
+	sfn.SetDataFrameObserver(func(_ *frame.DataFrame) {})
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	handler := NewHandler(server, credential)
+
+	t.Run("unauthorized", func(t *testing.T) {
+		code, _ := request(handler, http.MethodGet, "/connections", "wrong-credential", "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	var conns []Connection
+	t.Run("list connections", func(t *testing.T) {
+		code, body := request(handler, http.MethodGet, "/connections", credential, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.NoError(t, json.Unmarshal(body, &conns))
+		assert.Len(t, conns, 1)
+		assert.Equal(t, "sfn", conns[0].Name)
+		assert.Equal(t, "StreamFunction", conns[0].ClientType)
+		assert.Equal(t, []uint32{0x10}, conns[0].Tags)
+		assert.NotEmpty(t, conns[0].RemoteAddr)
+	})
+
+	t.Run("list routes", func(t *testing.T) {
+		code, body := request(handler, http.MethodGet, "/routes", credential, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, fmt.Sprintf(`{"16":[%d]}`, conns[0].ID), string(body))
+	})
+
+	t.Run("list downstreams", func(t *testing.T) {
+		code, body := request(handler, http.MethodGet, "/downstreams", credential, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `[]`, string(body))
+	})
+
+	t.Run("log level", func(t *testing.T) {
+		defer ylog.SetLevel("")
+
+		code, body := request(handler, http.MethodPut, "/log/level", credential, `{"level":"debug"}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"level":"debug"}`, string(body))
+
+		code, _ = request(handler, http.MethodPut, "/log/level", credential, `{"level":"verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, body = request(handler, http.MethodGet, "/log/level", credential, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"level":"debug"}`, string(body))
+	})
+
+	t.Run("evict connection", func(t *testing.T) {
+		code, _ := request(handler, http.MethodPost, "/connections/evict?id=0", credential, "")
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, _ = request(handler, http.MethodGet, "/connections/evict?id=1", credential, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+		code, _ = request(handler, http.MethodPost, fmt.Sprintf("/connections/evict?id=%d&message=bye", conns[0].ID), credential, "")
+		assert.Equal(t, http.StatusOK, code)
+
+		exited := make(chan struct{})
+		go func() {
+			sfn.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(time.Second):
+			t.Fatal("the evicted sfn should exit")
+		}
+	})
+}
+
+func request(handler http.Handler, method, path, credential, body string) (int, []byte) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+credential)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder.Code, recorder.Body.Bytes()
+}
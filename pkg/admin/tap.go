@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/acl"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+// TapBufferSize is the number of frames buffered for a tap, the frames are dropped if the buffer is full,
+// so a slow tap never slows down the zipper.
+var TapBufferSize = 1024
+
+// TapEvent is a data frame streamed by the tap api, the events are written as JSON lines.
+type TapEvent struct {
+	Time       time.Time  `json:"time"`
+	Tag        uint32     `json:"tag"`
+	Source     string     `json:"source"`
+	SourceType string     `json:"source_type"`
+	Metadata   metadata.M `json:"metadata"`
+	Payload    []byte     `json:"payload"`
+	// Dropped is the number of frames dropped before this one because the tap was too slow.
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// TapFilter filters the frames of the tap api, a frame matches if it matches all the non-empty fields.
+type TapFilter struct {
+	// Tags are the tags or tag ranges, e.g. `0x10` and `0x10-0x20`, a frame matches if it matches any of them.
+	Tags []string
+	// Sources are the names of the writers, a frame matches if it is written by any of them.
+	Sources []string
+	// Metadata is the metadata that the frame must carry, the value `*` only requires the key to exist.
+	Metadata map[string]string
+}
+
+// Query returns the url query of the filter.
+func (f TapFilter) Query() string {
+	q := url.Values{}
+	for _, tag := range f.Tags {
+		q.Add("tag", tag)
+	}
+	for _, source := range f.Sources {
+		q.Add("source", source)
+	}
+	for k, v := range f.Metadata {
+		q.Add("metadata", k+"="+v)
+	}
+	return q.Encode()
+}
+
+type tapMatcher struct {
+	tags     [][2]frame.Tag
+	sources  map[string]struct{}
+	metadata map[string]string
+}
+
+func newTapMatcher(r *http.Request) (*tapMatcher, error) {
+	query := r.URL.Query()
+	m := &tapMatcher{
+		sources:  make(map[string]struct{}),
+		metadata: make(map[string]string),
+	}
+	for _, spec := range query["tag"] {
+		min, max, err := acl.ParseTagRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		m.tags = append(m.tags, [2]frame.Tag{min, max})
+	}
+	for _, source := range query["source"] {
+		m.sources[source] = struct{}{}
+	}
+	for _, kv := range query["metadata"] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid metadata filter: %s, it should be key=value", kv)
+		}
+		m.metadata[k] = v
+	}
+	return m, nil
+}
+
+func (m *tapMatcher) match(from core.ConnectionInfo, tag frame.Tag, md metadata.M) bool {
+	if len(m.tags) > 0 {
+		matched := false
+		for _, r := range m.tags {
+			if tag >= r[0] && tag <= r[1] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(m.sources) > 0 {
+		if _, ok := m.sources[from.Name()]; !ok {
+			return false
+		}
+	}
+	for k, want := range m.metadata {
+		v, ok := md.Get(k)
+		if !ok || (want != "*" && v != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// tap streams the matching frames routed by the zipper as JSON lines until the request is canceled.
+func (h *handler) tap(w http.ResponseWriter, r *http.Request) {
+	matcher, err := newTapMatcher(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	var (
+		events  = make(chan TapEvent, TapBufferSize)
+		dropped atomic.Uint64
+	)
+	untap := h.server.Tap(func(from core.ConnectionInfo, df *frame.DataFrame, md metadata.M) {
+		if !matcher.match(from, df.Tag, md) {
+			return
+		}
+		event := TapEvent{
+			Time:       time.Now(),
+			Tag:        df.Tag,
+			Source:     from.Name(),
+			SourceType: from.ClientType().String(),
+			Metadata:   md.Clone(),
+			Payload:    append([]byte(nil), df.Payload...),
+			Dropped:    dropped.Swap(0),
+		}
+		select {
+		case events <- event:
+		default:
+			dropped.Add(event.Dropped + 1)
+		}
+	})
+	defer untap()
+
+	h.server.Logger().Info("tap started", "remote_addr", r.RemoteAddr, "filter", r.URL.RawQuery)
+	defer h.server.Logger().Info("tap stopped", "remote_addr", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Tap connects to the tap api of the admin api at the url, e.g. `http://127.0.0.1:9092`,
+// and calls fn with the matching frames until the ctx is canceled or fn returns an error.
+func Tap(ctx context.Context, adminURL, credential string, filter TapFilter, fn func(TapEvent) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(adminURL, "/")+"/tap?"+filter.Query(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+credential)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("tap: %s: %s", resp.Status, body.Error)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event TapEvent
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+func TestTap(t *testing.T) {
+	const (
+		zipperAddr = "127.0.0.1:19989"
+		credential = "admin-credential"
+	)
+
+	server := core.NewServer("zipper", core.WithServerLogger(discardingLogger))
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	adminServer := httptest.NewServer(NewHandler(server, credential))
+	defer adminServer.Close()
+
+	received := make(chan *frame.DataFrame, 100)
+	sfn := core.NewClient("sfn", zipperAddr, core.ClientTypeStreamFunction, core.WithLogger(discardingLogger))
+	sfn.SetObserveDataTags(0x10, 0x11)
+	sfn.SetDataFrameObserver(func(df *frame.DataFrame) { received <- df })
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	source := core.NewClient("source", zipperAddr, core.ClientTypeSource, core.WithLogger(discardingLogger))
+	assert.NoError(t, source.Connect(context.TODO()))
+	defer source.Close()
+
+	err := Tap(context.TODO(), adminServer.URL, "wrong-credential", TapFilter{}, func(TapEvent) error { return nil })
+	assert.EqualError(t, err, "tap: 401 Unauthorized: invalid admin credential")
+
+	events := make(chan TapEvent, 10)
+	tapped := make(chan error)
+	ctx, cancel := context.WithCancel(context.TODO())
+	go func() {
+		filter := TapFilter{Tags: []string{"0x10-0x1f"}, Sources: []string{"source"}, Metadata: map[string]string{"k": "*"}}
+		tapped <- Tap(ctx, adminServer.URL, credential, filter, func(e TapEvent) error {
+			events <- e
+			return nil
+		})
+	}()
+
+	md, _ := metadata.M{"k": "v"}.Encode()
+	assert.Eventually(t, func() bool {
+		// the tap may not be ready, so the frame is written until the tap receives it.
+		assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x10, Metadata: md, Payload: []byte("hello")}))
+		select {
+		case e := <-events:
+			assert.Equal(t, uint32(0x10), e.Tag)
+			assert.Equal(t, "source", e.Source)
+			assert.Equal(t, "Source", e.SourceType)
+			assert.Equal(t, "v", e.Metadata["k"])
+			assert.Equal(t, []byte("hello"), e.Payload)
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 2*time.Second, time.Millisecond)
+
+	// the frames that do not match the filter are not tapped.
+	assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x11, Payload: []byte("no metadata")}))
+	assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: 0x20, Metadata: md, Payload: []byte("unmatched tag")}))
+
+	// the tap does not affect the routing.
+	for df := range received {
+		if string(df.Payload) == "no metadata" {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, events)
+
+	cancel()
+	assert.True(t, errors.Is(<-tapped, context.Canceled))
+}
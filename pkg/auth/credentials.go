@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/core/ylog"
+	"gopkg.in/yaml.v3"
+)
+
+// TokenCredential is a token in the credentials file, the file looks like:
+//
+//	credentials:
+//	  - name: tenant-a-source
+//	    token: <TOKEN>
+//	    expires_at: 2025-01-01T00:00:00Z
+//	    metadata:
+//	      yomo-namespace: tenant-a
+//	      role: source
+//
+// The `yomo-namespace` metadata puts the client into the namespace, the clients in different namespaces are isolated.
+// The `yomo-mesh: "true"` metadata makes the credential a mesh credential, only the zippers connected with it
+// can forward the data of other namespaces.
+type TokenCredential struct {
+	// Name is the name of the credential, multiple credentials can share the same name during rotation.
+	Name string `yaml:"name"`
+	// Token is the token that the client carries.
+	Token string `yaml:"token"`
+	// ExpiresAt is the expiry of the token, zero means the token never expires.
+	ExpiresAt time.Time `yaml:"expires_at"`
+	// Metadata is the connection metadata of the client that authenticated by the token.
+	Metadata map[string]string `yaml:"metadata"`
+}
+
+// Expired reports whether the credential has expired at the given time.
+func (c TokenCredential) Expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt)
+}
+
+// LoadCredentials loads the credentials from the credentials file.
+func LoadCredentials(path string) ([]TokenCredential, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCredentials(buf)
+}
+
+func parseCredentials(buf []byte) ([]TokenCredential, error) {
+	var file struct {
+		Credentials []TokenCredential `yaml:"credentials"`
+	}
+	if err := yaml.Unmarshal(buf, &file); err != nil {
+		return nil, fmt.Errorf("credentials: %w", err)
+	}
+	for i, c := range file.Credentials {
+		if c.Token == "" {
+			return nil, fmt.Errorf("credentials: the token of credential #%d is empty", i)
+		}
+	}
+	return file.Credentials, nil
+}
+
+// LookupCredential returns the token of the named credential in the credentials file,
+// If there are multiple unexpired credentials with the name, the one expires last is returned.
+func LookupCredential(path, name string) (string, error) {
+	credentials, err := LoadCredentials(path)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		found *TokenCredential
+		now   = time.Now()
+	)
+	for i, c := range credentials {
+		if c.Name != name || c.Expired(now) {
+			continue
+		}
+		if found == nil || (!found.ExpiresAt.IsZero() && (c.ExpiresAt.IsZero() || c.ExpiresAt.After(found.ExpiresAt))) {
+			found = &credentials[i]
+		}
+	}
+	if found == nil {
+		return "", fmt.Errorf("credentials: no valid credential named %s", name)
+	}
+	return found.Token, nil
+}
+
+// credentialsReloadDelay is the delay between the credentials file changes and reloading.
+const credentialsReloadDelay = 100 * time.Millisecond
+
+// credentialStore holds the credentials loaded from the credentials file, and reloads them once the file changes.
+type credentialStore struct {
+	path string
+	mu   sync.RWMutex
+	// tokens maps token to credential.
+	tokens map[string]TokenCredential
+	// content is the content of the file loaded last time.
+	content []byte
+	// watcher watches the credentials file, it is nil if the store is not watching.
+	watcher *fsnotify.Watcher
+}
+
+func newCredentialStore(path string) (*credentialStore, error) {
+	s := &credentialStore{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// lookup returns the credential of the token.
+func (s *credentialStore) lookup(token string) (TokenCredential, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.tokens[token]
+	return c, ok
+}
+
+// reload reloads the credentials file if its content has changed.
+func (s *credentialStore) reload() error {
+	buf, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	// the empty content is usually caused by truncating the file before writing.
+	unchanged := s.tokens != nil && (len(buf) == 0 || bytes.Equal(buf, s.content))
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	credentials, err := parseCredentials(buf)
+	if err != nil {
+		return err
+	}
+	tokens := make(map[string]TokenCredential, len(credentials))
+	for _, c := range credentials {
+		tokens[c.Token] = c
+	}
+
+	s.mu.Lock()
+	s.tokens = tokens
+	s.content = buf
+	s.mu.Unlock()
+
+	return nil
+}
+
+// watch reloads the credentials file when it changes, the established connections are not affected.
+// It watches the directory rather than the file, so that replacing the file by renaming works.
+func (s *credentialStore) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	s.watcher = watcher
+
+	go func() {
+		defer watcher.Close()
+
+		// the events are debounced, a file change always produces several events.
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				debounce.Reset(credentialsReloadDelay)
+			case <-debounce.C:
+				if err := s.reload(); err != nil {
+					ylog.Error("failed to reload credentials", "path", s.path, "err", err)
+					continue
+				}
+				ylog.Debug("credentials reloaded", "path", s.path)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				ylog.Error("credentials watcher error", "path", s.path, "err", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func credentialMetadata(c TokenCredential) metadata.M {
+	return metadata.New(c.Metadata)
+}
+
+// close stops watching the credentials file.
+func (s *credentialStore) close() error {
+	if s.watcher == nil {
+		return nil
+	}
+	return s.watcher.Close()
+}
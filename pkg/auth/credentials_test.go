@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+const mockCredentials = `
+credentials:
+  - name: tenant-a
+    token: token-a
+    metadata:
+      tenant: a
+      role: source
+  - name: tenant-b
+    token: token-b-old
+    expires_at: 2000-01-01T00:00:00Z
+  - name: tenant-b
+    token: token-b
+    expires_at: 2100-01-01T00:00:00Z
+`
+
+func TestTokenCredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(mockCredentials), 0o600))
+
+	auth := NewTokenAuth()
+	auth.Init("", "credentials_file="+path)
+
+	md, err := auth.Authenticate("token-a")
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"tenant": "a", "role": "source"}, md)
+
+	md, err = auth.Authenticate("token-b")
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{}, md)
+
+	_, err = auth.Authenticate("token-b-old")
+	assert.EqualError(t, err, "token expired: tenant-b")
+
+	// the empty shared token is disabled.
+	_, err = auth.Authenticate("")
+	assert.EqualError(t, err, "invalid token: ")
+
+	// rotate the token of tenant-a.
+	rotated := `
+credentials:
+  - name: tenant-a
+    token: token-a-new
+    metadata:
+      tenant: a
+`
+	assert.NoError(t, os.WriteFile(path, []byte(rotated), 0o600))
+	assert.Eventually(t, func() bool {
+		_, err := auth.Authenticate("token-a-new")
+		return err == nil
+	}, 3*time.Second, 10*time.Millisecond)
+
+	md, err = auth.Authenticate("token-a-new")
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"tenant": "a"}, md)
+
+	_, err = auth.Authenticate("token-a")
+	assert.EqualError(t, err, "invalid token: token-a")
+
+	// the broken file does not affect the loaded credentials.
+	assert.NoError(t, os.WriteFile(path, []byte("credentials: ["), 0o600))
+	time.Sleep(3 * credentialsReloadDelay)
+	_, err = auth.Authenticate("token-a-new")
+	assert.NoError(t, err)
+}
+
+func TestTokenCredentialsFileWithSharedToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(mockCredentials), 0o600))
+
+	auth := NewTokenAuth()
+	auth.Init("shared-token", "credentials_file="+path)
+
+	_, err := auth.Authenticate("shared-token")
+	assert.NoError(t, err)
+
+	_, err = auth.Authenticate("token-a")
+	assert.NoError(t, err)
+
+	auth = NewTokenAuth()
+	auth.Init("shared-token", "credentials_file="+filepath.Join(t.TempDir(), "not-exist.yaml"))
+
+	_, err = auth.Authenticate("shared-token")
+	assert.ErrorContains(t, err, "token: load credentials")
+}
+
+func TestTokenCredentialsFileReinit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(mockCredentials), 0o600))
+
+	auth := NewTokenAuth()
+	auth.Init("", "credentials_file="+path)
+	previous := auth.credentials
+
+	auth.Init("", "credentials_file="+path)
+	current := auth.credentials
+
+	// the watcher of the previous Init is closed.
+	assert.ErrorIs(t, previous.watcher.Add(t.TempDir()), fsnotify.ErrClosed)
+	assert.NoError(t, current.watcher.Add(t.TempDir()))
+
+	_, err := auth.Authenticate("token-a")
+	assert.NoError(t, err)
+
+	assert.NoError(t, auth.Close())
+	assert.ErrorIs(t, current.watcher.Add(t.TempDir()), fsnotify.ErrClosed)
+}
+
+func TestLookupCredential(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(mockCredentials), 0o600))
+
+	token, err := LookupCredential(path, "tenant-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "token-a", token)
+
+	token, err = LookupCredential(path, "tenant-b")
+	assert.NoError(t, err)
+	assert.Equal(t, "token-b", token)
+
+	_, err = LookupCredential(path, "tenant-c")
+	assert.EqualError(t, err, "credentials: no valid credential named tenant-c")
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yomorun/yomo/core/auth"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+var _ auth.Authentication = (*JWTAuth)(nil)
+
+// JWTAuth is the JWT authentication, it verifies the tokens signed with HS256, RS256 or ES256,
+// and maps the selected claims of a valid token into the connection metadata.
+//
+// The arguments of Init are in the format of `key=value`:
+//
+//	hmac_secret_file=<path>  the file contains the HS256 secret.
+//	public_key_file=<path>   the PEM file contains the RS256 or ES256 public key.
+//	jwks_file=<path>         the JWKS file contains the keys, the key is matched by the `kid` header.
+//	audience=<aud>           the audience that the token must contain.
+//	issuer=<iss>             the issuer that the token must be issued by.
+//	claims=<claim:key,...>   the claims that will be mapped into metadata, e.g. `sub:user-id,tenant:yomo-namespace`,
+//	                         the `yomo-namespace` metadata puts the client into the namespace.
+type JWTAuth struct {
+	secret    []byte
+	publicKey any
+	jwks      map[string]any
+	audience  string
+	issuer    string
+	claims    map[string]string
+	// initErr is the error from Init, it will be returned when authenticating.
+	initErr error
+}
+
+// NewJWTAuth creates a JWT authentication.
+func NewJWTAuth() *JWTAuth {
+	return &JWTAuth{
+		jwks:   make(map[string]any),
+		claims: make(map[string]string),
+	}
+}
+
+// Init authentication initialize arguments, the arguments of the previous Init are replaced.
+func (a *JWTAuth) Init(args ...string) {
+	a.initErr = nil
+
+	// the arguments are parsed into a fresh config, so nothing is left over from the previous Init.
+	c := NewJWTAuth()
+	for _, arg := range args {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			a.initErr = fmt.Errorf("jwt: invalid argument: %s", arg)
+			return
+		}
+		if err := c.setArg(strings.TrimSpace(k), strings.TrimSpace(v)); err != nil {
+			a.initErr = err
+			return
+		}
+	}
+	if c.secret == nil && c.publicKey == nil && len(c.jwks) == 0 {
+		a.initErr = errors.New("jwt: no key has been configured")
+		return
+	}
+	a.secret, a.publicKey, a.jwks = c.secret, c.publicKey, c.jwks
+	a.audience, a.issuer, a.claims = c.audience, c.issuer, c.claims
+}
+
+func (a *JWTAuth) setArg(k, v string) error {
+	switch k {
+	case "hmac_secret_file":
+		secret, err := os.ReadFile(v)
+		if err != nil {
+			return fmt.Errorf("jwt: read hmac secret: %w", err)
+		}
+		a.secret = []byte(strings.TrimSpace(string(secret)))
+	case "public_key_file":
+		buf, err := os.ReadFile(v)
+		if err != nil {
+			return fmt.Errorf("jwt: read public key: %w", err)
+		}
+		key, err := parsePublicKeyPEM(buf)
+		if err != nil {
+			return err
+		}
+		a.publicKey = key
+	case "jwks_file":
+		buf, err := os.ReadFile(v)
+		if err != nil {
+			return fmt.Errorf("jwt: read jwks: %w", err)
+		}
+		keys, err := parseJWKS(buf)
+		if err != nil {
+			return err
+		}
+		a.jwks = keys
+	case "audience":
+		a.audience = v
+	case "issuer":
+		a.issuer = v
+	case "claims":
+		for _, pair := range strings.Split(v, ",") {
+			claim, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok {
+				key = claim
+			}
+			if claim != "" {
+				a.claims[claim] = key
+			}
+		}
+	default:
+		return fmt.Errorf("jwt: unknown argument: %s", k)
+	}
+	return nil
+}
+
+// Authenticate authentication client's credential
+func (a *JWTAuth) Authenticate(payload string) (metadata.M, error) {
+	if a.initErr != nil {
+		return metadata.M{}, a.initErr
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+	}
+	if a.audience != "" {
+		opts = append(opts, jwt.WithAudience(a.audience))
+	}
+	if a.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(payload, claims, a.keyFunc, opts...); err != nil {
+		return metadata.M{}, fmt.Errorf("invalid jwt: %w", err)
+	}
+
+	md := metadata.M{}
+	for claim, key := range a.claims {
+		v, ok := claims[claim]
+		if !ok {
+			continue
+		}
+		md.Set(key, claimString(v))
+	}
+
+	return md, nil
+}
+
+func (a *JWTAuth) keyFunc(token *jwt.Token) (any, error) {
+	if kid, ok := token.Header["kid"].(string); ok && len(a.jwks) > 0 {
+		key, ok := a.jwks[kid]
+		if !ok {
+			return nil, fmt.Errorf("key not found: %s", kid)
+		}
+		return key, nil
+	}
+
+	switch token.Method.Alg() {
+	case "HS256":
+		if a.secret != nil {
+			return a.secret, nil
+		}
+	case "RS256":
+		if key, ok := a.publicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	case "ES256":
+		if key, ok := a.publicKey.(*ecdsa.PublicKey); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no key for algorithm: %s", token.Method.Alg())
+}
+
+// Name authentication name
+func (a *JWTAuth) Name() string {
+	return "jwt"
+}
+
+func claimString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(buf)
+}
+
+func parsePublicKeyPEM(buf []byte) (any, error) {
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, errors.New("jwt: invalid public key PEM")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: parse certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: parse public key: %w", err)
+		}
+		return key, nil
+	}
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func parseJWKS(buf []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(buf, &set); err != nil {
+		return nil, fmt.Errorf("jwt: parse jwks: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwt: parse jwk %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+func init() {
+	auth.Register(NewJWTAuth())
+}
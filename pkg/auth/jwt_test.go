@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+func TestJWTHS256(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("mock-secret\n"), 0o600))
+
+	auth := NewJWTAuth()
+	auth.Init("hmac_secret_file="+secretFile, "audience=yomo", "claims=sub:user-id,tenant")
+
+	assert.Equal(t, "jwt", auth.Name())
+
+	token := signJWT(t, jwt.SigningMethodHS256, []byte("mock-secret"), "", jwt.MapClaims{
+		"sub":    "alice",
+		"tenant": "acme",
+		"aud":    "yomo",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	md, err := auth.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"user-id": "alice", "tenant": "acme"}, md)
+
+	// expired.
+	token = signJWT(t, jwt.SigningMethodHS256, []byte("mock-secret"), "", jwt.MapClaims{
+		"aud": "yomo",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+	_, err = auth.Authenticate(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	// not valid yet.
+	token = signJWT(t, jwt.SigningMethodHS256, []byte("mock-secret"), "", jwt.MapClaims{
+		"aud": "yomo",
+		"nbf": time.Now().Add(time.Hour).Unix(),
+	})
+	_, err = auth.Authenticate(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenNotValidYet)
+
+	// audience mismatch.
+	token = signJWT(t, jwt.SigningMethodHS256, []byte("mock-secret"), "", jwt.MapClaims{"aud": "other"})
+	_, err = auth.Authenticate(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	// wrong secret.
+	token = signJWT(t, jwt.SigningMethodHS256, []byte("other-secret"), "", jwt.MapClaims{"aud": "yomo"})
+	_, err = auth.Authenticate(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestJWTRS256(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	auth := NewJWTAuth()
+	auth.Init("public_key_file="+keyFile, "issuer=yomo-issuer", "claims=role:role")
+
+	token := signJWT(t, jwt.SigningMethodRS256, priv, "", jwt.MapClaims{"iss": "yomo-issuer", "role": "admin"})
+	md, err := auth.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"role": "admin"}, md)
+
+	// the algorithm does not match the key.
+	token = signJWT(t, jwt.SigningMethodHS256, []byte("mock-secret"), "", jwt.MapClaims{"iss": "yomo-issuer"})
+	_, err = auth.Authenticate(token)
+	assert.Error(t, err)
+}
+
+func TestJWTReinit(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("mock-secret"), 0o600))
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	auth := NewJWTAuth()
+	auth.Init("hmac_secret_file="+secretFile, "claims=sub:user-id")
+	auth.Init("public_key_file="+keyFile, "claims=role:role")
+
+	// the secret of the previous Init is no longer valid.
+	token := signJWT(t, jwt.SigningMethodHS256, []byte("mock-secret"), "", jwt.MapClaims{"sub": "alice"})
+	_, err = auth.Authenticate(token)
+	assert.Error(t, err)
+
+	// the claims of the previous Init are not mapped.
+	token = signJWT(t, jwt.SigningMethodRS256, priv, "", jwt.MapClaims{"sub": "alice", "role": "admin"})
+	md, err := auth.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"role": "admin"}, md)
+}
+
+func TestJWTES256WithJWKS(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwks := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kid": "key-1",
+				"kty": "EC",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(priv.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(priv.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+	buf, err := json.Marshal(jwks)
+	assert.NoError(t, err)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksFile, buf, 0o600))
+
+	auth := NewJWTAuth()
+	auth.Init("jwks_file="+jwksFile, "claims=groups:groups")
+
+	token := signJWT(t, jwt.SigningMethodES256, priv, "key-1", jwt.MapClaims{"groups": []string{"a", "b"}})
+	md, err := auth.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{"groups": `["a","b"]`}, md)
+
+	// unknown kid.
+	token = signJWT(t, jwt.SigningMethodES256, priv, "key-2", jwt.MapClaims{})
+	_, err = auth.Authenticate(token)
+	assert.Error(t, err)
+}
+
+func TestJWTInitError(t *testing.T) {
+	auth := NewJWTAuth()
+	auth.Init("audience=yomo")
+
+	_, err := auth.Authenticate("token")
+	assert.EqualError(t, err, "jwt: no key has been configured")
+
+	auth = NewJWTAuth()
+	auth.Init("unknown=value")
+
+	_, err = auth.Authenticate("token")
+	assert.EqualError(t, err, "jwt: unknown argument: unknown")
+}
+
+func signJWT(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
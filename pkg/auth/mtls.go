@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yomorun/yomo/core/auth"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+var _ auth.TLSAuthentication = (*MTLSAuth)(nil)
+
+// MTLSAuth is the mTLS authentication, it takes the identity from the verified client certificate
+// and puts it into the connection metadata. The zipper should verify the client certificate,
+// e.g. set `YOMO_TLS_VERIFY_PEER=true` and `YOMO_TLS_CACERT_FILE=<PATH>`.
+// The client uses `mtls:` as the credential.
+//
+// The arguments of Init are in the format of `key=value`:
+//
+//	identity=<subject|san|spiffe>  the identity of the certificate, the default is subject:
+//	                               subject is the common name of the certificate subject,
+//	                               san is one of DNS names, email addresses, IP addresses and URIs,
+//	                               spiffe is the URI that starts with `spiffe://`.
+//	allow=<id,...>                 the identities that are allowed to connect, all the identities are allowed if empty.
+//	metadata_key=<key>             the metadata key that the identity is put into, the default is `identity`.
+type MTLSAuth struct {
+	identity    string
+	allow       map[string]struct{}
+	metadataKey string
+	// initErr is the error from Init, it will be returned when authenticating.
+	initErr error
+}
+
+// NewMTLSAuth creates a mTLS authentication.
+func NewMTLSAuth() *MTLSAuth {
+	return &MTLSAuth{
+		identity:    "subject",
+		allow:       make(map[string]struct{}),
+		metadataKey: "identity",
+	}
+}
+
+// Init authentication initialize arguments, the arguments of the previous Init are replaced.
+func (a *MTLSAuth) Init(args ...string) {
+	a.initErr = nil
+
+	var (
+		identity    = "subject"
+		allow       = make(map[string]struct{})
+		metadataKey = "identity"
+	)
+	for _, arg := range args {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			a.initErr = fmt.Errorf("mtls: invalid argument: %s", arg)
+			return
+		}
+		v = strings.TrimSpace(v)
+		switch strings.TrimSpace(k) {
+		case "identity":
+			if v != "subject" && v != "san" && v != "spiffe" {
+				a.initErr = fmt.Errorf("mtls: unknown identity: %s", v)
+				return
+			}
+			identity = v
+		case "allow":
+			for _, id := range strings.Split(v, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					allow[id] = struct{}{}
+				}
+			}
+		case "metadata_key":
+			metadataKey = v
+		default:
+			a.initErr = fmt.Errorf("mtls: unknown argument: %s", k)
+			return
+		}
+	}
+	a.identity, a.allow, a.metadataKey = identity, allow, metadataKey
+}
+
+// Authenticate authentication client's credential, the mTLS authentication cannot
+// authenticate without the TLS connection state.
+func (a *MTLSAuth) Authenticate(payload string) (metadata.M, error) {
+	return a.AuthenticateTLS(payload, nil)
+}
+
+// AuthenticateTLS authenticates the client by the verified client certificate.
+func (a *MTLSAuth) AuthenticateTLS(_ string, state *tls.ConnectionState) (metadata.M, error) {
+	if a.initErr != nil {
+		return metadata.M{}, a.initErr
+	}
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return metadata.M{}, errors.New("mtls: no verified client certificate")
+	}
+	cert := state.VerifiedChains[0][0]
+
+	for _, id := range a.identities(cert) {
+		if a.allowed(id) {
+			md := metadata.M{}
+			md.Set(a.metadataKey, id)
+			return md, nil
+		}
+	}
+	return metadata.M{}, fmt.Errorf("mtls: the %s of client certificate is not allowed", a.identity)
+}
+
+// identities returns the candidate identities of the certificate.
+func (a *MTLSAuth) identities(cert *x509.Certificate) []string {
+	ids := []string{}
+	switch a.identity {
+	case "subject":
+		if cert.Subject.CommonName != "" {
+			ids = append(ids, cert.Subject.CommonName)
+		}
+	case "san":
+		ids = append(ids, cert.DNSNames...)
+		ids = append(ids, cert.EmailAddresses...)
+		for _, ip := range cert.IPAddresses {
+			ids = append(ids, ip.String())
+		}
+		for _, uri := range cert.URIs {
+			ids = append(ids, uri.String())
+		}
+	case "spiffe":
+		for _, uri := range cert.URIs {
+			if uri.Scheme == "spiffe" {
+				ids = append(ids, uri.String())
+			}
+		}
+	}
+	return ids
+}
+
+func (a *MTLSAuth) allowed(id string) bool {
+	if len(a.allow) == 0 {
+		return true
+	}
+	_, ok := a.allow[id]
+	return ok
+}
+
+// Name authentication name
+func (a *MTLSAuth) Name() string {
+	return "mtls"
+}
+
+func init() {
+	auth.Register(NewMTLSAuth())
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+func TestMTLS(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://yomo.run/sfn/upper")
+	cert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "sfn-upper"},
+		DNSNames:    []string{"upper.yomo.run"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+		URIs:        []*url.URL{spiffeID},
+	}
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+
+	t.Run("subject", func(t *testing.T) {
+		auth := NewMTLSAuth()
+		auth.Init()
+
+		assert.Equal(t, "mtls", auth.Name())
+
+		md, err := auth.AuthenticateTLS("", state)
+		assert.NoError(t, err)
+		assert.Equal(t, metadata.M{"identity": "sfn-upper"}, md)
+	})
+
+	t.Run("san allowed", func(t *testing.T) {
+		auth := NewMTLSAuth()
+		auth.Init("identity=san", "allow=10.0.0.1, other.yomo.run", "metadata_key=client")
+
+		md, err := auth.AuthenticateTLS("", state)
+		assert.NoError(t, err)
+		assert.Equal(t, metadata.M{"client": "10.0.0.1"}, md)
+	})
+
+	t.Run("spiffe not allowed", func(t *testing.T) {
+		auth := NewMTLSAuth()
+		auth.Init("identity=spiffe", "allow=spiffe://yomo.run/sfn/lower")
+
+		_, err := auth.AuthenticateTLS("", state)
+		assert.EqualError(t, err, "mtls: the spiffe of client certificate is not allowed")
+	})
+
+	t.Run("reinit", func(t *testing.T) {
+		auth := NewMTLSAuth()
+		auth.Init("identity=san", "allow=other.yomo.run", "metadata_key=client")
+		auth.Init("allow=sfn-upper")
+
+		md, err := auth.AuthenticateTLS("", state)
+		assert.NoError(t, err)
+		assert.Equal(t, metadata.M{"identity": "sfn-upper"}, md)
+
+		auth.Init("allow=other.yomo.run")
+
+		_, err = auth.AuthenticateTLS("", state)
+		assert.EqualError(t, err, "mtls: the subject of client certificate is not allowed")
+	})
+
+	t.Run("no certificate", func(t *testing.T) {
+		auth := NewMTLSAuth()
+		auth.Init()
+
+		_, err := auth.Authenticate("")
+		assert.EqualError(t, err, "mtls: no verified client certificate")
+
+		_, err = auth.AuthenticateTLS("", &tls.ConnectionState{})
+		assert.EqualError(t, err, "mtls: no verified client certificate")
+	})
+
+	t.Run("init error", func(t *testing.T) {
+		auth := NewMTLSAuth()
+		auth.Init("identity=email")
+
+		_, err := auth.AuthenticateTLS("", state)
+		assert.EqualError(t, err, "mtls: unknown identity: email")
+	})
+}
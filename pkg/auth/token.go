@@ -1,8 +1,10 @@
-// Package auth provides token based authentication
+// Package auth provides the implementations of authentication, such as token and jwt.
 package auth
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/yomorun/yomo/core/auth"
 	"github.com/yomorun/yomo/core/metadata"
@@ -11,8 +13,16 @@ import (
 var _ auth.Authentication = (*TokenAuth)(nil)
 
 // TokenAuth token authentication (simple)
+//
+// The first argument of Init is the shared token, the following arguments are in the format of `key=value`:
+//
+//	credentials_file=<path>  the credentials file that lists many tokens, each with its own metadata and expiry.
+//	                         The file is reloaded once it changes, see TokenCredential for the format.
 type TokenAuth struct {
-	token string
+	token       string
+	credentials *credentialStore
+	// initErr is the error from Init, it will be returned when authenticating.
+	initErr error
 }
 
 // NewTokenAuth create a token authentication
@@ -20,21 +30,67 @@ func NewTokenAuth() *TokenAuth {
 	return &TokenAuth{}
 }
 
-// Init authentication initialize arguments
+// Init authentication initialize arguments, the credentials file watched by the previous Init is no longer watched.
 func (a *TokenAuth) Init(args ...string) {
+	a.Close()
+	a.initErr = nil
+
 	if len(args) > 0 {
 		a.token = args[0]
 	}
+	for _, arg := range args[min(len(args), 1):] {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok || k != "credentials_file" {
+			a.initErr = fmt.Errorf("token: invalid argument: %s", arg)
+			return
+		}
+		store, err := newCredentialStore(v)
+		if err != nil {
+			a.initErr = fmt.Errorf("token: load credentials: %w", err)
+			return
+		}
+		if err := store.watch(); err != nil {
+			a.initErr = fmt.Errorf("token: watch credentials: %w", err)
+			return
+		}
+		a.Close()
+		a.credentials = store
+	}
 }
 
 // Authenticate authentication client's credential
 func (a *TokenAuth) Authenticate(payload string) (metadata.M, error) {
+	if a.initErr != nil {
+		return metadata.M{}, a.initErr
+	}
+	if a.credentials != nil {
+		if c, ok := a.credentials.lookup(payload); ok {
+			if c.Expired(time.Now()) {
+				return metadata.M{}, fmt.Errorf("token expired: %s", c.Name)
+			}
+			return credentialMetadata(c), nil
+		}
+		// the shared token is disabled if it is empty.
+		if a.token == "" {
+			return metadata.M{}, fmt.Errorf("invalid token: %s", payload)
+		}
+	}
 	if a.token == payload {
 		return metadata.M{}, nil
 	}
 	return metadata.M{}, fmt.Errorf("invalid token: %s", payload)
 }
 
+// Close stops watching the credentials file.
+func (a *TokenAuth) Close() error {
+	if a.credentials == nil {
+		return nil
+	}
+	err := a.credentials.close()
+	a.credentials = nil
+	return err
+}
+
 // Name authentication name
 func (a *TokenAuth) Name() string {
 	return "token"
@@ -138,6 +138,11 @@ func (t *mockDataFlow) SetHandler(fn core.AsyncHandler) error {
 	return nil
 }
 
+func (t *mockDataFlow) SetHandlerE(fn core.AsyncHandlerE) error {
+	t.reducer = func(ctx serverless.Context) { _ = fn(ctx) }
+	return nil
+}
+
 func (t *mockDataFlow) Close() error { return nil }
 
 // this function explains how the data flow works,
@@ -162,3 +167,8 @@ func (t *mockDataFlow) SetWantedTarget(string)                                {
 func (t *mockDataFlow) Wait()                                                 { panic("unimplemented") }
 func (t *mockDataFlow) SetErrorHandler(fn func(err error))                    { panic("unimplemented") }
 func (t *mockDataFlow) WriteWithTarget(_ uint32, _ []byte, _ string) error    { panic("unimplemented") }
+func (t *mockDataFlow) ConnectionState() yomo.ConnectionState                 { panic("unimplemented") }
+func (t *mockDataFlow) Shutdown(ctx context.Context) error                    { panic("unimplemented") }
+func (t *mockDataFlow) Handle(_ uint32, _ core.AsyncHandlerE, _ ...yomo.HandlerOption) error {
+	panic("unimplemented")
+}
@@ -54,12 +54,14 @@ func UnregisterFunction(connID uint64, md metadata.M) {
 }
 
 type connectedFn struct {
-	connID uint64
-	tag    uint32
-	tools  openai.Tool
+	connID    uint64
+	tag       uint32
+	namespace string
+	tools     openai.Tool
 }
 
-// Register provides an stateful register for registering and unregistering functions
+// Register provides an stateful register for registering and unregistering functions.
+// The functions are isolated by the namespace in metadata, see metadata.NamespaceKey.
 type Register interface {
 	// ListToolCalls returns the list of tool calls
 	ListToolCalls(md metadata.M) (map[uint32]openai.Tool, error)
@@ -75,9 +77,13 @@ type register struct {
 
 func (r *register) ListToolCalls(md metadata.M) (map[uint32]openai.Tool, error) {
 	result := make(map[uint32]openai.Tool)
+	ns := metadata.GetNamespace(md)
 
 	r.underlying.Range(func(_, value any) bool {
 		fn := value.(*connectedFn)
+		if fn.namespace != ns {
+			return true
+		}
 		result[fn.tag] = fn.tools
 		return true
 	})
@@ -87,8 +93,9 @@ func (r *register) ListToolCalls(md metadata.M) (map[uint32]openai.Tool, error)
 
 func (r *register) RegisterFunction(tag uint32, functionDefinition *ai.FunctionDefinition, connID uint64, md metadata.M) error {
 	r.underlying.Store(connID, &connectedFn{
-		connID: connID,
-		tag:    tag,
+		connID:    connID,
+		tag:       tag,
+		namespace: metadata.GetNamespace(md),
 		tools: openai.Tool{
 			Type:     openai.ToolTypeFunction,
 			Function: functionDefinition,
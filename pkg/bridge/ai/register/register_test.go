@@ -6,6 +6,7 @@ import (
 	"github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/yomorun/yomo/ai"
+	"github.com/yomorun/yomo/core/metadata"
 )
 
 func TestRegister(t *testing.T) {
@@ -40,6 +41,31 @@ func TestRegister(t *testing.T) {
 	assertToolCalls(t, 0, nil, toolCalls)
 }
 
+func TestRegisterNamespace(t *testing.T) {
+	r := NewDefault()
+
+	fnA := &ai.FunctionDefinition{Name: "function-a"}
+	fnB := &ai.FunctionDefinition{Name: "function-b"}
+
+	mdA := metadata.M{metadata.NamespaceKey: "tenant-a"}
+	mdB := metadata.M{metadata.NamespaceKey: "tenant-b"}
+
+	assert.NoError(t, r.RegisterFunction(1, fnA, 1, mdA))
+	assert.NoError(t, r.RegisterFunction(2, fnB, 2, mdB))
+
+	toolCalls, err := r.ListToolCalls(mdA)
+	assert.NoError(t, err)
+	assertToolCalls(t, 1, fnA, toolCalls)
+
+	toolCalls, err = r.ListToolCalls(mdB)
+	assert.NoError(t, err)
+	assertToolCalls(t, 2, fnB, toolCalls)
+
+	toolCalls, err = r.ListToolCalls(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, toolCalls)
+}
+
 func assertToolCalls(t *testing.T, wantTag uint32, want *ai.FunctionDefinition, toolCalls map[uint32]openai.Tool) {
 	var (
 		tag uint32
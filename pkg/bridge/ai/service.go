@@ -14,6 +14,7 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 	"github.com/yomorun/yomo"
 	"github.com/yomorun/yomo/ai"
+	"github.com/yomorun/yomo/core/auth"
 	"github.com/yomorun/yomo/core/metadata"
 	"github.com/yomorun/yomo/core/ylog"
 	"github.com/yomorun/yomo/pkg/bridge/ai/provider"
@@ -97,14 +98,34 @@ func initOption(opt *ServiceOptions) *ServiceOptions {
 		}
 	}
 	if opt.MetadataExchanger == nil {
-		opt.MetadataExchanger = func(credential string) (metadata.M, error) {
-			return metadata.New(), nil
-		}
+		opt.MetadataExchanger = exchangeNamespace
 	}
 
 	return opt
 }
 
+// exchangeNamespace authenticates the credential by the registered authentication, and returns
+// the metadata that only contains the namespace, so the caller only sees the functions in its namespace.
+// The credential without a registered authentication is in the default namespace, and the authentication
+// failure is returned, so the caller is never put into the default namespace by an invalid credential.
+func exchangeNamespace(credential string) (metadata.M, error) {
+	md := metadata.New()
+
+	cred := auth.NewCredential(credential)
+	authentication, ok := auth.GetAuth(cred.Name())
+	if !ok {
+		return md, nil
+	}
+	authMd, err := authentication.Authenticate(cred.Payload())
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	if ns := metadata.GetNamespace(authMd); ns != "" {
+		md.Set(metadata.NamespaceKey, ns)
+	}
+	return md, nil
+}
+
 func newService(zipperAddr string, provider provider.LLMProvider, ncf newCallerFunc, opt *ServiceOptions) *Service {
 	var onEvict = func(_ string, caller *Caller) {
 		caller.Close()
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/yomorun/yomo"
 	"github.com/yomorun/yomo/ai"
+	"github.com/yomorun/yomo/core/auth"
 	"github.com/yomorun/yomo/core/metadata"
 	"github.com/yomorun/yomo/pkg/bridge/ai/provider"
 	"github.com/yomorun/yomo/pkg/bridge/ai/register"
@@ -349,6 +350,34 @@ func (m *mockCallSyncer) Close() error { return nil }
 
 func toInt(val int) *int { return &val }
 
+func TestExchangeNamespace(t *testing.T) {
+	auth.Register(&namespaceAuth{})
+
+	md, err := exchangeNamespace("namespace-test:tenant-a")
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{metadata.NamespaceKey: "tenant-a"}, md)
+
+	_, err = exchangeNamespace("namespace-test:")
+	assert.Error(t, err)
+
+	md, err = exchangeNamespace("")
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.M{}, md)
+}
+
+// namespaceAuth takes the payload as the namespace, the empty payload is invalid.
+type namespaceAuth struct{}
+
+func (a *namespaceAuth) Init(_ ...string) {}
+func (a *namespaceAuth) Name() string     { return "namespace-test" }
+
+func (a *namespaceAuth) Authenticate(payload string) (metadata.M, error) {
+	if payload == "" {
+		return nil, errors.New("empty payload")
+	}
+	return metadata.M{metadata.NamespaceKey: payload, "other": "value"}, nil
+}
+
 var stopStreamResp = `data: {"id":"chatcmpl-9blY98pEJe6mXGKivCZyl61vxaUFq","object":"chat.completion.chunk","created":1718787945,"model":"gpt-4o-2024-05-13","system_fingerprint":"fp_f4e629d0a5","choices":[{"index":0,"delta":{"role":"assistant","content":""},"logprobs":null,"finish_reason":null}],"usage":null}
 
 data: {"id":"chatcmpl-9blY98pEJe6mXGKivCZyl61vxaUFq","object":"chat.completion.chunk","created":1718787945,"model":"gpt-4o-2024-05-13","system_fingerprint":"fp_f4e629d0a5","choices":[{"index":0,"delta":{"content":"Hello"},"logprobs":null,"finish_reason":null}],"usage":null}
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
+	"github.com/yomorun/yomo/core/acl"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,13 +23,127 @@ type Config struct {
 	// Auth is the way for the source or SFN to be authenticated by the zipper.
 	// The token typed auth has two key-value pairs associated with it:
 	// a `type:token` key-value pair and a `token:<CREDENTIAL>` key-value pair.
+	// Other typed auths take all the other key-value pairs as arguments, e.g.
+	// the jwt typed auth has `type:jwt` and `jwks_file:<PATH>` key-value pairs.
 	Auth map[string]string `yaml:"auth"`
+	// ACL is the publish/subscribe access control of tags, It is matched against the
+	// connection metadata from auth. If ACL is nil, all tags can be published and subscribed.
+	ACL *acl.Config `yaml:"acl"`
+	// Metrics is the prometheus metrics config, the metrics are disabled if it is nil.
+	Metrics *Metrics `yaml:"metrics"`
+	// Admin is the admin http api config, the admin api is disabled if it is nil.
+	Admin *Admin `yaml:"admin"`
+	// Recording is the recording config, the data frames are not recorded if it is nil.
+	Recording *Recording `yaml:"recording"`
+	// RateLimit is the rate limit and quota config, the data frames are not limited if it is nil.
+	RateLimit *RateLimit `yaml:"ratelimit"`
+	// Telemetry is the OpenTelemetry config, the traces, metrics and logs are not exported by OTLP if it is nil.
+	Telemetry *Telemetry `yaml:"telemetry"`
+	// Cron is the config of the cron handlers scheduled by the zipper, the missed ticks are skipped if it is nil.
+	Cron *Cron `yaml:"cron"`
 	// Mesh holds all cascading zippers config. the map-key is mesh name.
 	Mesh map[string]Mesh `yaml:"mesh"`
 	// Bridge is the bridge config.
 	Bridge map[string]any `yaml:"bridge"`
 }
 
+// Metrics describes the prometheus metrics endpoint of the zipper.
+type Metrics struct {
+	// Listen is the address that serves the `/metrics` endpoint, e.g. `:9091`.
+	Listen string `yaml:"listen"`
+}
+
+// Admin describes the admin http api of the zipper.
+type Admin struct {
+	// Listen is the address that serves the admin api, e.g. `127.0.0.1:9092`.
+	Listen string `yaml:"listen"`
+	// Credential is the credential that the admin api requests carry in the `Authorization: Bearer` header.
+	Credential string `yaml:"credential"`
+}
+
+// Recording describes how the zipper records the data frames, the recording can be replayed by `yomo replay`.
+type Recording struct {
+	// File is the recording file, the frames are appended if the file exists.
+	File string `yaml:"file"`
+	// Tags are the tags or tag ranges to record, e.g. `0x10` and `0x10-0x20`, all the tags are recorded if it is empty.
+	Tags []string `yaml:"tags"`
+}
+
+// RateLimit describes the rate limits and byte quotas of the data frames written to the zipper, it looks like:
+//
+//	ratelimit:
+//	  store: ./quota.json
+//	  rules:
+//	    - name: per-source
+//	      key: client
+//	      rate: 100
+//	      burst: 200
+//	      action: delay
+//	    - name: tenant-quota
+//	      key: metadata:yomo-namespace
+//	      quota_bytes: 1073741824
+//	      quota_period: monthly
+//	      action: reject
+type RateLimit struct {
+	// Store is the file that persists the byte quota usage, the usage is kept in memory only if it is empty.
+	Store string `yaml:"store"`
+	// Rules are the rules of rate limit, a data frame is checked by all the rules that it matches.
+	Rules []RateLimitRule `yaml:"rules"`
+}
+
+// RateLimitRule limits the data frames of the connections that match it.
+type RateLimitRule struct {
+	// Name is the name of the rule, it identifies the persisted quota usage, the index of the rule is used if it is empty.
+	Name string `yaml:"name"`
+	// Key decides how the data frames share the limits, it is `client` for the client name, `tag` for the tag,
+	// or `metadata:<key>` for a metadata value of the connection, e.g. `metadata:yomo-namespace` for the tenant.
+	Key string `yaml:"key"`
+	// Match is the metadata that the connection must carry, the value `*` only requires the key to exist.
+	// An empty Match matches all connections.
+	Match map[string]string `yaml:"match"`
+	// Tags are the tags or tag ranges that the rule applies to, e.g. `0x10` and `0x10-0x20`, all the tags if it is empty.
+	Tags []string `yaml:"tags"`
+	// Rate is the number of data frames per second, the frames are not rate limited if it is zero.
+	Rate float64 `yaml:"rate"`
+	// Burst is the number of data frames that can exceed the rate at once, it is the rate rounded up by default.
+	Burst int `yaml:"burst"`
+	// QuotaBytes is the payload bytes allowed in a quota period, there is no quota if it is zero.
+	QuotaBytes int64 `yaml:"quota_bytes"`
+	// QuotaPeriod is the quota period, it is `daily` or `monthly` in UTC, default is `daily`.
+	QuotaPeriod string `yaml:"quota_period"`
+	// Action is what to do with the data frames over the limit, it is `drop`, `delay` or `reject`, default is `drop`.
+	// `delay` waits for the rate limit at most MaxDelay, and `reject` answers the writer with a GoawayFrame.
+	Action string `yaml:"action"`
+	// MaxDelay is the longest time that `delay` waits, the frame is dropped if it should wait longer, default is 1s.
+	MaxDelay time.Duration `yaml:"max_delay"`
+}
+
+// Telemetry describes how the zipper exports the traces, metrics and logs to an OpenTelemetry collector by OTLP/HTTP.
+type Telemetry struct {
+	// Endpoint is the OTLP/HTTP endpoint of the collector, e.g. `http://localhost:4318`.
+	Endpoint string `yaml:"endpoint"`
+	// Headers are the headers of the export requests, e.g. the api key of the collector.
+	Headers map[string]string `yaml:"headers"`
+	// ServiceName is the service name of the signals, it is `yomo` by default.
+	ServiceName string `yaml:"service_name"`
+	// Traces enables exporting the traces.
+	Traces bool `yaml:"traces"`
+	// Metrics enables exporting the metrics, they are the same as the prometheus metrics.
+	Metrics bool `yaml:"metrics"`
+	// MetricsInterval is the interval of exporting the metrics, it is 1m by default.
+	MetricsInterval time.Duration `yaml:"metrics_interval"`
+	// Logs enables exporting the logs.
+	Logs bool `yaml:"logs"`
+}
+
+// Cron describes how the zipper schedules the cron handlers of the stream functions,
+// the zipper triggers one instance of the stream function on each tick.
+type Cron struct {
+	// MissedTicks is the policy of the ticks missed because no instance is connected, it is `skip` or `fire_once`,
+	// `fire_once` triggers the cron handler once when an instance connects after the missed ticks.
+	MissedTicks string `yaml:"missed_ticks"`
+}
+
 // Mesh describes a cascading zipper config.
 type Mesh struct {
 	// Host is the host of mesh zipper.
@@ -36,7 +153,48 @@ type Mesh struct {
 	// Credential is the credential when connect to mesh zipper.
 	// It is in the format of 'authType:authPayload', separated by a colon.
 	// If Credential is empty, it represents that mesh will not authenticate the current Zipper.
+	// The mesh zipper keeps the namespaces of the forwarded data only if the credential has the `yomo-mesh` metadata.
 	Credential string `yaml:"credential"`
+	// CredentialFile is the credentials file that holds the token of mesh zipper,
+	// If it is set, the token of the credential named CredentialName will be used as the credential,
+	// the file is read again on reconnection, so the token can be rotated without restart.
+	CredentialFile string `yaml:"credential_file"`
+	// CredentialName is the name of the credential in the CredentialFile.
+	CredentialName string `yaml:"credential_name"`
+}
+
+// AuthArgs returns the auth type and the arguments for initializing the auth.
+// The token typed auth takes the token as the first argument, followed by the
+// `credentials_file=<PATH>` argument if the credentials file is set. Other typed auths take
+// all the key-value pairs except the type in the format of `key=value`.
+func (c Config) AuthArgs() (string, []string) {
+	authType, ok := c.Auth["type"]
+	if !ok {
+		return "", nil
+	}
+	if authType == "token" {
+		token, hasToken := c.Auth["token"]
+		file, hasFile := c.Auth["credentials_file"]
+		switch {
+		case hasFile:
+			return authType, []string{token, "credentials_file=" + file}
+		case hasToken:
+			return authType, []string{token}
+		default:
+			return "", nil
+		}
+	}
+
+	args := []string{}
+	for k, v := range c.Auth {
+		if k == "type" {
+			continue
+		}
+		args = append(args, k+"="+v)
+	}
+	sort.Strings(args)
+
+	return authType, args
 }
 
 // ErrConfigExt represents the extension of config file is incorrect.
@@ -70,3 +70,21 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthArgs(t *testing.T) {
+	authType, args := Config{}.AuthArgs()
+	assert.Equal(t, "", authType)
+	assert.Empty(t, args)
+
+	authType, args = Config{Auth: map[string]string{"type": "token", "token": "<CREDENTIAL>"}}.AuthArgs()
+	assert.Equal(t, "token", authType)
+	assert.Equal(t, []string{"<CREDENTIAL>"}, args)
+
+	authType, args = Config{Auth: map[string]string{"type": "token", "credentials_file": "credentials.yaml"}}.AuthArgs()
+	assert.Equal(t, "token", authType)
+	assert.Equal(t, []string{"", "credentials_file=credentials.yaml"}, args)
+
+	authType, args = Config{Auth: map[string]string{"type": "jwt", "jwks_file": "jwks.json", "audience": "yomo"}}.AuthArgs()
+	assert.Equal(t, "jwt", authType)
+	assert.Equal(t, []string{"audience=yomo", "jwks_file=jwks.json"}, args)
+}
@@ -51,6 +51,10 @@ func (c *y3codec) Encode(f frame.Frame) ([]byte, error) {
 		return encodeGoawayFrame(ff)
 	case *frame.ConnectToFrame:
 		return encodeConnectToFrame(ff)
+	case *frame.UnsubscribeFrame:
+		return encodeUnsubscribeFrame(ff)
+	case *frame.CronTriggerFrame:
+		return encodeCronTriggerFrame(ff)
 	default:
 		return nil, ErrUnknownFrame
 	}
@@ -70,6 +74,10 @@ func (c *y3codec) Decode(data []byte, f frame.Frame) error {
 		return decodeGoawayFrame(data, ff)
 	case *frame.ConnectToFrame:
 		return decodeConnectToFrame(data, ff)
+	case *frame.UnsubscribeFrame:
+		return decodeUnsubscribeFrame(data, ff)
+	case *frame.CronTriggerFrame:
+		return decodeCronTriggerFrame(data, ff)
 	default:
 		return ErrUnknownFrame
 	}
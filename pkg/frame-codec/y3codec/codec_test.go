@@ -100,6 +100,22 @@ func TestCodec(t *testing.T) {
 				data:  []byte{0xa9, 0x0},
 			},
 		},
+		{
+			name: "UnsubscribeFrame",
+			args: args{
+				newF:  new(frame.UnsubscribeFrame),
+				dataF: &frame.UnsubscribeFrame{},
+				data:  []byte{0xaa, 0x0},
+			},
+		},
+		{
+			name: "CronTriggerFrame",
+			args: args{
+				newF:  new(frame.CronTriggerFrame),
+				dataF: &frame.CronTriggerFrame{Time: 1700000000000},
+				data:  []byte{0xab, 0x8, 0x1, 0x6, 0x1, 0x8b, 0xcf, 0xe5, 0x68, 0x0},
+			},
+		},
 		{
 			name: "RejectedFrame",
 			args: args{
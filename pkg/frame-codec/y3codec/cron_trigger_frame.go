@@ -0,0 +1,42 @@
+package y3codec
+
+import (
+	"github.com/yomorun/y3"
+	frame "github.com/yomorun/yomo/core/frame"
+)
+
+// encodeCronTriggerFrame encodes CronTriggerFrame to Y3 encoded bytes.
+func encodeCronTriggerFrame(f *frame.CronTriggerFrame) ([]byte, error) {
+	// time
+	timeBlock := y3.NewPrimitivePacketEncoder(tagCronTriggerTime)
+	timeBlock.SetInt64Value(f.Time)
+	// frame
+	ff := y3.NewNodePacketEncoder(byte(f.Type()))
+	ff.AddPrimitivePacket(timeBlock)
+
+	return ff.Encode(), nil
+}
+
+// decodeCronTriggerFrame decodes Y3 encoded bytes to CronTriggerFrame.
+func decodeCronTriggerFrame(data []byte, f *frame.CronTriggerFrame) error {
+	node := y3.NodePacket{}
+	_, err := y3.DecodeToNodePacket(data, &node)
+	if err != nil {
+		return err
+	}
+
+	// time
+	if timeBlock, ok := node.PrimitivePackets[tagCronTriggerTime]; ok {
+		t, err := timeBlock.ToInt64()
+		if err != nil {
+			return err
+		}
+		f.Time = t
+	}
+
+	return nil
+}
+
+var (
+	tagCronTriggerTime byte = 0x01
+)
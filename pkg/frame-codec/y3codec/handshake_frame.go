@@ -52,6 +52,12 @@ func encodeHandshakeFrame(f *frame.HandshakeFrame) ([]byte, error) {
 	handshake.AddPrimitivePacket(versionBlock)
 	handshake.AddPrimitivePacket(fdBlock)
 	handshake.AddPrimitivePacket(wantTargetBlock)
+	// cron spec, it is only encoded if set, so the handshake is unchanged for the servers that do not schedule cron.
+	if f.CronSpec != "" {
+		cronSpecBlock := y3.NewPrimitivePacketEncoder(tagHandshakeCronSpec)
+		cronSpecBlock.SetStringValue(f.CronSpec)
+		handshake.AddPrimitivePacket(cronSpecBlock)
+	}
 
 	return handshake.Encode(), nil
 }
@@ -131,6 +137,14 @@ func decodeHandshakeFrame(data []byte, f *frame.HandshakeFrame) error {
 		}
 		f.WantedTarget = wantTarget
 	}
+	// cron spec
+	if cronSpecBlock, ok := node.PrimitivePackets[tagHandshakeCronSpec]; ok {
+		cronSpec, err := cronSpecBlock.ToUTF8String()
+		if err != nil {
+			return err
+		}
+		f.CronSpec = cronSpec
+	}
 
 	return nil
 }
@@ -145,4 +159,5 @@ const (
 	tagHandshakeVersion            byte = 0x07
 	tagHandshakeWantedTarget       byte = 0x08
 	tagHandshakeFunctionDefinition byte = 0x09
+	tagHandshakeCronSpec           byte = 0x0A
 )
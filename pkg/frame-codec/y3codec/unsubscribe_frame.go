@@ -0,0 +1,22 @@
+package y3codec
+
+import (
+	"github.com/yomorun/y3"
+	frame "github.com/yomorun/yomo/core/frame"
+)
+
+// encodeUnsubscribeFrame encodes UnsubscribeFrame to Y3 encoded bytes.
+func encodeUnsubscribeFrame(f *frame.UnsubscribeFrame) ([]byte, error) {
+	unsubscribe := y3.NewNodePacketEncoder(byte(f.Type()))
+	return unsubscribe.Encode(), nil
+}
+
+// decodeUnsubscribeFrame decodes Y3 encoded bytes to UnsubscribeFrame.
+func decodeUnsubscribeFrame(data []byte, _ *frame.UnsubscribeFrame) error {
+	node := y3.NodePacket{}
+	_, err := y3.DecodeToNodePacket(data, &node)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -72,6 +72,12 @@ func (p *FrameConn) LocalAddr() net.Addr {
 	return p.conn.LocalAddr()
 }
 
+// ConnectionState returns the TLS connection state of the connection,
+// The verified peer certificates can be retrieved from it.
+func (p *FrameConn) ConnectionState() tls.ConnectionState {
+	return p.conn.ConnectionState().TLS
+}
+
 // CloseWithError closes the connection.
 // After calling CloseWithError, ReadFrame and WriteFrame will return frame.ErrConnClosed error.
 func (p *FrameConn) CloseWithError(errString string) error {
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var _ core.Observer = (*OTel)(nil)
+
+// OTel collects the metrics of zipper by the otel metric api, they are the same metrics as Prometheus,
+// so they can be exported to an OpenTelemetry collector by OTLP.
+type OTel struct {
+	framesReceived     metric.Int64Counter
+	bytesReceived      metric.Int64Counter
+	frameErrors        metric.Int64Counter
+	framesRouted       metric.Int64Counter
+	bytesRouted        metric.Int64Counter
+	routeErrors        metric.Int64Counter
+	framesForwarded    metric.Int64Counter
+	forwardErrors      metric.Int64Counter
+	connections        metric.Int64UpDownCounter
+	routingDuration    metric.Float64Histogram
+	handshakeFailures  metric.Int64Counter
+	mu                 sync.Mutex
+	downstreamStatusFn func() map[string]bool
+}
+
+// NewOTel returns the otel metrics of zipper, the instruments are created by the meter provider.
+func NewOTel(mp metric.MeterProvider) (*OTel, error) {
+	var (
+		o     = &OTel{}
+		meter = mp.Meter("yomo-zipper")
+		err   error
+		errs  []error
+	)
+
+	o.framesReceived, err = meter.Int64Counter("yomo.zipper.frames.received",
+		metric.WithDescription("The number of data frames received from sources and stream functions."))
+	errs = append(errs, err)
+	o.bytesReceived, err = meter.Int64Counter("yomo.zipper.bytes.received", metric.WithUnit("By"),
+		metric.WithDescription("The payload bytes of data frames received from sources and stream functions."))
+	errs = append(errs, err)
+	o.frameErrors, err = meter.Int64Counter("yomo.zipper.frame.errors",
+		metric.WithDescription("The number of data frames that are denied or failed to be handled."))
+	errs = append(errs, err)
+	o.framesRouted, err = meter.Int64Counter("yomo.zipper.frames.routed",
+		metric.WithDescription("The number of data frames routed to stream functions."))
+	errs = append(errs, err)
+	o.bytesRouted, err = meter.Int64Counter("yomo.zipper.bytes.routed", metric.WithUnit("By"),
+		metric.WithDescription("The payload bytes of data frames routed to stream functions."))
+	errs = append(errs, err)
+	o.routeErrors, err = meter.Int64Counter("yomo.zipper.route.errors",
+		metric.WithDescription("The number of data frames that failed to be routed to stream functions."))
+	errs = append(errs, err)
+	o.framesForwarded, err = meter.Int64Counter("yomo.zipper.frames.forwarded",
+		metric.WithDescription("The number of data frames forwarded to downstream zippers."))
+	errs = append(errs, err)
+	o.forwardErrors, err = meter.Int64Counter("yomo.zipper.forward.errors",
+		metric.WithDescription("The number of data frames that failed to be forwarded to downstream zippers."))
+	errs = append(errs, err)
+	o.connections, err = meter.Int64UpDownCounter("yomo.zipper.connections",
+		metric.WithDescription("The number of connections by client type."))
+	errs = append(errs, err)
+	o.routingDuration, err = meter.Float64Histogram("yomo.zipper.routing.duration", metric.WithUnit("s"),
+		metric.WithDescription("The time spent in routing a data frame."),
+		metric.WithExplicitBucketBoundaries(.00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1))
+	errs = append(errs, err)
+	o.handshakeFailures, err = meter.Int64Counter("yomo.zipper.handshake.failures",
+		metric.WithDescription("The number of failed handshakes by reason."))
+	errs = append(errs, err)
+	_, err = meter.Int64ObservableGauge("yomo.zipper.downstream.up",
+		metric.WithDescription("Whether the downstream zipper of mesh is connected."),
+		metric.WithInt64Callback(o.observeDownstreams))
+	errs = append(errs, err)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// WatchDownstreams sets the function that reports whether the downstream zippers are connected,
+// it is called when the metrics are collected.
+func (o *OTel) WatchDownstreams(fn func() map[string]bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.downstreamStatusFn = fn
+}
+
+func (o *OTel) observeDownstreams(_ context.Context, observer metric.Int64Observer) error {
+	o.mu.Lock()
+	fn := o.downstreamStatusFn
+	o.mu.Unlock()
+
+	if fn == nil {
+		return nil
+	}
+	for name, connected := range fn() {
+		up := int64(0)
+		if connected {
+			up = 1
+		}
+		observer.Observe(up, metric.WithAttributes(attribute.String("downstream", name)))
+	}
+	return nil
+}
+
+// ConnOpened implements core.Observer.
+func (o *OTel) ConnOpened(conn core.ConnectionInfo) {
+	o.connections.Add(context.Background(), 1, metric.WithAttributes(attribute.String("client_type", conn.ClientType().String())))
+}
+
+// ConnClosed implements core.Observer.
+func (o *OTel) ConnClosed(conn core.ConnectionInfo) {
+	o.connections.Add(context.Background(), -1, metric.WithAttributes(attribute.String("client_type", conn.ClientType().String())))
+}
+
+// HandshakeFailed implements core.Observer.
+func (o *OTel) HandshakeFailed(clientType core.ClientType, reason string) {
+	o.handshakeFailures.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("client_type", clientType.String()),
+		attribute.String("reason", reason),
+	))
+}
+
+// FrameReceived implements core.Observer.
+func (o *OTel) FrameReceived(from core.ConnectionInfo, tag frame.Tag, size int, elapsed time.Duration, err error) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(
+		tagAttr(tag),
+		attribute.String("client_type", from.ClientType().String()),
+		attribute.String("client_name", from.Name()),
+	)
+
+	o.framesReceived.Add(ctx, 1, attrs)
+	o.bytesReceived.Add(ctx, int64(size), attrs)
+	if err != nil {
+		o.frameErrors.Add(ctx, 1, attrs)
+		return
+	}
+	o.routingDuration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(tagAttr(tag)))
+}
+
+// FrameRouted implements core.Observer.
+func (o *OTel) FrameRouted(to core.ConnectionInfo, tag frame.Tag, size int, err error) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(tagAttr(tag), attribute.String("sfn", to.Name()))
+
+	if err != nil {
+		o.routeErrors.Add(ctx, 1, attrs)
+		return
+	}
+	o.framesRouted.Add(ctx, 1, attrs)
+	o.bytesRouted.Add(ctx, int64(size), attrs)
+}
+
+// FrameForwarded implements core.Observer.
+func (o *OTel) FrameForwarded(downstream string, _ frame.Tag, _ int, err error) {
+	attrs := metric.WithAttributes(attribute.String("downstream", downstream))
+
+	if err != nil {
+		o.forwardErrors.Add(context.Background(), 1, attrs)
+		return
+	}
+	o.framesForwarded.Add(context.Background(), 1, attrs)
+}
+
+func tagAttr(tag frame.Tag) attribute.KeyValue {
+	return attribute.String("tag", tagLabel(tag))
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/ylog"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOTel(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	o, err := NewOTel(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	assert.NoError(t, err)
+
+	source := core.NewConnection(1, "source", "source-id", core.ClientTypeSource, nil, nil, nil, ylog.Default())
+	sfn := core.NewConnection(2, "sfn", "sfn-id", core.ClientTypeStreamFunction, nil, []uint32{1}, nil, ylog.Default())
+
+	o.ConnOpened(source)
+	o.ConnOpened(sfn)
+	o.ConnClosed(sfn)
+	o.HandshakeFailed(core.ClientTypeSource, core.HandshakeFailedAuthentication)
+	o.FrameReceived(source, 1, 10, time.Millisecond, nil)
+	o.FrameReceived(source, 1, 5, 0, core.ErrFrameDenied)
+	o.FrameRouted(sfn, 1, 10, nil)
+	o.FrameRouted(sfn, 1, 10, errors.New("write error"))
+	o.FrameForwarded("zipper-2", 1, 10, nil)
+	o.WatchDownstreams(func() map[string]bool {
+		return map[string]bool{"zipper-2": true, "zipper-3": false}
+	})
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+
+	values := map[string]map[attribute.Distinct]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			values[m.Name] = map[attribute.Distinct]int64{}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name][dp.Attributes.Equivalent()] = dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name][dp.Attributes.Equivalent()] = dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name][dp.Attributes.Equivalent()] = int64(dp.Count)
+				}
+			}
+		}
+	}
+	key := func(kv ...attribute.KeyValue) attribute.Distinct {
+		set := attribute.NewSet(kv...)
+		return set.Equivalent()
+	}
+	value := func(name string, kv ...attribute.KeyValue) int64 {
+		return values[name][key(kv...)]
+	}
+
+	received := []attribute.KeyValue{
+		attribute.String("tag", "1"), attribute.String("client_type", "Source"), attribute.String("client_name", "source"),
+	}
+	routed := []attribute.KeyValue{attribute.String("tag", "1"), attribute.String("sfn", "sfn")}
+
+	assert.Equal(t, int64(1), value("yomo.zipper.connections", attribute.String("client_type", "Source")))
+	assert.Equal(t, int64(0), value("yomo.zipper.connections", attribute.String("client_type", "StreamFunction")))
+	assert.Equal(t, int64(1), value("yomo.zipper.handshake.failures",
+		attribute.String("client_type", "Source"), attribute.String("reason", "authentication")))
+	assert.Equal(t, int64(2), value("yomo.zipper.frames.received", received...))
+	assert.Equal(t, int64(15), value("yomo.zipper.bytes.received", received...))
+	assert.Equal(t, int64(1), value("yomo.zipper.frame.errors", received...))
+	assert.Equal(t, int64(1), value("yomo.zipper.routing.duration", attribute.String("tag", "1")))
+	assert.Equal(t, int64(1), value("yomo.zipper.frames.routed", routed...))
+	assert.Equal(t, int64(10), value("yomo.zipper.bytes.routed", routed...))
+	assert.Equal(t, int64(1), value("yomo.zipper.route.errors", routed...))
+	assert.Equal(t, int64(1), value("yomo.zipper.frames.forwarded", attribute.String("downstream", "zipper-2")))
+	assert.Equal(t, int64(1), value("yomo.zipper.downstream.up", attribute.String("downstream", "zipper-2")))
+	assert.Equal(t, int64(0), value("yomo.zipper.downstream.up", attribute.String("downstream", "zipper-3")))
+	assert.Contains(t, values["yomo.zipper.downstream.up"], key(attribute.String("downstream", "zipper-3")))
+}
@@ -0,0 +1,262 @@
+// Package metrics provides the metrics of zipper.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+)
+
+const namespace = "yomo_zipper"
+
+var _ core.Observer = (*Prometheus)(nil)
+
+// Prometheus collects the metrics of zipper and exposes them in the prometheus format.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	framesReceived     *prometheus.CounterVec
+	bytesReceived      *prometheus.CounterVec
+	frameErrors        *prometheus.CounterVec
+	framesRouted       *prometheus.CounterVec
+	bytesRouted        *prometheus.CounterVec
+	routeErrors        *prometheus.CounterVec
+	framesForwarded    *prometheus.CounterVec
+	forwardErrors      *prometheus.CounterVec
+	connections        *prometheus.GaugeVec
+	routingDuration    *prometheus.HistogramVec
+	handshakeFailures  *prometheus.CounterVec
+	downstreamUp       *prometheus.Desc
+	mu                 sync.Mutex
+	downstreamStatusFn func() map[string]bool
+	server             *http.Server
+	closed             bool
+}
+
+// NewPrometheus returns the prometheus metrics of zipper.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		framesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "frames_received_total",
+			Help:      "The number of data frames received from sources and stream functions.",
+		}, []string{"tag", "client_type", "client_name"}),
+		bytesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_received_total",
+			Help:      "The payload bytes of data frames received from sources and stream functions.",
+		}, []string{"tag", "client_type", "client_name"}),
+		frameErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "frame_errors_total",
+			Help:      "The number of data frames that are denied or failed to be handled.",
+		}, []string{"tag", "client_type", "client_name"}),
+		framesRouted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "frames_routed_total",
+			Help:      "The number of data frames routed to stream functions.",
+		}, []string{"tag", "sfn"}),
+		bytesRouted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_routed_total",
+			Help:      "The payload bytes of data frames routed to stream functions.",
+		}, []string{"tag", "sfn"}),
+		routeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "route_errors_total",
+			Help:      "The number of data frames that failed to be routed to stream functions.",
+		}, []string{"tag", "sfn"}),
+		framesForwarded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "frames_forwarded_total",
+			Help:      "The number of data frames forwarded to downstream zippers.",
+		}, []string{"downstream"}),
+		forwardErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "forward_errors_total",
+			Help:      "The number of data frames that failed to be forwarded to downstream zippers.",
+		}, []string{"downstream"}),
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "connections",
+			Help:      "The number of connections by client type.",
+		}, []string{"client_type"}),
+		routingDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "routing_duration_seconds",
+			Help:      "The time spent in routing a data frame.",
+			Buckets:   []float64{.00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+		}, []string{"tag"}),
+		handshakeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "handshake_failures_total",
+			Help:      "The number of failed handshakes by reason.",
+		}, []string{"client_type", "reason"}),
+		downstreamUp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "downstream_up"),
+			"Whether the downstream zipper of mesh is connected.",
+			[]string{"downstream"}, nil,
+		),
+	}
+
+	p.registry.MustRegister(
+		p.framesReceived, p.bytesReceived, p.frameErrors,
+		p.framesRouted, p.bytesRouted, p.routeErrors,
+		p.framesForwarded, p.forwardErrors,
+		p.connections, p.routingDuration, p.handshakeFailures,
+		downstreamCollector{p},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return p
+}
+
+// WatchDownstreams sets the function that reports whether the downstream zippers are connected,
+// it is called when the metrics are scraped.
+func (p *Prometheus) WatchDownstreams(fn func() map[string]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.downstreamStatusFn = fn
+}
+
+// Registry returns the registry of metrics, it can be used to register other collectors.
+func (p *Prometheus) Registry() *prometheus.Registry {
+	return p.registry
+}
+
+// Handler returns the http handler that serves the metrics.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// ListenAndServe serves the metrics on the `/metrics` path of the address,
+// it returns nil after the metrics server is closed.
+func (p *Prometheus) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p.Handler())
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.server = &http.Server{Addr: addr, Handler: mux}
+	server := p.server
+	p.mu.Unlock()
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully shuts down the metrics server.
+func (p *Prometheus) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	server := p.server
+	p.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Close shuts down the metrics server, it waits at most 5 seconds for the scrapes in flight.
+func (p *Prometheus) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return p.Shutdown(ctx)
+}
+
+// ConnOpened implements core.Observer.
+func (p *Prometheus) ConnOpened(conn core.ConnectionInfo) {
+	p.connections.WithLabelValues(conn.ClientType().String()).Inc()
+}
+
+// ConnClosed implements core.Observer.
+func (p *Prometheus) ConnClosed(conn core.ConnectionInfo) {
+	p.connections.WithLabelValues(conn.ClientType().String()).Dec()
+}
+
+// HandshakeFailed implements core.Observer.
+func (p *Prometheus) HandshakeFailed(clientType core.ClientType, reason string) {
+	p.handshakeFailures.WithLabelValues(clientType.String(), reason).Inc()
+}
+
+// FrameReceived implements core.Observer.
+func (p *Prometheus) FrameReceived(from core.ConnectionInfo, tag frame.Tag, size int, elapsed time.Duration, err error) {
+	t := tagLabel(tag)
+	clientType := from.ClientType().String()
+
+	p.framesReceived.WithLabelValues(t, clientType, from.Name()).Inc()
+	p.bytesReceived.WithLabelValues(t, clientType, from.Name()).Add(float64(size))
+	if err != nil {
+		p.frameErrors.WithLabelValues(t, clientType, from.Name()).Inc()
+		return
+	}
+	p.routingDuration.WithLabelValues(t).Observe(elapsed.Seconds())
+}
+
+// FrameRouted implements core.Observer.
+func (p *Prometheus) FrameRouted(to core.ConnectionInfo, tag frame.Tag, size int, err error) {
+	t := tagLabel(tag)
+
+	if err != nil {
+		p.routeErrors.WithLabelValues(t, to.Name()).Inc()
+		return
+	}
+	p.framesRouted.WithLabelValues(t, to.Name()).Inc()
+	p.bytesRouted.WithLabelValues(t, to.Name()).Add(float64(size))
+}
+
+// FrameForwarded implements core.Observer.
+func (p *Prometheus) FrameForwarded(downstream string, _ frame.Tag, _ int, err error) {
+	if err != nil {
+		p.forwardErrors.WithLabelValues(downstream).Inc()
+		return
+	}
+	p.framesForwarded.WithLabelValues(downstream).Inc()
+}
+
+// downstreamCollector collects the status of downstream zippers when the metrics are scraped.
+type downstreamCollector struct{ p *Prometheus }
+
+func (c downstreamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.p.downstreamUp
+}
+
+func (c downstreamCollector) Collect(ch chan<- prometheus.Metric) {
+	c.p.mu.Lock()
+	fn := c.p.downstreamStatusFn
+	c.p.mu.Unlock()
+
+	if fn == nil {
+		return
+	}
+	for name, connected := range fn() {
+		up := 0.0
+		if connected {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.p.downstreamUp, prometheus.GaugeValue, up, name)
+	}
+}
+
+func tagLabel(tag frame.Tag) string {
+	return strconv.FormatUint(uint64(tag), 10)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/ylog"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+
+	source := core.NewConnection(1, "source", "source-id", core.ClientTypeSource, nil, nil, nil, ylog.Default())
+	sfn := core.NewConnection(2, "sfn", "sfn-id", core.ClientTypeStreamFunction, nil, []uint32{1}, nil, ylog.Default())
+
+	p.ConnOpened(source)
+	p.ConnOpened(sfn)
+	p.ConnClosed(sfn)
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.connections.WithLabelValues("Source")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(p.connections.WithLabelValues("StreamFunction")))
+
+	p.HandshakeFailed(core.ClientTypeSource, core.HandshakeFailedAuthentication)
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.handshakeFailures.WithLabelValues("Source", "authentication")))
+
+	p.FrameReceived(source, 1, 10, time.Millisecond, nil)
+	p.FrameReceived(source, 1, 5, 0, core.ErrFrameDenied)
+	assert.Equal(t, 2.0, testutil.ToFloat64(p.framesReceived.WithLabelValues("1", "Source", "source")))
+	assert.Equal(t, 15.0, testutil.ToFloat64(p.bytesReceived.WithLabelValues("1", "Source", "source")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.frameErrors.WithLabelValues("1", "Source", "source")))
+	assert.Equal(t, 1, testutil.CollectAndCount(p.routingDuration))
+
+	p.FrameRouted(sfn, 1, 10, nil)
+	p.FrameRouted(sfn, 1, 10, errors.New("write error"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.framesRouted.WithLabelValues("1", "sfn")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(p.bytesRouted.WithLabelValues("1", "sfn")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.routeErrors.WithLabelValues("1", "sfn")))
+
+	p.FrameForwarded("zipper-2", 1, 10, nil)
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.framesForwarded.WithLabelValues("zipper-2")))
+
+	p.WatchDownstreams(func() map[string]bool {
+		return map[string]bool{"zipper-2": true, "zipper-3": false}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `yomo_zipper_downstream_up{downstream="zipper-2"} 1`)
+	assert.Contains(t, string(body), `yomo_zipper_downstream_up{downstream="zipper-3"} 0`)
+	assert.Contains(t, string(body), `yomo_zipper_frames_received_total{client_name="source",client_type="Source",tag="1"} 2`)
+}
+
+func TestPrometheusClose(t *testing.T) {
+	p := NewPrometheus()
+
+	done := make(chan error)
+	go func() { done <- p.ListenAndServe("127.0.0.1:19979") }()
+
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://127.0.0.1:19979/metrics")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 3*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, p.Close())
+	assert.NoError(t, <-done)
+
+	// the closed metrics server cannot be served again.
+	assert.NoError(t, p.ListenAndServe("127.0.0.1:19979"))
+}
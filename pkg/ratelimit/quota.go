@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/core/ylog"
+)
+
+// The quota periods, they are in UTC.
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// periodOf returns the period that the time belongs to, e.g. `2024-08-01` for daily and `2024-08` for monthly.
+func periodOf(period string, t time.Time) string {
+	if period == PeriodMonthly {
+		return t.UTC().Format("2006-01")
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// Usage is the quota usage of a rule key in a period, it is persisted in the store.
+type Usage struct {
+	Period string `json:"period"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// quotas tracks the quota usage, and saves it to the store periodically if the store is not empty.
+type quotas struct {
+	store  string
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	usages map[string]*Usage
+	dirty  bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+	exited    chan struct{}
+}
+
+func newQuotas(store string) (*quotas, error) {
+	q := &quotas{
+		store:  store,
+		now:    time.Now,
+		logger: ylog.Default(),
+		usages: make(map[string]*Usage),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	if store == "" {
+		close(q.exited)
+		return q, nil
+	}
+
+	data, err := os.ReadFile(store)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &q.usages); err != nil {
+			return nil, err
+		}
+	}
+
+	go q.run()
+
+	return q, nil
+}
+
+// reserve adds the bytes to all the usages if none of them exceeds its quota, otherwise it returns
+// the index of the exceeded usage and false. The usages are checked and added under one lock.
+func (q *quotas) reserve(usages []usage, bytes int64) (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, u := range usages {
+		if q.usedLocked(u.key, u.period)+bytes > u.rule.quota {
+			return i, false
+		}
+	}
+	for _, u := range usages {
+		q.add(u.key, u.period, bytes)
+	}
+	return 0, true
+}
+
+// used returns the bytes used by the key in the period.
+func (q *quotas) used(key, period string) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.usedLocked(key, period)
+}
+
+func (q *quotas) usedLocked(key, period string) int64 {
+	u, ok := q.usages[key]
+	if !ok || u.Period != period {
+		return 0
+	}
+	return u.Bytes
+}
+
+// add adds the bytes used by the key in the period, the usage of the previous period is reset.
+// It must be called with the lock held.
+func (q *quotas) add(key, period string, bytes int64) {
+	u, ok := q.usages[key]
+	if !ok || u.Period != period {
+		u = &Usage{Period: period}
+		q.usages[key] = u
+	}
+	u.Bytes += bytes
+	q.dirty = true
+}
+
+func (q *quotas) run() {
+	defer close(q.exited)
+
+	ticker := time.NewTicker(SaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := q.save(); err != nil {
+				q.logger.Error("ratelimit: failed to save quota usage", "store", q.store, "err", err)
+			}
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// save writes the usage to the store if it has been changed, the store is replaced atomically.
+func (q *quotas) save() error {
+	q.mu.Lock()
+	if !q.dirty {
+		q.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(q.usages)
+	q.dirty = false
+	q.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.store), filepath.Base(q.store)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), q.store)
+}
+
+func (q *quotas) close() error {
+	q.closeOnce.Do(func() { close(q.done) })
+	<-q.exited
+
+	if q.store == "" {
+		return nil
+	}
+	return q.save()
+}
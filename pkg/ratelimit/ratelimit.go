@@ -0,0 +1,314 @@
+// Package ratelimit provides the rate limits and byte quotas of the data frames written to the zipper,
+// it works as a core.FrameMiddleware, so one misbehaving writer can not starve the others.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/acl"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// The actions for the data frames over the limit.
+const (
+	// ActionDrop drops the data frame silently, only an audit log is written.
+	ActionDrop = "drop"
+	// ActionDelay waits until the rate limit allows the data frame, it slows down the writer.
+	ActionDelay = "delay"
+	// ActionReject drops the data frame and answers the writer with a GoawayFrame.
+	ActionReject = "reject"
+)
+
+var (
+	// ErrRateLimited is reported to the Observer when the data frame is over the rate limit.
+	ErrRateLimited = errors.New("yomo: frame rate limited")
+	// ErrQuotaExceeded is reported to the Observer when the data frame is over the byte quota.
+	ErrQuotaExceeded = errors.New("yomo: frame quota exceeded")
+)
+
+var (
+	// DefaultMaxDelay is the longest time that ActionDelay waits if it is not configured.
+	DefaultMaxDelay = time.Second
+	// GoawayGracePeriod is the period that the rejected writer has to close the connection by itself,
+	// the zipper closes the connection after the period.
+	GoawayGracePeriod = time.Second
+	// SaveInterval is the interval of saving the quota usage to the store.
+	SaveInterval = 10 * time.Second
+	// EvictInterval is the interval of evicting the rate limiters of the idle keys, a key is idle once its
+	// limiter is full of tokens again, so evicting it does not change the rate limit.
+	EvictInterval = time.Minute
+)
+
+// Limiter limits the data frames by the rules.
+type Limiter struct {
+	rules  []*rule
+	quotas *quotas
+}
+
+type rule struct {
+	id       string
+	key      func(c *core.Context) (string, bool)
+	match    map[string]string
+	tags     [][2]frame.Tag
+	rate     rate.Limit
+	burst    int
+	quota    int64
+	period   string
+	action   string
+	maxDelay time.Duration
+
+	mu        sync.Mutex
+	limiters  map[string]*rate.Limiter
+	lastEvict time.Time
+}
+
+// New returns a Limiter from the config, the quota usage is loaded from the store.
+func New(conf config.RateLimit) (*Limiter, error) {
+	l := &Limiter{}
+
+	for i, rc := range conf.Rules {
+		r, err := newRule(i, rc)
+		if err != nil {
+			return nil, err
+		}
+		l.rules = append(l.rules, r)
+	}
+
+	quotas, err := newQuotas(conf.Store)
+	if err != nil {
+		return nil, err
+	}
+	l.quotas = quotas
+
+	return l, nil
+}
+
+func newRule(i int, rc config.RateLimitRule) (*rule, error) {
+	r := &rule{
+		id:       rc.Name,
+		match:    rc.Match,
+		rate:     rate.Limit(rc.Rate),
+		burst:    rc.Burst,
+		quota:    rc.QuotaBytes,
+		period:   rc.QuotaPeriod,
+		action:   rc.Action,
+		maxDelay: rc.MaxDelay,
+		limiters: make(map[string]*rate.Limiter),
+	}
+	r.lastEvict = time.Now()
+	if r.id == "" {
+		r.id = "rule-" + strconv.Itoa(i)
+	}
+
+	switch key := rc.Key; {
+	case key == "client":
+		r.key = func(c *core.Context) (string, bool) { return c.Connection.Name(), true }
+	case key == "tag":
+		r.key = func(c *core.Context) (string, bool) { return strconv.FormatUint(uint64(c.Frame.Tag), 10), true }
+	case strings.HasPrefix(key, "metadata:") && len(key) > len("metadata:"):
+		k := strings.TrimPrefix(key, "metadata:")
+		r.key = func(c *core.Context) (string, bool) { return c.Connection.Metadata().Get(k) }
+	default:
+		return nil, fmt.Errorf("ratelimit: %s: invalid key: %s, it should be client, tag or metadata:<key>", r.id, key)
+	}
+
+	for _, spec := range rc.Tags {
+		min, max, err := acl.ParseTagRange(spec)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: %s: %w", r.id, err)
+		}
+		r.tags = append(r.tags, [2]frame.Tag{min, max})
+	}
+
+	if rc.Rate < 0 || rc.Burst < 0 || rc.QuotaBytes < 0 {
+		return nil, fmt.Errorf("ratelimit: %s: the rate, burst and quota should not be negative", r.id)
+	}
+	if rc.Rate == 0 && rc.QuotaBytes == 0 {
+		return nil, fmt.Errorf("ratelimit: %s: either rate or quota_bytes is required", r.id)
+	}
+	if r.burst == 0 {
+		r.burst = int(math.Ceil(rc.Rate))
+	}
+
+	switch r.period {
+	case "":
+		r.period = PeriodDaily
+	case PeriodDaily, PeriodMonthly:
+	default:
+		return nil, fmt.Errorf("ratelimit: %s: invalid quota period: %s, it should be daily or monthly", r.id, r.period)
+	}
+
+	switch r.action {
+	case "":
+		r.action = ActionDrop
+	case ActionDrop, ActionDelay, ActionReject:
+	default:
+		return nil, fmt.Errorf("ratelimit: %s: invalid action: %s, it should be drop, delay or reject", r.id, r.action)
+	}
+	if r.maxDelay <= 0 {
+		r.maxDelay = DefaultMaxDelay
+	}
+
+	return r, nil
+}
+
+// Middleware returns the frame middleware that limits the data frames.
+func (l *Limiter) Middleware() core.FrameMiddleware {
+	return func(next core.FrameHandler) core.FrameHandler {
+		return func(c *core.Context) {
+			if l.allow(c) {
+				next(c)
+			}
+		}
+	}
+}
+
+// Close saves the quota usage to the store and stops saving it periodically.
+func (l *Limiter) Close() error {
+	return l.quotas.close()
+}
+
+// usage is the quota usage of a rule key that the frame will consume.
+type usage struct {
+	rule    *rule
+	ruleKey string
+	key     string
+	period  string
+}
+
+// allow reports whether the data frame can be passed on, it takes the action of the first rule that limits the frame.
+func (l *Limiter) allow(c *core.Context) bool {
+	var (
+		now    = l.quotas.now()
+		size   = int64(len(c.Frame.Payload))
+		usages []usage
+	)
+	for _, r := range l.rules {
+		key, ok := r.matches(c)
+		if !ok {
+			continue
+		}
+
+		if r.rate > 0 {
+			if err := r.wait(key); err != nil {
+				l.limit(c, r, key, ErrRateLimited)
+				return false
+			}
+		}
+
+		if r.quota > 0 {
+			u := usage{rule: r, ruleKey: key, key: r.id + "/" + key, period: periodOf(r.period, now)}
+			if l.quotas.used(u.key, u.period)+size > r.quota {
+				l.limit(c, r, key, ErrQuotaExceeded)
+				return false
+			}
+			usages = append(usages, u)
+		}
+	}
+
+	// the quotas are checked again when they are reserved, because the other frames may use them in the meantime.
+	if i, ok := l.quotas.reserve(usages, size); !ok {
+		l.limit(c, usages[i].rule, usages[i].ruleKey, ErrQuotaExceeded)
+		return false
+	}
+	return true
+}
+
+// limit takes the action of the rule for the data frame over the limit.
+func (l *Limiter) limit(c *core.Context, r *rule, key string, err error) {
+	c.SetError(err)
+	c.Logger.Warn(
+		"ratelimit: frame limited", "audit", "ratelimit", "rule", r.id, "key", key, "tag", c.Frame.Tag,
+		"action", r.action, "reason", err.Error(), "client_type", c.Connection.ClientType().String(),
+	)
+
+	if r.action != ActionReject {
+		return
+	}
+	message := fmt.Sprintf("ratelimit: %s for tag %d", err.Error(), c.Frame.Tag)
+	fconn := c.Connection.FrameConn()
+	if err := fconn.WriteFrame(&frame.GoawayFrame{Message: message}); err != nil {
+		c.Logger.Error("failed to write goaway frame", "err", err)
+	}
+	time.AfterFunc(GoawayGracePeriod, func() { _ = fconn.CloseWithError(message) })
+}
+
+// matches returns the key of the data frame if the rule applies to it.
+func (r *rule) matches(c *core.Context) (string, bool) {
+	if len(r.tags) > 0 {
+		matched := false
+		for _, t := range r.tags {
+			if c.Frame.Tag >= t[0] && c.Frame.Tag <= t[1] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", false
+		}
+	}
+	md := c.Connection.Metadata()
+	for k, want := range r.match {
+		v, ok := md.Get(k)
+		if !ok || (want != "*" && v != want) {
+			return "", false
+		}
+	}
+	return r.key(c)
+}
+
+// wait takes a token of the key, it waits for the token if the action is delay.
+func (r *rule) wait(key string) error {
+	limiter := r.limiter(key)
+
+	if r.action != ActionDelay {
+		if !limiter.Allow() {
+			return ErrRateLimited
+		}
+		return nil
+	}
+
+	reservation := limiter.Reserve()
+	delay := reservation.Delay()
+	if delay > r.maxDelay {
+		reservation.Cancel()
+		return ErrRateLimited
+	}
+	time.Sleep(delay)
+	return nil
+}
+
+func (r *rule) limiter(key string) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); now.Sub(r.lastEvict) >= EvictInterval {
+		r.evict(now)
+	}
+
+	limiter, ok := r.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(r.rate, r.burst)
+		r.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// evict removes the limiters of the idle keys, it must be called with the lock held.
+func (r *rule) evict(now time.Time) {
+	for key, limiter := range r.limiters {
+		if limiter.TokensAt(now) >= float64(r.burst) {
+			delete(r.limiters, key)
+		}
+	}
+	r.lastEvict = now
+}
@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/ylog"
+	"github.com/yomorun/yomo/pkg/config"
+)
+
+var discardingLogger = ylog.NewFromConfig(ylog.Config{Output: "/dev/null", ErrorOutput: "/dev/null"})
+
+func TestLimiter(t *testing.T) {
+	const zipperAddr = "127.0.0.1:19987"
+
+	store := filepath.Join(t.TempDir(), "quota.json")
+	limiter, err := New(config.RateLimit{
+		Store: store,
+		Rules: []config.RateLimitRule{
+			{Name: "drop", Key: "client", Tags: []string{"0x10"}, Rate: 0.001, Burst: 2},
+			{Name: "delay", Key: "tag", Tags: []string{"0x11"}, Rate: 20, Burst: 1, Action: ActionDelay},
+			{Name: "quota", Key: "client", Tags: []string{"0x12"}, QuotaBytes: 10, QuotaPeriod: PeriodMonthly},
+			{Name: "reject", Key: "client", Tags: []string{"0x13"}, Rate: 0.001, Burst: 1, Action: ActionReject},
+		},
+	})
+	assert.NoError(t, err)
+
+	server := core.NewServer("zipper", core.WithServerLogger(discardingLogger), core.WithFrameMiddleware(limiter.Middleware()))
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	var (
+		mu       sync.Mutex
+		received = map[frame.Tag]int{}
+	)
+	sfn := core.NewClient("sfn", zipperAddr, core.ClientTypeStreamFunction, core.WithLogger(discardingLogger))
+	sfn.SetObserveDataTags(0x10, 0x11, 0x12, 0x13)
+	sfn.SetDataFrameObserver(func(df *frame.DataFrame) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[df.Tag]++
+	})
+	assert.NoError(t, sfn.Connect(context.TODO()))
+	defer sfn.Close()
+
+	source := core.NewClient("source", zipperAddr, core.ClientTypeSource, core.WithLogger(discardingLogger))
+	assert.NoError(t, source.Connect(context.TODO()))
+	defer source.Close()
+
+	write := func(tag frame.Tag, n int) {
+		for i := 0; i < n; i++ {
+			assert.NoError(t, source.WriteFrame(&frame.DataFrame{Tag: tag, Payload: []byte("hello")}))
+		}
+	}
+	count := func(tag frame.Tag) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return received[tag]
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		write(0x10, 5)
+		assert.Eventually(t, func() bool { return count(0x10) == 2 }, time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, 2, count(0x10))
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		start := time.Now()
+		write(0x11, 5)
+		assert.Eventually(t, func() bool { return count(0x11) == 5 }, time.Second, 10*time.Millisecond)
+		// the last 4 frames wait for 50ms each.
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		write(0x12, 3)
+		assert.Eventually(t, func() bool { return count(0x12) == 2 }, time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, 2, count(0x12))
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		write(0x13, 2)
+
+		exited := make(chan struct{})
+		go func() {
+			source.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the rejected source should exit")
+		}
+		assert.Equal(t, 1, count(0x13))
+	})
+
+	t.Run("persist quota", func(t *testing.T) {
+		assert.NoError(t, limiter.Close())
+
+		data, err := os.ReadFile(store)
+		assert.NoError(t, err)
+
+		var usages map[string]Usage
+		assert.NoError(t, json.Unmarshal(data, &usages))
+		assert.Equal(t, Usage{Period: periodOf(PeriodMonthly, time.Now()), Bytes: 10}, usages["quota/source"])
+
+		// the usage is loaded by the new limiter.
+		limiter, err := New(config.RateLimit{Store: store})
+		assert.NoError(t, err)
+		defer limiter.Close()
+		assert.Equal(t, int64(10), limiter.quotas.used("quota/source", periodOf(PeriodMonthly, time.Now())))
+		assert.Equal(t, int64(0), limiter.quotas.used("quota/source", "1970-01"))
+	})
+}
+
+func TestNewError(t *testing.T) {
+	for name, rule := range map[string]config.RateLimitRule{
+		"invalid key":    {Key: "ip", Rate: 1},
+		"empty metadata": {Key: "metadata:", Rate: 1},
+		"invalid tag":    {Key: "tag", Tags: []string{"x"}, Rate: 1},
+		"no limit":       {Key: "client"},
+		"negative rate":  {Key: "client", Rate: -1},
+		"invalid period": {Key: "client", QuotaBytes: 1, QuotaPeriod: "weekly"},
+		"invalid action": {Key: "client", Rate: 1, Action: "block"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(config.RateLimit{Rules: []config.RateLimitRule{rule}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPeriodOf(t *testing.T) {
+	now := time.Date(2024, 8, 31, 23, 30, 0, 0, time.FixedZone("UTC-1", -3600))
+
+	assert.Equal(t, "2024-09-01", periodOf(PeriodDaily, now))
+	assert.Equal(t, "2024-09", periodOf(PeriodMonthly, now))
+}
+
+func TestQuotaReserve(t *testing.T) {
+	q, err := newQuotas("")
+	assert.NoError(t, err)
+	defer q.close()
+
+	r := &rule{quota: 10}
+	usages := []usage{{rule: r, key: "quota/source", period: "2024-08"}}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := q.reserve(usages, 1); ok {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, reserved)
+	assert.Equal(t, int64(10), q.used("quota/source", "2024-08"))
+
+	// nothing is reserved if any of the quotas is exceeded.
+	other := usage{rule: &rule{quota: 10}, key: "other/source", period: "2024-08"}
+	i, ok := q.reserve([]usage{other, usages[0]}, 1)
+	assert.False(t, ok)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, int64(0), q.used("other/source", "2024-08"))
+}
+
+func TestRuleEvict(t *testing.T) {
+	r, err := newRule(0, config.RateLimitRule{Key: "client", Rate: 10, Burst: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.wait("source"))
+	assert.ErrorIs(t, r.wait("source"), ErrRateLimited)
+
+	// the limiter is kept until it is full of tokens again, so the key is still limited.
+	r.evict(time.Now())
+	assert.Len(t, r.limiters, 1)
+	assert.ErrorIs(t, r.wait("source"), ErrRateLimited)
+
+	r.evict(time.Now().Add(time.Second))
+	assert.Empty(t, r.limiters)
+}
@@ -0,0 +1,202 @@
+package recording
+
+import (
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/acl"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+// RecorderBufferSize is the number of frames buffered for the recorder, the frames are dropped if the buffer is full,
+// so a slow disk never slows down the zipper.
+var RecorderBufferSize = 4096
+
+// Recorder records the data frames routed by the zipper into a recording file,
+// it is a core.TapFunc, use `server.Tap(recorder.Tap)` to start recording.
+type Recorder struct {
+	tags    [][2]frame.Tag
+	file    *os.File
+	writer  *Writer
+	records chan Record
+	dropped atomic.Uint64
+	logger  *slog.Logger
+
+	closeOnce sync.Once
+	done      chan struct{}
+	exited    chan struct{}
+}
+
+// NewRecorder returns a Recorder that appends the frames of the tags to the file, the tags are tags or
+// tag ranges, e.g. `0x10` and `0x10-0x20`, all the frames are recorded if the tags are empty.
+func NewRecorder(path string, tags []string, logger *slog.Logger) (*Recorder, error) {
+	r := &Recorder{
+		records: make(chan Record, RecorderBufferSize),
+		logger:  logger,
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	for _, spec := range tags {
+		min, max, err := acl.ParseTagRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		r.tags = append(r.tags, [2]frame.Tag{min, max})
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// append to the existing recording file, after the last complete record.
+	if stat.Size() > 0 {
+		size, err := completeSize(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if size < stat.Size() {
+			logger.Warn("truncate the incomplete record of the recording file", "file", path, "size", stat.Size(), "truncated_size", size)
+			if err := file.Truncate(size); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		if _, err := file.Seek(size, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	writer, err := NewWriter(file, stat.Size() == 0)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.file = file
+	r.writer = writer
+
+	go r.run()
+
+	return r, nil
+}
+
+// completeSize returns the size of the magic and the complete records of the recording file,
+// the bytes after it are the record truncated by crashing.
+func completeSize(file io.Reader) (int64, error) {
+	r, err := NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+
+	size := int64(len(Magic))
+	for {
+		var header [12]byte
+		if _, err := io.ReadFull(r.r, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return size, nil
+			}
+			return 0, err
+		}
+		length := int64(binary.BigEndian.Uint32(header[8:]))
+		if _, err := io.CopyN(io.Discard, r.r, length); err != nil {
+			if err == io.EOF {
+				return size, nil
+			}
+			return 0, err
+		}
+		size += int64(len(header)) + length
+	}
+}
+
+// Tap records the frame if its tag matches, it never blocks.
+func (r *Recorder) Tap(_ core.ConnectionInfo, df *frame.DataFrame, md metadata.M) {
+	if !r.match(df.Tag) {
+		return
+	}
+	record := Record{
+		Time:     time.Now(),
+		Tag:      df.Tag,
+		Metadata: md.Clone(),
+		Payload:  append([]byte(nil), df.Payload...),
+	}
+	select {
+	case <-r.done:
+	case r.records <- record:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of frames dropped because the recorder was too slow.
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close writes the buffered frames and closes the file, the frames tapped after closing are ignored.
+func (r *Recorder) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	<-r.exited
+
+	return r.file.Close()
+}
+
+func (r *Recorder) match(tag frame.Tag) bool {
+	if len(r.tags) == 0 {
+		return true
+	}
+	for _, t := range r.tags {
+		if tag >= t[0] && tag <= t[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// run writes the records to the file, the file is flushed whenever there are no pending records.
+func (r *Recorder) run() {
+	defer close(r.exited)
+
+	for {
+		select {
+		case record := <-r.records:
+			r.write(record)
+			if len(r.records) == 0 {
+				r.flush()
+			}
+		case <-r.done:
+			for {
+				select {
+				case record := <-r.records:
+					r.write(record)
+				default:
+					r.flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *Recorder) write(record Record) {
+	if err := r.writer.Write(record); err != nil {
+		r.logger.Error("failed to record frame", "tag", record.Tag, "err", err)
+	}
+}
+
+func (r *Recorder) flush() {
+	if err := r.writer.Flush(); err != nil {
+		r.logger.Error("failed to flush recording", "err", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package recording records the data frames into a file and replays them.
+//
+// The recording file is a stream of records, so it can be read while it is being written,
+// and a record that is truncated by crashing can be detected:
+//
+//	file    = magic *record
+//	magic   = "YOMOREC1"                              ; 8 bytes
+//	record  = timestamp length packet
+//	timestamp = uint64                                ; big endian, unix nanoseconds when the frame was routed
+//	length  = uint32                                  ; big endian, the length of packet
+//	packet  = the y3 encoded DataFrame                ; the same as on the wire, carries tag, metadata and payload
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/pkg/frame-codec/y3codec"
+)
+
+// Magic is the magic of the recording file.
+const Magic = "YOMOREC1"
+
+// ErrInvalidMagic is returned if the file is not a recording file.
+var ErrInvalidMagic = errors.New("recording: invalid magic, it is not a recording file")
+
+// Record is a recorded data frame.
+type Record struct {
+	// Time is the time when the frame was routed.
+	Time time.Time
+	// Tag is the tag of the frame.
+	Tag frame.Tag
+	// Metadata is the metadata of the frame.
+	Metadata metadata.M
+	// Payload is the payload of the frame.
+	Payload []byte
+}
+
+// Writer writes the records to the underlying writer.
+type Writer struct {
+	w     *bufio.Writer
+	codec frame.Codec
+}
+
+// NewWriter returns a Writer, it writes the magic if writeMagic is true,
+// the magic should not be written when appending to an existing recording file.
+func NewWriter(w io.Writer, writeMagic bool) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if writeMagic {
+		if _, err := bw.WriteString(Magic); err != nil {
+			return nil, err
+		}
+	}
+	return &Writer{w: bw, codec: y3codec.Codec()}, nil
+}
+
+// Write writes the record, the record is buffered until Flush is called.
+func (w *Writer) Write(r Record) error {
+	md, err := r.Metadata.Encode()
+	if err != nil {
+		return err
+	}
+	packet, err := w.codec.Encode(&frame.DataFrame{Tag: r.Tag, Metadata: md, Payload: r.Payload})
+	if err != nil {
+		return err
+	}
+
+	var header [12]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(r.Time.UnixNano()))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(packet)))
+
+	if _, err := w.w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err = w.w.Write(packet)
+	return err
+}
+
+// Flush writes the buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads the records from the recording file.
+type Reader struct {
+	r     *bufio.Reader
+	codec frame.Codec
+}
+
+// NewReader returns a Reader, it returns ErrInvalidMagic if the file is not a recording file.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, ErrInvalidMagic
+	}
+	if !bytes.Equal(magic, []byte(Magic)) {
+		return nil, ErrInvalidMagic
+	}
+	return &Reader{r: br, codec: y3codec.Codec()}, nil
+}
+
+// Next returns the next record, it returns io.EOF if there are no more records,
+// and io.ErrUnexpectedEOF if the last record is truncated.
+func (r *Reader) Next() (Record, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		return Record{}, err
+	}
+	packet := make([]byte, binary.BigEndian.Uint32(header[8:]))
+	if _, err := io.ReadFull(r.r, packet); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, err
+	}
+
+	df := new(frame.DataFrame)
+	if err := r.codec.Decode(packet, df); err != nil {
+		return Record{}, fmt.Errorf("recording: decode frame: %w", err)
+	}
+	md, err := metadata.Decode(df.Metadata)
+	if err != nil {
+		return Record{}, fmt.Errorf("recording: decode metadata: %w", err)
+	}
+
+	return Record{
+		Time:     time.Unix(0, int64(binary.BigEndian.Uint64(header[:8]))),
+		Tag:      df.Tag,
+		Metadata: md,
+		Payload:  df.Payload,
+	}, nil
+}
+
+// Replay writes the records read from the reader to the writer, it keeps the intervals between
+// the records scaled by the speed, e.g. the speed 2 replays twice as fast as the original,
+// and the speed 0 replays as fast as possible. The filter decides which records are replayed,
+// nil means all the records. It returns the number of replayed records.
+func Replay(ctx context.Context, r *Reader, w frame.Writer, speed float64, filter func(Record) bool) (int, error) {
+	var (
+		n     int
+		first time.Time
+		start = time.Now()
+	)
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if filter != nil && !filter(record) {
+			continue
+		}
+
+		if first.IsZero() {
+			first = record.Time
+		}
+		if speed > 0 {
+			due := start.Add(time.Duration(float64(record.Time.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return n, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		md, err := record.Metadata.Encode()
+		if err != nil {
+			return n, err
+		}
+		if err := w.WriteFrame(&frame.DataFrame{Tag: record.Tag, Metadata: md, Payload: record.Payload}); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
@@ -0,0 +1,170 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/core/ylog"
+)
+
+func TestRecording(t *testing.T) {
+	now := time.Now()
+	records := []Record{
+		{Time: now, Tag: 0x10, Metadata: metadata.M{"yomo-tid": "tid-1"}, Payload: []byte("hello")},
+		{Time: now.Add(time.Second), Tag: 0x20, Metadata: metadata.M{}, Payload: []byte("yomo")},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, true)
+	assert.NoError(t, err)
+	for _, r := range records {
+		assert.NoError(t, w.Write(r))
+	}
+	assert.NoError(t, w.Flush())
+
+	t.Run("read", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+
+		for _, want := range records {
+			got, err := r.Next()
+			assert.NoError(t, err)
+			assert.True(t, want.Time.Equal(got.Time))
+			assert.Equal(t, want.Tag, got.Tag)
+			assert.Equal(t, want.Metadata, got.Metadata)
+			assert.Equal(t, want.Payload, got.Payload)
+		}
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		assert.NoError(t, err)
+
+		_, err = r.Next()
+		assert.NoError(t, err)
+		_, err = r.Next()
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+
+	t.Run("invalid magic", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte("not a recording file")))
+		assert.Equal(t, ErrInvalidMagic, err)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+
+		w := &frameWriter{}
+		start := time.Now()
+		n, err := Replay(context.TODO(), r, w, 10, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		// the interval is one second at the speed 10.
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		assert.Equal(t, frame.Tag(0x10), w.frames[0].Tag)
+		assert.Equal(t, []byte("hello"), w.frames[0].Payload)
+		md, err := metadata.Decode(w.frames[0].Metadata)
+		assert.NoError(t, err)
+		assert.Equal(t, metadata.M{"yomo-tid": "tid-1"}, md)
+	})
+
+	t.Run("replay filter", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+
+		w := &frameWriter{}
+		n, err := Replay(context.TODO(), r, w, 0, func(r Record) bool { return r.Tag == 0x20 })
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, frame.Tag(0x20), w.frames[0].Tag)
+	})
+
+	t.Run("replay canceled", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		n, err := Replay(ctx, r, &frameWriter{}, 1, nil)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, 1, n)
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.yomo")
+	logger := ylog.NewFromConfig(ylog.Config{Output: "/dev/null", ErrorOutput: "/dev/null"})
+
+	record := func(tags []string, dfs ...*frame.DataFrame) {
+		recorder, err := NewRecorder(path, tags, logger)
+		assert.NoError(t, err)
+		for _, df := range dfs {
+			recorder.Tap(nil, df, metadata.M{"source": "test"})
+		}
+		assert.NoError(t, recorder.Close())
+	}
+
+	record([]string{"0x10-0x1f"},
+		&frame.DataFrame{Tag: 0x10, Payload: []byte("a")},
+		&frame.DataFrame{Tag: 0x20, Payload: []byte("b")},
+	)
+	// the frames are appended to the existing file.
+	record(nil, &frame.DataFrame{Tag: 0x20, Payload: []byte("c")})
+
+	// the record truncated by crashing is dropped before appending.
+	truncated, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, err = truncated.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 100, 0xbf})
+	assert.NoError(t, err)
+	assert.NoError(t, truncated.Close())
+	record(nil, &frame.DataFrame{Tag: 0x20, Payload: []byte("d")})
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	r, err := NewReader(file)
+	assert.NoError(t, err)
+
+	var payloads []string
+	for {
+		got, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, metadata.M{"source": "test"}, got.Metadata)
+		payloads = append(payloads, string(got.Payload))
+	}
+	assert.Equal(t, []string{"a", "c", "d"}, payloads)
+
+	t.Run("invalid file", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "invalid")
+		assert.NoError(t, os.WriteFile(invalid, []byte("invalid"), 0o644))
+
+		_, err := NewRecorder(invalid, nil, logger)
+		assert.Equal(t, ErrInvalidMagic, err)
+	})
+}
+
+type frameWriter struct {
+	frames []*frame.DataFrame
+}
+
+func (w *frameWriter) WriteFrame(f frame.Frame) error {
+	w.frames = append(w.frames, f.(*frame.DataFrame))
+	return nil
+}
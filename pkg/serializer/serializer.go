@@ -0,0 +1,135 @@
+// Package serializer provides the serializers of the typed data written by sources and stream functions,
+// the name of the serializer is carried in the metadata of the data, so the consumers decode the data by it.
+package serializer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Serializer marshals and unmarshals the typed data.
+type Serializer interface {
+	// Name returns the name of the serializer, it is carried in the metadata of the data.
+	Name() string
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes the data to v, v must be a pointer.
+	Unmarshal(data []byte, v any) error
+}
+
+// The builtin serializers.
+var (
+	// JSON is the serializer of encoding/json, it is the default serializer.
+	JSON Serializer = jsonSerializer{}
+	// Msgpack is the serializer of msgpack.
+	Msgpack Serializer = msgpackSerializer{}
+	// Protobuf is the serializer of protobuf, the values must be proto.Message.
+	Protobuf Serializer = protobufSerializer{}
+)
+
+var (
+	mu          sync.RWMutex
+	serializers = map[string]Serializer{
+		JSON.Name():     JSON,
+		Msgpack.Name():  Msgpack,
+		Protobuf.Name(): Protobuf,
+	}
+)
+
+// Register registers the serializer, so the data carrying its name can be decoded automatically.
+// It replaces the serializer registered with the same name.
+func Register(s Serializer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	serializers[s.Name()] = s
+}
+
+// Get returns the serializer registered with the name.
+func Get(name string) (Serializer, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	s, ok := serializers[name]
+	return s, ok
+}
+
+// ErrMismatch is returned when the data is encoded by a serializer other than the expected one.
+var ErrMismatch = errors.New("serializer mismatch")
+
+// ErrUnknown is returned when the data is encoded by a serializer that is not registered.
+var ErrUnknown = errors.New("unknown serializer")
+
+// Resolve returns the serializer to decode the data encoded by the serializer named name,
+// the empty name means the data carries no serializer name, it is decoded by s or JSON if s is nil.
+// When s is not nil, the name must be the name of s.
+func Resolve(name string, s Serializer) (Serializer, error) {
+	if name == "" {
+		if s == nil {
+			return JSON, nil
+		}
+		return s, nil
+	}
+	if s != nil {
+		if s.Name() != name {
+			return nil, fmt.Errorf("%w: the data is encoded by %s, expected %s", ErrMismatch, name, s.Name())
+		}
+		return s, nil
+	}
+	s, ok := Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+	return s, nil
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Name() string                       { return "json" }
+func (jsonSerializer) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonSerializer) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) Name() string                       { return "msgpack" }
+func (msgpackSerializer) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackSerializer) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+type protobufSerializer struct{}
+
+func (protobufSerializer) Name() string { return "protobuf" }
+
+func (protobufSerializer) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf serializer: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal decodes the data to v, v is a proto.Message or a pointer to a nil proto.Message,
+// e.g. the *In of a TypedHandler whose In is *pb.Message.
+func (protobufSerializer) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Pointer {
+		return fmt.Errorf("protobuf serializer: %T is not a proto.Message", v)
+	}
+	m, ok := reflect.New(rv.Elem().Type().Elem()).Interface().(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf serializer: %T is not a proto.Message", v)
+	}
+	if err := proto.Unmarshal(data, m); err != nil {
+		return err
+	}
+	rv.Elem().Set(reflect.ValueOf(m))
+	return nil
+}
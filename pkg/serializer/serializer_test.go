@@ -0,0 +1,65 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type noise struct {
+	Noise float32 `json:"noise" msgpack:"noise"`
+	From  string  `json:"from" msgpack:"from"`
+}
+
+func TestSerializers(t *testing.T) {
+	for _, s := range []Serializer{JSON, Msgpack} {
+		t.Run(s.Name(), func(t *testing.T) {
+			data, err := s.Marshal(noise{Noise: 1.5, From: "sensor"})
+			assert.NoError(t, err)
+
+			var v noise
+			assert.NoError(t, s.Unmarshal(data, &v))
+			assert.Equal(t, noise{Noise: 1.5, From: "sensor"}, v)
+		})
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	data, err := Protobuf.Marshal(wrapperspb.String("hello"))
+	assert.NoError(t, err)
+
+	v := &wrapperspb.StringValue{}
+	assert.NoError(t, Protobuf.Unmarshal(data, v))
+	assert.Equal(t, "hello", v.GetValue())
+
+	// decode to a pointer to a nil message.
+	var p *wrapperspb.StringValue
+	assert.NoError(t, Protobuf.Unmarshal(data, &p))
+	assert.Equal(t, "hello", p.GetValue())
+
+	_, err = Protobuf.Marshal(noise{})
+	assert.Error(t, err)
+	assert.Error(t, Protobuf.Unmarshal(data, &noise{}))
+}
+
+func TestResolve(t *testing.T) {
+	s, err := Resolve("", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, JSON, s)
+
+	s, err = Resolve("", Msgpack)
+	assert.NoError(t, err)
+	assert.Equal(t, Msgpack, s)
+
+	s, err = Resolve("msgpack", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Msgpack, s)
+
+	_, err = Resolve("msgpack", JSON)
+	assert.ErrorIs(t, err, ErrMismatch)
+	assert.EqualError(t, err, "serializer mismatch: the data is encoded by msgpack, expected json")
+
+	_, err = Resolve("yaml", nil)
+	assert.ErrorIs(t, err, ErrUnknown)
+}
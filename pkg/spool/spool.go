@@ -0,0 +1,277 @@
+// Package spool provides the disk spool of sources, it keeps the data frames written while the source
+// is disconnected from the zipper, and the frames are written to the zipper in order after reconnecting.
+//
+// The spool is a bbolt file, so the spooled frames survive the crashes and restarts of the source.
+// The file can not be opened by two sources at the same time.
+package spool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/pkg/frame-codec/y3codec"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("spool")
+
+// frontKeys is the number of keys reserved before the pushed frames, they are used by PushFront.
+const frontKeys = 1 << 32
+
+// ErrFrameTooLarge is returned when a data frame is larger than the max bytes of the spool.
+var ErrFrameTooLarge = errors.New("spool: the data frame is larger than the spool")
+
+// Disk is the spool on disk, it is bounded by the max bytes and the max age of the frames,
+// the oldest frames are dropped when the spool is full, and the frames are dropped when they are too old.
+//
+// Disk is a prometheus.Collector of the spooled bytes, spooled frames and dropped frames.
+type Disk struct {
+	db       *bolt.DB
+	codec    frame.Codec
+	maxBytes int64
+	maxAge   time.Duration
+
+	mu      sync.Mutex
+	bytes   int64
+	frames  int64
+	dropped uint64
+
+	bytesDesc   *prometheus.Desc
+	framesDesc  *prometheus.Desc
+	droppedDesc *prometheus.Desc
+}
+
+var _ core.Spool = (*Disk)(nil)
+
+// NewDisk opens the spool in the file, the file is created if it does not exist, the frames spooled
+// before are kept. The maxBytes and maxAge bound the spool, zero means no limit.
+func NewDisk(path string, maxBytes int64, maxAge time.Duration) (*Disk, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Disk{
+		db:       db,
+		codec:    y3codec.Codec(),
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		bytesDesc: prometheus.NewDesc("yomo_source_spool_bytes",
+			"The bytes of data frames in the spool.", nil, nil),
+		framesDesc: prometheus.NewDesc("yomo_source_spool_frames",
+			"The number of data frames in the spool.", nil, nil),
+		droppedDesc: prometheus.NewDesc("yomo_source_spool_dropped_frames_total",
+			"The number of data frames dropped because the spool is full or they are too old.", nil, nil),
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(_, v []byte) error {
+			d.bytes += int64(len(v))
+			d.frames++
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Push implements core.Spool, the oldest frames are dropped if the spool is full after pushing.
+func (d *Disk) Push(f *frame.DataFrame) error {
+	value, err := d.encode(f)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return d.put(tx, key(frontKeys+seq), value)
+	})
+}
+
+// PushFront implements core.Spool.
+func (d *Disk) PushFront(f *frame.DataFrame) error {
+	value, err := d.encode(f)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		k, _ := b.Cursor().First()
+		if k == nil {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			return d.put(tx, key(frontKeys+seq), value)
+		}
+		first := binary.BigEndian.Uint64(k)
+		if first == 0 {
+			return errors.New("spool: no key before the head")
+		}
+		return d.put(tx, key(first-1), value)
+	})
+}
+
+// Front implements core.Spool, the frames older than the max age are dropped before returning.
+func (d *Disk) Front() (f *frame.DataFrame, ok bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err = d.update(func(tx *bolt.Tx) error {
+		if err := d.evict(tx, time.Now()); err != nil {
+			return err
+		}
+		_, v := tx.Bucket(bucket).Cursor().First()
+		if v == nil {
+			return nil
+		}
+		// the value is only valid in the transaction.
+		f = new(frame.DataFrame)
+		if err := d.codec.Decode(bytes.Clone(v[8:]), f); err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+	return
+}
+
+// Pop implements core.Spool.
+func (d *Disk) Pop() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		k, v := c.First()
+		if k == nil {
+			return nil
+		}
+		return d.delete(c, v)
+	})
+}
+
+// Stats returns the bytes and the number of the frames in the spool, and the number of the dropped frames.
+func (d *Disk) Stats() (bytes, frames int64, dropped uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.bytes, d.frames, d.dropped
+}
+
+// Describe implements prometheus.Collector.
+func (d *Disk) Describe(ch chan<- *prometheus.Desc) {
+	ch <- d.bytesDesc
+	ch <- d.framesDesc
+	ch <- d.droppedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (d *Disk) Collect(ch chan<- prometheus.Metric) {
+	n, frames, dropped := d.Stats()
+
+	ch <- prometheus.MustNewConstMetric(d.bytesDesc, prometheus.GaugeValue, float64(n))
+	ch <- prometheus.MustNewConstMetric(d.framesDesc, prometheus.GaugeValue, float64(frames))
+	ch <- prometheus.MustNewConstMetric(d.droppedDesc, prometheus.CounterValue, float64(dropped))
+}
+
+// Close closes the spool file, the spooled frames are kept.
+func (d *Disk) Close() error {
+	return d.db.Close()
+}
+
+// encode encodes the data frame into the value with the spooled time.
+func (d *Disk) encode(f *frame.DataFrame) ([]byte, error) {
+	packet, err := d.codec.Encode(f)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 8+len(packet))
+	binary.BigEndian.PutUint64(value, uint64(time.Now().UnixNano()))
+	copy(value[8:], packet)
+
+	if d.maxBytes > 0 && int64(len(value)) > d.maxBytes {
+		return nil, ErrFrameTooLarge
+	}
+	return value, nil
+}
+
+// put puts the frame value with the key, and evicts the oldest frames if the spool is full.
+func (d *Disk) put(tx *bolt.Tx, k, value []byte) error {
+	if err := tx.Bucket(bucket).Put(k, value); err != nil {
+		return err
+	}
+	d.bytes += int64(len(value))
+	d.frames++
+
+	return d.evict(tx, time.Now())
+}
+
+// evict drops the oldest frames while the spool is full or they are older than the max age.
+func (d *Disk) evict(tx *bolt.Tx, now time.Time) error {
+	c := tx.Bucket(bucket).Cursor()
+	for k, v := c.First(); k != nil; k, v = c.First() {
+		full := d.maxBytes > 0 && d.bytes > d.maxBytes
+		expired := d.maxAge > 0 && now.Sub(time.Unix(0, int64(binary.BigEndian.Uint64(v)))) > d.maxAge
+		if !full && !expired {
+			return nil
+		}
+		if err := d.delete(c, v); err != nil {
+			return err
+		}
+		d.dropped++
+	}
+	return nil
+}
+
+// delete deletes the frame value at the cursor.
+func (d *Disk) delete(c *bolt.Cursor, v []byte) error {
+	n := int64(len(v))
+	if err := c.Delete(); err != nil {
+		return err
+	}
+	d.bytes -= n
+	d.frames--
+	return nil
+}
+
+// update runs the write transaction, the counters are restored if the transaction fails.
+func (d *Disk) update(fn func(tx *bolt.Tx) error) error {
+	n, frames, dropped := d.bytes, d.frames, d.dropped
+	err := d.db.Update(fn)
+	if err != nil {
+		d.bytes, d.frames, d.dropped = n, frames, dropped
+	}
+	return err
+}
+
+func key(seq uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, seq)
+	return k
+}
@@ -0,0 +1,121 @@
+package spool
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/frame"
+)
+
+func TestDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.db")
+
+	d, err := NewDisk(path, 0, 0)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, d.Push(&frame.DataFrame{Tag: frame.Tag(i), Payload: []byte("data")}))
+	}
+	_, frames, _ := d.Stats()
+	assert.Equal(t, int64(3), frames)
+	assert.NoError(t, d.Close())
+
+	// the frames survive the reopening.
+	d, err = NewDisk(path, 0, 0)
+	assert.NoError(t, err)
+	defer d.Close()
+
+	for i := 0; i < 3; i++ {
+		f, ok, err := d.Front()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, frame.Tag(i), f.Tag)
+		assert.Equal(t, []byte("data"), f.Payload)
+		assert.NoError(t, d.Pop())
+	}
+
+	_, ok, err := d.Front()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	n, frames, dropped := d.Stats()
+	assert.Equal(t, int64(0), n)
+	assert.Equal(t, int64(0), frames)
+	assert.Equal(t, uint64(0), dropped)
+}
+
+func TestDiskPushFront(t *testing.T) {
+	d, err := NewDisk(filepath.Join(t.TempDir(), "spool.db"), 0, 0)
+	assert.NoError(t, err)
+	defer d.Close()
+
+	assert.NoError(t, d.PushFront(&frame.DataFrame{Tag: 2}))
+	assert.NoError(t, d.Push(&frame.DataFrame{Tag: 3}))
+	assert.NoError(t, d.PushFront(&frame.DataFrame{Tag: 1}))
+	assert.NoError(t, d.PushFront(&frame.DataFrame{Tag: 0}))
+
+	for i := 0; i < 4; i++ {
+		f, ok, err := d.Front()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, frame.Tag(i), f.Tag)
+		assert.NoError(t, d.Pop())
+	}
+	_, frames, _ := d.Stats()
+	assert.Equal(t, int64(0), frames)
+}
+
+func TestDiskLimits(t *testing.T) {
+	t.Run("max bytes", func(t *testing.T) {
+		d, err := NewDisk(filepath.Join(t.TempDir(), "spool.db"), 100, 0)
+		assert.NoError(t, err)
+		defer d.Close()
+
+		assert.ErrorIs(t, d.Push(&frame.DataFrame{Tag: 1, Payload: make([]byte, 200)}), ErrFrameTooLarge)
+
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, d.Push(&frame.DataFrame{Tag: frame.Tag(i), Payload: make([]byte, 30)}))
+		}
+		n, frames, dropped := d.Stats()
+		assert.LessOrEqual(t, n, int64(100))
+		assert.Equal(t, uint64(5), uint64(frames)+dropped)
+
+		// the oldest frames are dropped.
+		f, ok, err := d.Front()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, frame.Tag(5-frames), f.Tag)
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		d, err := NewDisk(filepath.Join(t.TempDir(), "spool.db"), 0, 50*time.Millisecond)
+		assert.NoError(t, err)
+		defer d.Close()
+
+		assert.NoError(t, d.Push(&frame.DataFrame{Tag: 1, Payload: []byte("old")}))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, d.Push(&frame.DataFrame{Tag: 2, Payload: []byte("new")}))
+
+		f, ok, err := d.Front()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, frame.Tag(2), f.Tag)
+
+		_, _, dropped := d.Stats()
+		assert.Equal(t, uint64(1), dropped)
+	})
+}
+
+func TestDiskCollector(t *testing.T) {
+	d, err := NewDisk(filepath.Join(t.TempDir(), "spool.db"), 0, 0)
+	assert.NoError(t, err)
+	defer d.Close()
+
+	assert.NoError(t, d.Push(&frame.DataFrame{Tag: 1, Payload: []byte("data")}))
+
+	assert.Equal(t, 3, testutil.CollectAndCount(d))
+	assert.Equal(t, 1, testutil.CollectAndCount(d, "yomo_source_spool_frames"))
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("state")
+
+// Disk is the state store embedded on disk, the state survives the restarts of the sfn.
+// The file can not be opened by two sfn processes at the same time.
+type Disk struct {
+	db        *bolt.DB
+	lastSweep time.Time // it is guarded by the write transaction
+}
+
+var _ Store = (*Disk)(nil)
+
+// NewDisk opens the state store in the file, the file is created if it does not exist.
+func NewDisk(path string) (*Disk, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Disk{db: db, lastSweep: time.Now()}, nil
+}
+
+// Get implements serverless.State.
+func (d *Disk) Get(key string) (value []byte, ok bool, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		e, exists, err := get(tx, key, time.Now())
+		if err != nil {
+			return err
+		}
+		value, ok = e.value, exists
+		return nil
+	})
+	return
+}
+
+// Put implements serverless.State.
+func (d *Disk) Put(key string, value []byte, ttl time.Duration) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		if err := put(tx, key, newEntry(value, ttl, now)); err != nil {
+			return err
+		}
+		return d.sweep(tx, now)
+	})
+}
+
+// Delete implements serverless.State.
+func (d *Disk) Delete(key string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
+
+// CompareAndSwap implements serverless.State.
+func (d *Disk) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	err = d.db.Update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		e, ok, err := get(tx, key, now)
+		if err != nil {
+			return err
+		}
+		if !swappable(e.value, ok, old) {
+			return nil
+		}
+		swapped = true
+		if err := put(tx, key, newEntry(new, ttl, now)); err != nil {
+			return err
+		}
+		return d.sweep(tx, now)
+	})
+	return
+}
+
+// Close implements Store.
+func (d *Disk) Close() error {
+	return d.db.Close()
+}
+
+// sweep deletes the expired keys in the transaction if the last sweep is SweepInterval ago.
+func (d *Disk) sweep(tx *bolt.Tx, now time.Time) error {
+	if now.Sub(d.lastSweep) < SweepInterval {
+		return nil
+	}
+	d.lastSweep = now
+
+	var (
+		b       = tx.Bucket(bucket)
+		expired [][]byte
+	)
+	err := b.ForEach(func(k, v []byte) error {
+		if e, err := decodeEntry(v); err != nil || e.expired(now) {
+			expired = append(expired, bytes.Clone(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func get(tx *bolt.Tx, key string, now time.Time) (entry, bool, error) {
+	v := tx.Bucket(bucket).Get([]byte(key))
+	if v == nil {
+		return entry{}, false, nil
+	}
+	e, err := decodeEntry(v)
+	if err != nil {
+		return entry{}, false, err
+	}
+	if e.expired(now) {
+		return entry{}, false, nil
+	}
+	return e, true, nil
+}
+
+func put(tx *bolt.Tx, key string, e entry) error {
+	return tx.Bucket(bucket).Put([]byte(key), encodeEntry(e))
+}
+
+// encodeEntry encodes the entry as the 8 bytes big-endian expiration followed by the value.
+func encodeEntry(e entry) []byte {
+	buf := make([]byte, 8+len(e.value))
+	binary.BigEndian.PutUint64(buf, uint64(e.expire))
+	copy(buf[8:], e.value)
+	return buf
+}
+
+func decodeEntry(buf []byte) (entry, error) {
+	if len(buf) < 8 {
+		return entry{}, errors.New("state: invalid entry")
+	}
+	// the buf is only valid in the transaction, so the value is copied.
+	value := make([]byte, len(buf)-8)
+	copy(value, buf[8:])
+	return entry{value: value, expire: int64(binary.BigEndian.Uint64(buf))}, nil
+}
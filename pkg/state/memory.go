@@ -0,0 +1,98 @@
+package state
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Memory is the state store in memory, the state is lost when the sfn exits.
+type Memory struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns the state store in memory.
+func NewMemory() *Memory {
+	return &Memory{
+		entries:   make(map[string]entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get implements serverless.State.
+func (m *Memory) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.get(key, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+	return bytes.Clone(e.value), true, nil
+}
+
+// Put implements serverless.State.
+func (m *Memory) Put(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.entries[key] = newEntry(value, ttl, now)
+	m.sweep(now)
+	return nil
+}
+
+// Delete implements serverless.State.
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// CompareAndSwap implements serverless.State.
+func (m *Memory) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e, ok := m.get(key, now)
+	if !swappable(e.value, ok, old) {
+		return false, nil
+	}
+	m.entries[key] = newEntry(new, ttl, now)
+	m.sweep(now)
+	return true, nil
+}
+
+// Close implements Store.
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) get(key string, now time.Time) (entry, bool) {
+	e, ok := m.entries[key]
+	if !ok || e.expired(now) {
+		return entry{}, false
+	}
+	return e, true
+}
+
+// sweep deletes the expired keys if the last sweep is SweepInterval ago.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < SweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for k, e := range m.entries {
+		if e.expired(now) {
+			delete(m.entries, k)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Package state provides the key-value state stores of stream functions,
+// the stores implement serverless.State, the values can have a ttl.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yomorun/yomo/serverless"
+)
+
+// SweepInterval is the interval of deleting the expired keys from the stores, the expired keys are deleted
+// when a key is written, so the stores do not run background goroutines.
+var SweepInterval = time.Minute
+
+// Store is the state store of stream functions.
+type Store interface {
+	serverless.State
+	// Close releases the store.
+	Close() error
+}
+
+// WithPrefix returns the state whose keys are prefixed with the prefix in the underlying state,
+// e.g. the handler of the sfn with serial keys can keep the state of a key by `state.WithPrefix(ctx.State(), key+"/")`.
+func WithPrefix(s serverless.State, prefix string) serverless.State {
+	return &prefixState{s, prefix}
+}
+
+type prefixState struct {
+	state  serverless.State
+	prefix string
+}
+
+func (p *prefixState) Get(key string) ([]byte, bool, error) {
+	return p.state.Get(p.prefix + key)
+}
+
+func (p *prefixState) Put(key string, value []byte, ttl time.Duration) error {
+	return p.state.Put(p.prefix+key, value, ttl)
+}
+
+func (p *prefixState) Delete(key string) error {
+	return p.state.Delete(p.prefix + key)
+}
+
+func (p *prefixState) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error) {
+	return p.state.CompareAndSwap(p.prefix+key, old, new, ttl)
+}
+
+// Do handles the json encoded serverless.StateRequest of the wasm and deno guests,
+// and returns the json encoded serverless.StateResponse.
+func Do(s serverless.State, reqBuf []byte) ([]byte, error) {
+	var req serverless.StateRequest
+	if err := json.Unmarshal(reqBuf, &req); err != nil {
+		return nil, fmt.Errorf("unmarshal state request error: %s", err)
+	}
+
+	var (
+		resp serverless.StateResponse
+		ttl  = time.Duration(req.TTL) * time.Millisecond
+		err  error
+	)
+	switch req.Op {
+	case serverless.StateOpGet:
+		resp.Value, resp.OK, err = s.Get(req.Key)
+	case serverless.StateOpPut:
+		err = s.Put(req.Key, req.Value, ttl)
+	case serverless.StateOpDelete:
+		err = s.Delete(req.Key)
+	case serverless.StateOpCompareAndSwap:
+		resp.OK, err = s.CompareAndSwap(req.Key, req.Old, req.Value, ttl)
+	default:
+		err = fmt.Errorf("unknown state operation: %s", req.Op)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	return json.Marshal(resp)
+}
+
+// entry is the value and the expiration of a key, the zero expiration means never expires.
+type entry struct {
+	value  []byte
+	expire int64
+}
+
+func newEntry(value []byte, ttl time.Duration, now time.Time) entry {
+	e := entry{value: bytes.Clone(value)}
+	if ttl > 0 {
+		e.expire = now.Add(ttl).UnixNano()
+	}
+	return e
+}
+
+func (e entry) expired(now time.Time) bool {
+	return e.expire > 0 && e.expire <= now.UnixNano()
+}
+
+// swappable reports whether the current value is the old value of CompareAndSwap.
+func swappable(current []byte, exists bool, old []byte) bool {
+	if old == nil {
+		return !exists
+	}
+	return exists && bytes.Equal(current, old)
+}
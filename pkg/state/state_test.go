@@ -0,0 +1,138 @@
+package state
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/serverless"
+)
+
+func TestStores(t *testing.T) {
+	disk, err := NewDisk(filepath.Join(t.TempDir(), "state.db"))
+	assert.NoError(t, err)
+	defer disk.Close()
+
+	for name, store := range map[string]Store{"memory": NewMemory(), "disk": disk} {
+		t.Run(name, func(t *testing.T) {
+			testState(t, store)
+		})
+	}
+}
+
+func testState(t *testing.T, s serverless.State) {
+	_, ok, err := s.Get("counter")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// put and get
+	assert.NoError(t, s.Put("counter", []byte("1"), 0))
+	v, ok, err := s.Get("counter")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+
+	// compare and swap
+	swapped, err := s.CompareAndSwap("counter", []byte("0"), []byte("2"), 0)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = s.CompareAndSwap("counter", []byte("1"), []byte("2"), 0)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = s.CompareAndSwap("counter", nil, []byte("3"), 0)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = s.CompareAndSwap("new", nil, []byte("1"), 0)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+
+	v, _, _ = s.Get("counter")
+	assert.Equal(t, []byte("2"), v)
+
+	// delete
+	assert.NoError(t, s.Delete("counter"))
+	_, ok, _ = s.Get("counter")
+	assert.False(t, ok)
+
+	// ttl
+	assert.NoError(t, s.Put("session", []byte("token"), 50*time.Millisecond))
+	_, ok, _ = s.Get("session")
+	assert.True(t, ok)
+	time.Sleep(60 * time.Millisecond)
+	_, ok, _ = s.Get("session")
+	assert.False(t, ok)
+	swapped, err = s.CompareAndSwap("session", nil, []byte("token"), 0)
+	assert.NoError(t, err)
+	assert.True(t, swapped, "the expired key does not exist")
+
+	// prefix
+	p := WithPrefix(s, "device-1/")
+	assert.NoError(t, p.Put("temperature", []byte("20"), 0))
+	v, ok, _ = s.Get("device-1/temperature")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("20"), v)
+}
+
+func TestDiskReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	disk, err := NewDisk(path)
+	assert.NoError(t, err)
+	assert.NoError(t, disk.Put("counter", []byte("42"), 0))
+	assert.NoError(t, disk.Put("expired", []byte("1"), time.Millisecond))
+	assert.NoError(t, disk.Close())
+
+	time.Sleep(5 * time.Millisecond)
+
+	disk, err = NewDisk(path)
+	assert.NoError(t, err)
+	defer disk.Close()
+
+	v, ok, err := disk.Get("counter")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("42"), v)
+
+	_, ok, _ = disk.Get("expired")
+	assert.False(t, ok)
+}
+
+func TestSweep(t *testing.T) {
+	interval := SweepInterval
+	SweepInterval = 0
+	defer func() { SweepInterval = interval }()
+
+	m := NewMemory()
+	assert.NoError(t, m.Put("expired", []byte("1"), time.Millisecond))
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, m.Put("key", []byte("1"), 0))
+	assert.Len(t, m.entries, 1)
+}
+
+func TestDo(t *testing.T) {
+	m := NewMemory()
+
+	do := func(req serverless.StateRequest) serverless.StateResponse {
+		reqBuf, _ := json.Marshal(req)
+		respBuf, err := Do(m, reqBuf)
+		assert.NoError(t, err)
+
+		var resp serverless.StateResponse
+		assert.NoError(t, json.Unmarshal(respBuf, &resp))
+		return resp
+	}
+
+	assert.Equal(t, serverless.StateResponse{}, do(serverless.StateRequest{Op: serverless.StateOpPut, Key: "k", Value: []byte("v")}))
+	assert.Equal(t, serverless.StateResponse{Value: []byte("v"), OK: true}, do(serverless.StateRequest{Op: serverless.StateOpGet, Key: "k"}))
+	assert.Equal(t, serverless.StateResponse{OK: true}, do(serverless.StateRequest{
+		Op: serverless.StateOpCompareAndSwap, Key: "k", Old: []byte("v"), Value: []byte("v2"),
+	}))
+	assert.Equal(t, serverless.StateResponse{}, do(serverless.StateRequest{Op: serverless.StateOpDelete, Key: "k"}))
+	assert.Equal(t, serverless.StateResponse{}, do(serverless.StateRequest{Op: serverless.StateOpGet, Key: "k"}))
+	assert.Equal(t, "unknown state operation: incr", do(serverless.StateRequest{Op: "incr", Key: "k"}).Error)
+
+	_, err := Do(m, []byte("{"))
+	assert.Error(t, err)
+}
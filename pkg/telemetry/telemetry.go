@@ -0,0 +1,165 @@
+// Package telemetry exports the traces, metrics and logs of zipper to an OpenTelemetry collector by OTLP/HTTP,
+// the three signals are configured together, so they share the endpoint and the service name.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yomorun/yomo/pkg/config"
+	"github.com/yomorun/yomo/pkg/trace"
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// DefaultMetricsInterval is the interval of exporting metrics if it is not configured.
+const DefaultMetricsInterval = time.Minute
+
+// Telemetry holds the otel providers created from the config.
+type Telemetry struct {
+	meterProvider  metric.MeterProvider
+	loggerProvider *sdklog.LoggerProvider
+	serviceName    string
+
+	mu        sync.Mutex
+	shutdowns []func(context.Context) error
+}
+
+// New creates the otel providers of the enabled signals from the config,
+// the tracer provider is set as the global tracer provider, so the tracers of yomo use it.
+func New(ctx context.Context, conf config.Telemetry) (*Telemetry, error) {
+	if conf.Endpoint == "" {
+		return nil, errors.New("telemetry: the endpoint is required")
+	}
+	endpoint := strings.TrimSuffix(conf.Endpoint, "/")
+
+	serviceName := conf.ServiceName
+	if serviceName == "" {
+		serviceName = trace.ServiceName
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))
+
+	t := &Telemetry{
+		meterProvider: noop.NewMeterProvider(),
+		serviceName:   serviceName,
+	}
+
+	if conf.Traces {
+		exporter, err := otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"),
+			otlptracehttp.WithHeaders(conf.Headers),
+		)
+		if err != nil {
+			return nil, err
+		}
+		tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		t.shutdowns = append(t.shutdowns, tp.Shutdown)
+	}
+
+	if conf.Metrics {
+		exporter, err := otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"),
+			otlpmetrichttp.WithHeaders(conf.Headers),
+		)
+		if err != nil {
+			return nil, err
+		}
+		interval := conf.MetricsInterval
+		if interval <= 0 {
+			interval = DefaultMetricsInterval
+		}
+		mp := sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+			sdkmetric.WithResource(res),
+		)
+		t.meterProvider = mp
+		t.shutdowns = append(t.shutdowns, mp.Shutdown)
+	}
+
+	if conf.Logs {
+		exporter, err := otlploghttp.New(ctx,
+			otlploghttp.WithEndpointURL(endpoint+"/v1/logs"),
+			otlploghttp.WithHeaders(conf.Headers),
+		)
+		if err != nil {
+			return nil, err
+		}
+		lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)), sdklog.WithResource(res))
+		t.loggerProvider = lp
+		t.shutdowns = append(t.shutdowns, lp.Shutdown)
+	}
+
+	return t, nil
+}
+
+// MeterProvider returns the meter provider, it is a noop provider if the metrics are not enabled.
+func (t *Telemetry) MeterProvider() metric.MeterProvider {
+	return t.meterProvider
+}
+
+// Logger returns a logger that attaches the trace_id and span_id to the records of the logger,
+// and exports the records by OTLP if the logs are enabled.
+func (t *Telemetry) Logger(logger *slog.Logger) *slog.Logger {
+	handler := logger.Handler()
+	if t.loggerProvider != nil {
+		handler = teeHandler{handler, otelslog.NewHandler(t.serviceName, otelslog.WithLoggerProvider(t.loggerProvider))}
+	}
+	return slog.New(trace.NewLogHandler(handler))
+}
+
+// Close flushes the signals and shuts down the providers, it waits at most 5 seconds for the exporting.
+func (t *Telemetry) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return t.Shutdown(ctx)
+}
+
+// Shutdown flushes the signals and shuts down the providers.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	shutdowns := t.shutdowns
+	t.shutdowns = nil
+	t.mu.Unlock()
+
+	var errs []error
+	for _, shutdown := range shutdowns {
+		errs = append(errs, shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// teeHandler passes the records to the exporting handler as well, the level of the logger applies to both.
+type teeHandler struct {
+	slog.Handler
+	export slog.Handler
+}
+
+func (h teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	return errors.Join(h.Handler.Handle(ctx, r.Clone()), h.export.Handle(ctx, r))
+}
+
+func (h teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return teeHandler{h.Handler.WithAttrs(attrs), h.export.WithAttrs(attrs)}
+}
+
+func (h teeHandler) WithGroup(name string) slog.Handler {
+	return teeHandler{h.Handler.WithGroup(name), h.export.WithGroup(name)}
+}
@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/pkg/config"
+	"github.com/yomorun/yomo/pkg/trace"
+)
+
+func TestTelemetry(t *testing.T) {
+	t.Run("endpoint required", func(t *testing.T) {
+		_, err := New(context.Background(), config.Telemetry{Logs: true})
+		assert.Error(t, err)
+	})
+
+	var (
+		mu      sync.Mutex
+		paths   = map[string]int{}
+		headers = map[string]string{}
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths[r.URL.Path]++
+		headers[r.URL.Path] = r.Header.Get("X-Api-Key")
+	}))
+	defer collector.Close()
+
+	tel, err := New(context.Background(), config.Telemetry{
+		Endpoint: collector.URL,
+		Headers:  map[string]string{"X-Api-Key": "api-key"},
+		Traces:   true,
+		Metrics:  true,
+		Logs:     true,
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := tel.Logger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	md := metadata.M{}
+	tracer := trace.NewTracer("Zipper")
+	span := tracer.Start(md, "zipper")
+	logger.InfoContext(trace.NewContextWithMetadata(md), "routing")
+	tracer.End(md, span)
+
+	counter, err := tel.MeterProvider().Meter("test").Int64Counter("test.counter")
+	assert.NoError(t, err)
+	counter.Add(context.Background(), 1)
+
+	// the log is written to the logger and carries the trace.
+	assert.Contains(t, buf.String(), "trace_id="+md[metadata.TraceIDKey])
+
+	// the signals are flushed by shutting down.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, tel.Shutdown(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/v1/traces", "/v1/metrics", "/v1/logs"} {
+		assert.Equal(t, 1, paths[path], path)
+		assert.Equal(t, "api-key", headers[path], path)
+	}
+}
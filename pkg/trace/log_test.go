@@ -0,0 +1,31 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+)
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewTextHandler(&buf, nil))).With("tid", "tid-1")
+
+	logger.Info("without trace")
+	assert.NotContains(t, buf.String(), "trace_id")
+
+	buf.Reset()
+	md := metadata.M{
+		metadata.TraceIDKey: "4bf92f3577b34da6a3ce929d0e0e4736",
+		metadata.SpanIDKey:  "00f067aa0ba902b7",
+	}
+	logger.InfoContext(NewContextWithMetadata(md), "with trace")
+	assert.Contains(t, buf.String(), "tid=tid-1 trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7")
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "without trace")
+	assert.NotContains(t, buf.String(), "trace_id")
+}
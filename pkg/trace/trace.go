@@ -107,18 +107,24 @@ func (t *Tracer) Start(md metadata.M, operation string) trace.Span {
 	_, span := t.tracer.Start(NewContextWithMetadata(md),
 		operation,
 	)
-	propagateTrace(md, span)
+	InjectMetadata(md, span.SpanContext())
 	return span
 }
 
-// yomo uses metadata to propagate the trace info.
-func propagateTrace(md metadata.M, span trace.Span) {
-	if span.SpanContext().TraceID().IsValid() {
-		md.Set(metadata.TraceIDKey, span.SpanContext().TraceID().String())
+// InjectMetadata propagates the span context by the metadata, it sets the traceID and spanID,
+// and updates the W3C traceparent if the metadata carries it.
+func InjectMetadata(md metadata.M, sc trace.SpanContext) {
+	if sc.TraceID().IsValid() {
+		md.Set(metadata.TraceIDKey, sc.TraceID().String())
 	}
 
-	if span.SpanContext().SpanID().IsValid() {
-		md.Set(metadata.SpanIDKey, span.SpanContext().SpanID().String())
+	if sc.SpanID().IsValid() {
+		md.Set(metadata.SpanIDKey, sc.SpanID().String())
+	}
+
+	if _, ok := md.Get(metadata.TraceParentKey); ok && sc.IsValid() {
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+		propagation.TraceContext{}.Inject(ctx, metadataCarrier(md))
 	}
 }
 
@@ -131,25 +137,20 @@ func (t *Tracer) End(md metadata.M, span trace.Span, kv ...attribute.KeyValue) {
 }
 
 // NewContextWithMetadata create new context with metadata for tracer starting.
-// In yomo, we use metadata from dataFrame as the trace Propagator. And yomo only
-// carries traceID and spanID in metadata.
+// In yomo, we use metadata from dataFrame as the trace Propagator. And yomo
+// carries traceID and spanID in metadata, if they are absent, the W3C traceparent
+// in metadata is used, so the traces from HTTP frontends can be continued.
 func NewContextWithMetadata(md metadata.M) context.Context {
-	traceID, ok := md.Get(metadata.TraceIDKey)
-	if !ok {
-		return context.Background()
-	}
-	spanID, ok := md.Get(metadata.SpanIDKey)
-	if !ok {
-		return context.Background()
-	}
+	traceID, _ := md.Get(metadata.TraceIDKey)
+	spanID, _ := md.Get(metadata.SpanIDKey)
 
 	tid, err := trace.TraceIDFromHex(traceID)
 	if err != nil {
-		return context.Background()
+		return propagation.TraceContext{}.Extract(context.Background(), metadataCarrier(md))
 	}
 	sid, err := trace.SpanIDFromHex(spanID)
 	if err != nil {
-		return context.Background()
+		return propagation.TraceContext{}.Extract(context.Background(), metadataCarrier(md))
 	}
 
 	scc := trace.SpanContextConfig{
@@ -160,3 +161,21 @@ func NewContextWithMetadata(md metadata.M) context.Context {
 
 	return trace.ContextWithSpanContext(context.Background(), spanContext)
 }
+
+// metadataCarrier adapts the metadata to propagation.TextMapCarrier.
+type metadataCarrier metadata.M
+
+func (c metadataCarrier) Get(key string) string {
+	v, _ := metadata.M(c).Get(key)
+	return v
+}
+
+func (c metadataCarrier) Set(key, value string) { metadata.M(c).Set(key, value) }
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
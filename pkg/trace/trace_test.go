@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yomorun/yomo/core/metadata"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestTraceProvider(t *testing.T) {
@@ -51,3 +52,25 @@ func assertMd(t *testing.T, md metadata.M) string {
 
 	return traceID
 }
+
+func TestTraceParent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	md := metadata.M{metadata.TraceParentKey: "00-" + traceID + "-" + spanID + "-01"}
+
+	sc := trace.SpanContextFromContext(NewContextWithMetadata(md))
+	assert.Equal(t, traceID, sc.TraceID().String())
+	assert.Equal(t, spanID, sc.SpanID().String())
+
+	// the yomo trace metadata takes precedence over the traceparent.
+	child, err := trace.SpanIDFromHex("b7ad6b7169203331")
+	assert.NoError(t, err)
+	InjectMetadata(md, sc.WithSpanID(child))
+
+	assert.Equal(t, traceID, md[metadata.TraceIDKey])
+	assert.Equal(t, "b7ad6b7169203331", md[metadata.SpanIDKey])
+	assert.Equal(t, "00-"+traceID+"-b7ad6b7169203331-01", md[metadata.TraceParentKey])
+	assert.Equal(t, "b7ad6b7169203331", trace.SpanContextFromContext(NewContextWithMetadata(md)).SpanID().String())
+}
@@ -1,22 +1,36 @@
 // Package serverless defines serverless handler context
 package serverless
 
-import "github.com/yomorun/yomo/ai"
+import (
+	"context"
+
+	"github.com/yomorun/yomo/ai"
+)
 
 // Context sfn handler context
 type Context interface {
+	// Context returns the context of the handler, it is done when the deadline of the data is exceeded,
+	// the handler timeout is reached or the sfn is closed.
+	Context() context.Context
 	// Data incoming data
 	Data() []byte
 	// Tag incoming tag
 	Tag() uint32
 	// Metadata incoming metadata
 	Metadata(string) (string, bool)
+	// MetadataKeys returns the sorted keys of incoming metadata
+	MetadataKeys() []string
 	// Write writes data
 	Write(tag uint32, data []byte) error
 	// HTTP http interface
 	HTTP() HTTP
+	// State returns the key-value state of the sfn
+	State() State
 	// WriteWithTarget writes data to sfn instance with specified target
 	WriteWithTarget(tag uint32, data []byte, target string) error
+	// WriteWithMetadata writes data with the metadata added to incoming metadata, it only applies to this write,
+	// the keys prefixed with `yomo-` are reserved and can not be written.
+	WriteWithMetadata(tag uint32, data []byte, md map[string]string) error
 	// ReadLLMArguments reads LLM function arguments
 	ReadLLMArguments(args any) error
 	// WriteLLMResult writes LLM function result
@@ -27,12 +41,19 @@ type Context interface {
 
 // CronContext sfn corn handler context
 type CronContext interface {
+	// Context returns the context of the handler, it is done when the handler timeout is reached or the sfn is closed.
+	Context() context.Context
 	// Write writes data
 	Write(tag uint32, data []byte) error
 	// HTTP http interface
 	HTTP() HTTP
+	// State returns the key-value state of the sfn
+	State() State
 	// WriteWithTarget writes data to sfn instance with specified target
 	WriteWithTarget(tag uint32, data []byte, target string) error
+	// WriteWithMetadata writes data with the metadata, it only applies to this write,
+	// the keys prefixed with `yomo-` are reserved and can not be written.
+	WriteWithMetadata(tag uint32, data []byte, md map[string]string) error
 }
 
 // HTTP http interface
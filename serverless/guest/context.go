@@ -2,6 +2,8 @@
 package guest
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	_ "unsafe"
 
@@ -24,6 +26,11 @@ var _ serverless.Context = (*GuestContext)(nil)
 // GuestContext is the context for guest
 type GuestContext struct{}
 
+// Context returns the context of the handler, the deadline of the data is checked by the host before the handler is called.
+func (c *GuestContext) Context() context.Context {
+	return context.Background()
+}
+
 // Tag returns the tag of the context
 func (c *GuestContext) Tag() uint32 {
 	return yomoContextTag()
@@ -39,6 +46,34 @@ func (c *GuestContext) Metadata(key string) (string, bool) {
 	panic("not implemented")
 }
 
+// MetadataKeys returns the sorted keys of incoming metadata
+func (c *GuestContext) MetadataKeys() []string {
+	buf := GetBytes(contextMetadataKeys)
+	if len(buf) == 0 {
+		return nil
+	}
+	var keys []string
+	if err := json.Unmarshal(buf, &keys); err != nil {
+		return nil
+	}
+	return keys
+}
+
+// WriteWithMetadata writes data with metadata to the context
+func (c *GuestContext) WriteWithMetadata(tag uint32, data []byte, md map[string]string) error {
+	if data == nil {
+		return nil
+	}
+	mdBytes, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+	if yomoWriteWithMetadata(tag, &data[0], len(data), &mdBytes[0], len(mdBytes)) != 0 {
+		return errors.New("yomoWriteWithMetadata error")
+	}
+	return nil
+}
+
 // Write writes data to the context
 func (c *GuestContext) Write(tag uint32, data []byte) error {
 	if data == nil {
@@ -77,6 +112,10 @@ func yomoWrite(tag uint32, pointer *byte, length int) uint32
 //go:linkname yomoWriteWithTarget
 func yomoWriteWithTarget(tag uint32, pointer *byte, length int, targetPointer *byte, targetLength int) uint32
 
+//export yomo_write_with_metadata
+//go:linkname yomoWriteWithMetadata
+func yomoWriteWithMetadata(tag uint32, pointer *byte, length int, metadataPointer *byte, metadataLength int) uint32
+
 //export yomo_context_tag
 //go:linkname yomoContextTag
 func yomoContextTag() uint32
@@ -85,6 +124,10 @@ func yomoContextTag() uint32
 //go:linkname contextData
 func contextData(ptr uintptr, size uint32) uint32
 
+//export yomo_context_metadata_keys
+//go:linkname contextMetadataKeys
+func contextMetadataKeys(ptr uintptr, size uint32) uint32
+
 //export yomo_observe_datatags
 //go:linkname yomoObserveDataTags
 func yomoObserveDataTags() {
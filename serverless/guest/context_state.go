@@ -0,0 +1,84 @@
+package guest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "unsafe"
+
+	"github.com/yomorun/yomo/serverless"
+)
+
+// State returns the key-value state of the sfn, the state is kept by the host.
+func (c *GuestContext) State() serverless.State {
+	return &GuestState{}
+}
+
+// GuestState is the key-value state for guest
+type GuestState struct{}
+
+// Get returns the value of the key
+func (s *GuestState) Get(key string) ([]byte, bool, error) {
+	resp, err := s.do(&serverless.StateRequest{Op: serverless.StateOpGet, Key: key})
+	if err != nil {
+		return nil, false, err
+	}
+	return resp.Value, resp.OK, nil
+}
+
+// Put sets the value of the key
+func (s *GuestState) Put(key string, value []byte, ttl time.Duration) error {
+	_, err := s.do(&serverless.StateRequest{Op: serverless.StateOpPut, Key: key, Value: value, TTL: ttl.Milliseconds()})
+	return err
+}
+
+// Delete deletes the key
+func (s *GuestState) Delete(key string) error {
+	_, err := s.do(&serverless.StateRequest{Op: serverless.StateOpDelete, Key: key})
+	return err
+}
+
+// CompareAndSwap sets the value of the key to new if the current value is old
+func (s *GuestState) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error) {
+	resp, err := s.do(&serverless.StateRequest{
+		Op:    serverless.StateOpCompareAndSwap,
+		Key:   key,
+		Value: new,
+		Old:   old,
+		TTL:   ttl.Milliseconds(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.OK, nil
+}
+
+func (s *GuestState) do(req *serverless.StateRequest) (*serverless.StateResponse, error) {
+	reqBuf, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	reqPtr, reqSize := bufferToPtrSize(reqBuf)
+
+	var respPtr *uint32
+	var respSize uint32
+	if errCode := stateDo(reqPtr, reqSize, &respPtr, &respSize); errCode != 0 {
+		return nil, fmt.Errorf("state request error: %d", errCode)
+	}
+	respBuf := readBufferFromMemory(respPtr, respSize)
+
+	var resp serverless.StateResponse
+	if err := json.Unmarshal(respBuf, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+//export yomo_state
+//go:linkname stateDo
+func stateDo(reqPtr uintptr, reqSize uint32, respPtr **uint32, respSize *uint32) uint32
@@ -2,28 +2,35 @@
 package mock
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/yomorun/yomo/ai"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/pkg/state"
 	"github.com/yomorun/yomo/serverless"
 )
 
 var _ serverless.Context = (*MockContext)(nil)
 
-// WriteRecord composes the data, tag and target.
+// WriteRecord composes the data, tag, target and metadata.
 type WriteRecord struct {
-	Data   []byte
-	Tag    uint32
-	Target string
+	Data     []byte
+	Tag      uint32
+	Target   string
+	Metadata map[string]string
 }
 
 // MockContext mock context.
 type MockContext struct {
 	data   []byte
 	tag    uint32
+	md     metadata.M
 	fnCall *ai.FunctionCall
+	state  serverless.State
 
 	mu      sync.Mutex
 	wrSlice []WriteRecord
@@ -33,11 +40,23 @@ type MockContext struct {
 // the data is that returned by ctx.Data(), the tag is that returned by ctx.Tag().
 func NewMockContext(data []byte, tag uint32) *MockContext {
 	return &MockContext{
-		data: data,
-		tag:  tag,
+		data:  data,
+		tag:   tag,
+		md:    metadata.M{},
+		state: state.NewMemory(),
 	}
 }
 
+// SetMetadata sets the incoming metadata returned by ctx.Metadata().
+func (c *MockContext) SetMetadata(key, value string) {
+	c.md.Set(key, value)
+}
+
+// Context returns the context of the handler, it is never done.
+func (c *MockContext) Context() context.Context {
+	return context.Background()
+}
+
 // Data incoming data.
 func (c *MockContext) Data() []byte {
 	return c.data
@@ -48,9 +67,19 @@ func (c *MockContext) Tag() uint32 {
 	return c.tag
 }
 
-// Metadata returns the metadata by the given key.
-func (c *MockContext) Metadata(_ string) (string, bool) {
-	panic("not implemented")
+// Metadata returns the metadata set by SetMetadata by the given key.
+func (c *MockContext) Metadata(key string) (string, bool) {
+	return c.md.Get(key)
+}
+
+// MetadataKeys returns the sorted keys of the metadata set by SetMetadata.
+func (c *MockContext) MetadataKeys() []string {
+	keys := make([]string, 0, len(c.md))
+	for k := range c.md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // HTTP returns the HTTP interface.H
@@ -58,6 +87,11 @@ func (c *MockContext) HTTP() serverless.HTTP {
 	panic("not implemented, to use `net/http` package")
 }
 
+// State returns the key-value state in memory.
+func (c *MockContext) State() serverless.State {
+	return c.state
+}
+
 // Write writes the data with the given tag.
 func (c *MockContext) Write(tag uint32, data []byte) error {
 	c.mu.Lock()
@@ -85,6 +119,26 @@ func (c *MockContext) WriteWithTarget(tag uint32, data []byte, target string) er
 	return nil
 }
 
+// WriteWithMetadata writes the data with the given tag and metadata.
+func (c *MockContext) WriteWithMetadata(tag uint32, data []byte, md map[string]string) error {
+	for k := range md {
+		if err := metadata.IsReservedKey(k); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.wrSlice = append(c.wrSlice, WriteRecord{
+		Data:     data,
+		Tag:      tag,
+		Metadata: md,
+	})
+
+	return nil
+}
+
 // ReadLLMArguments reads LLM function arguments.
 func (c *MockContext) ReadLLMArguments(args any) error {
 	fnCall, err := c.LLMFunctionCall()
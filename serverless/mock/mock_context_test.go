@@ -25,10 +25,30 @@ func TestWrite(t *testing.T) {
 	assert.Equal(t, []byte("REQUEST"), ctx.Data())
 	assert.Equal(t, uint32(0x10), ctx.Tag())
 
+	ctx.WriteWithMetadata(0x13, []byte("METADATA_RESPONSE"), map[string]string{"key": "value"})
+	assert.Error(t, ctx.WriteWithMetadata(0x13, []byte("RESERVED"), map[string]string{"yomo-target": "target"}))
+
 	records := ctx.RecordsWritten()
 
+	assert.Len(t, records, 3)
 	assert.Equal(t, []byte("RESPONSE"), records[0].Data)
 	assert.Equal(t, []byte("TRAGET_RESPONSE"), records[1].Data)
+	assert.Equal(t, map[string]string{"key": "value"}, records[2].Metadata)
+}
+
+func TestMetadata(t *testing.T) {
+	ctx := NewMockContext([]byte("REQUEST"), 0x10)
+
+	_, ok := ctx.Metadata("key")
+	assert.False(t, ok)
+
+	ctx.SetMetadata("key", "value")
+	ctx.SetMetadata("serializer", "json")
+
+	v, ok := ctx.Metadata("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, []string{"key", "serializer"}, ctx.MetadataKeys())
 }
 
 func TestReadFunctionCall(t *testing.T) {
@@ -0,0 +1,41 @@
+package serverless
+
+import "time"
+
+// State is the key-value state of the stream function, it is shared by the handlers of the sfn,
+// and it outlives the restarts of the sfn if the state store is on disk.
+type State interface {
+	// Get returns the value of the key, the ok is false if the key does not exist or it is expired.
+	Get(key string) (value []byte, ok bool, err error)
+	// Put sets the value of the key, the key expires after the ttl, it never expires if the ttl is zero.
+	Put(key string, value []byte, ttl time.Duration) error
+	// Delete deletes the key.
+	Delete(key string) error
+	// CompareAndSwap sets the value of the key to new if the current value is old, the nil old means that
+	// the key does not exist. It reports whether the value is swapped.
+	CompareAndSwap(key string, old, new []byte, ttl time.Duration) (swapped bool, err error)
+}
+
+// The operations of StateRequest.
+const (
+	StateOpGet            = "get"
+	StateOpPut            = "put"
+	StateOpDelete         = "delete"
+	StateOpCompareAndSwap = "cas"
+)
+
+// StateRequest is the state operation requested by the wasm and deno guests.
+type StateRequest struct {
+	Op    string // get, put, delete, cas
+	Key   string // the key of the state
+	Value []byte // the value of put, the new value of cas
+	Old   []byte // the old value of cas, null means the key does not exist
+	TTL   int64  // ttl in milliseconds, zero means never expires
+}
+
+// StateResponse is the result of StateRequest.
+type StateResponse struct {
+	Value []byte // the value of get
+	OK    bool   // whether the key exists for get, whether the value is swapped for cas
+	Error string // the error message, empty means no error
+}
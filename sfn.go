@@ -3,7 +3,14 @@ package yomo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/robfig/cron/v3"
 
@@ -12,11 +19,16 @@ import (
 	"github.com/yomorun/yomo/core/metadata"
 	"github.com/yomorun/yomo/core/serverless"
 	"github.com/yomorun/yomo/pkg/id"
+	"github.com/yomorun/yomo/pkg/serializer"
+	"github.com/yomorun/yomo/pkg/state"
 	"github.com/yomorun/yomo/pkg/trace"
 	yserverless "github.com/yomorun/yomo/serverless"
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrHandlerPanic is the error of the sfn handler that panics, the handler is not retried for it.
+var ErrHandlerPanic = errors.New("yomo: sfn handler panic")
+
 // StreamFunction defines serverless streaming functions.
 type StreamFunction interface {
 	// SetWantedTarget sets target for sfn that to receive data carrying the same target.
@@ -26,11 +38,24 @@ type StreamFunction interface {
 	SetObserveDataTags(tag ...uint32)
 	// Init will initialize the stream function
 	Init(fn func() error) error
-	// SetHandler set the handler function, which accept the raw bytes data and return the tag & response
+	// SetHandler set the handler function, which accept the raw bytes data and return the tag & response.
+	// It handles the data of the observed tags that have no handler registered by Handle.
 	SetHandler(fn core.AsyncHandler) error
+	// SetHandlerE set the handler function that returns an error, the handler is retried by the retry policy
+	// set by WithSfnRetryPolicy, and the data is written to the dead-letter tag set by WithSfnDeadLetterTag if it fails at last.
+	SetHandlerE(fn core.AsyncHandlerE) error
+	// Handle registers the handler of the tag, the tag is observed without SetObserveDataTags.
+	// The handler is retried and dead-lettered as the handler set by SetHandlerE, the options set its own
+	// concurrency and serializer. The data of the tags without a registered handler are handled by
+	// the handler set by SetHandler, or the pipe handler. It returns an error after Connect.
+	//  Examples:
+	//  sfn.Handle(0x33, onNoise, yomo.WithHandlerConcurrency(8))
+	//  sfn.Handle(0x34, yomo.TypedHandler[Alert, Ack](onAlert).Handler(0x35, nil), yomo.WithHandlerSerializer(serializer.Msgpack))
+	Handle(tag uint32, fn core.AsyncHandlerE, opts ...HandlerOption) error
 	// SetErrorHandler set the error handler function when server error occurs
 	SetErrorHandler(fn func(err error))
-	// SetPipeHandler set the pipe handler function
+	// SetPipeHandler set the pipe handler function, it handles the data of the tags without a registered handler
+	// when the handler is not set by SetHandler.
 	SetPipeHandler(fn core.PipeHandler) error
 	// SetCronHandler set the cron handler function.
 	//  Examples:
@@ -38,16 +63,24 @@ type StreamFunction interface {
 	//  sfn.SetCronHandler("@hourly",      func(ctx serverless.CronContext) {})
 	//  sfn.SetCronHandler("@every 1h30m", func(ctx serverless.CronContext) {})
 	// more spec style see: https://pkg.go.dev/github.com/robfig/cron#hdr-Usage
+	// The cron handler runs in every sfn instance, unless WithSfnClusterCron makes it run once per tick in the cluster.
 	SetCronHandler(spec string, fn core.CronHandler) error
 	// Connect create a connection to the zipper
 	Connect() error
 	// Close will close the connection
 	Close() error
+	// Shutdown unsubscribes from the zipper so no new data arrives, waits for the in-flight handlers,
+	// cron handlers and pipe outputs until the ctx is done, flushes the writes and closes the connection.
+	// The input channel of the pipe handler is closed on shutdown.
+	Shutdown(ctx context.Context) error
 	// Wait waits sfn to finish.
 	Wait()
+	// ConnectionState returns the state of the connection to the zipper.
+	ConnectionState() ConnectionState
 }
 
 // NewStreamFunction create a stream function.
+// The zipperAddr can be a comma-separated list of zipper endpoints, the sfn fails over between them.
 func NewStreamFunction(name, zipperAddr string, opts ...SfnOption) StreamFunction {
 	trace.SetTracerProvider()
 
@@ -65,11 +98,16 @@ func NewStreamFunction(name, zipperAddr string, opts ...SfnOption) StreamFunctio
 		"zipper_addr", zipperAddr,
 	)
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	sfn := &streamFunction{
+		ctx:             ctx,
+		cancel:          cancel,
 		name:            name,
 		zipperAddr:      zipperAddr,
 		client:          client,
 		observeDataTags: make([]uint32, 0),
+		routes:          make(map[uint32]*route),
 	}
 
 	return sfn
@@ -82,14 +120,25 @@ type streamFunction struct {
 	name            string
 	zipperAddr      string
 	client          *core.Client
-	observeDataTags []uint32          // tag list that will be observed
-	fn              core.AsyncHandler // user's function which will be invoked when data arrived
+	observeDataTags []uint32           // tag list that will be observed
+	fn              core.AsyncHandlerE // user's function which will be invoked when data arrived
+	routes          map[uint32]*route  // the handlers registered by Handle, the key is the tag
 	pfn             core.PipeHandler
 	pIn             chan []byte
+	pMu             sync.RWMutex  // guards sending to pIn and closing it
+	pClosed         bool          // pIn is closed by Shutdown
+	pDone           chan struct{} // it is closed after the outputs of the pipe handler are written
 	cronSpec        string
 	cronFn          core.CronHandler
 	cron            *cron.Cron
 	pOut            chan *frame.DataFrame
+	pool            jobQueue        // the handler workers shared by the handlers without their own, nil means a goroutine per data frame
+	ctx             context.Context // the parent of handler contexts, it is canceled when the sfn is closed
+	cancel          context.CancelFunc
+	state           yserverless.State
+	ownState        bool        // the state is created by the sfn, so it is closed by the sfn
+	inflight        inflight    // the data frames being handled or waiting for a worker, and the cron handlers
+	connected       atomic.Bool // Connect is called, the handlers can not be registered any more
 }
 
 func (s *streamFunction) SetWantedTarget(target string) {
@@ -108,11 +157,43 @@ func (s *streamFunction) SetObserveDataTags(tag ...uint32) {
 
 // SetHandler set the handler function, which accept the raw bytes data and return the tag & response.
 func (s *streamFunction) SetHandler(fn core.AsyncHandler) error {
+	if fn == nil {
+		return s.SetHandlerE(nil)
+	}
+	return s.SetHandlerE(func(ctx yserverless.Context) error {
+		fn(ctx)
+		return nil
+	})
+}
+
+// SetHandlerE set the handler function that returns an error.
+func (s *streamFunction) SetHandlerE(fn core.AsyncHandlerE) error {
 	s.fn = fn
 	s.client.Logger.Debug("set async handler")
 	return nil
 }
 
+// Handle registers the handler of the tag.
+func (s *streamFunction) Handle(tag uint32, fn core.AsyncHandlerE, opts ...HandlerOption) error {
+	if fn == nil {
+		return errors.New("yomo: the handler is nil")
+	}
+	if s.connected.Load() {
+		return fmt.Errorf("yomo: the handler of tag %#x must be registered before Connect", tag)
+	}
+	if _, ok := s.routes[tag]; ok {
+		return fmt.Errorf("yomo: the handler of tag %#x has been registered", tag)
+	}
+
+	r := &route{fn: fn}
+	for _, o := range opts {
+		o(&r.opts)
+	}
+	s.routes[tag] = r
+	s.client.Logger.Debug("register handler", "tag", tag)
+	return nil
+}
+
 func (s *streamFunction) SetCronHandler(cronSpec string, fn core.CronHandler) error {
 	s.cronSpec = cronSpec
 	s.cronFn = fn
@@ -129,25 +210,30 @@ func (s *streamFunction) SetPipeHandler(fn core.PipeHandler) error {
 // Connect create a connection to the zipper, when data arrvied, the data will be passed to the
 // handler set by SetHandler method.
 func (s *streamFunction) Connect() error {
+	s.connected.Store(true)
+
+	if s.state = s.client.State(); s.state == nil {
+		s.state, s.ownState = state.NewMemory(), true
+	}
+
 	hasCron := s.cronFn != nil && s.cronSpec != ""
-	if hasCron {
+	if hasCron && s.client.ClusterCron() {
+		// the zipper triggers one of the sfn instances on each tick.
+		s.client.SetCronSpec(s.cronSpec)
+		s.client.SetCronTrigger(s.onCronTrigger)
+	} else if hasCron {
 		s.cron = cron.New()
-		s.cron.AddFunc(s.cronSpec, func() {
-			md := core.NewMetadata(s.client.ClientID(), id.New())
-			// add trace
-			tracer := trace.NewTracer("StreamFunction")
-			span := tracer.Start(md, s.name)
-			defer tracer.End(md, span, attribute.String("sfn_handler_type", "corn_handler"))
-
-			cronCtx := serverless.NewCronContext(s.client, md)
-			s.cronFn(cronCtx)
-		})
+		s.cron.AddFunc(s.cronSpec, s.runCron)
 		s.cron.Start()
 	}
 
-	if len(s.observeDataTags) == 0 && !hasCron {
+	tags := s.tags()
+	if len(tags) == 0 && !hasCron {
 		return errors.New("streamFunction cannot observe data because the required tag has not been set")
 	}
+	s.client.SetObserveDataTags(tags...)
+
+	s.startPools()
 
 	s.client.Logger.Debug("sfn connecting to zipper ...")
 	// notify underlying network operations, when data with tag we observed arrived, invoke the func
@@ -159,49 +245,26 @@ func (s *streamFunction) Connect() error {
 	if s.pfn != nil {
 		s.pIn = make(chan []byte)
 		s.pOut = make(chan *frame.DataFrame)
+		s.pDone = make(chan struct{})
+		pfnDone := make(chan struct{})
 
 		// handle user's pipe function
 		go func() {
+			defer close(pfnDone)
 			s.pfn(s.pIn, s.pOut)
 		}()
 
-		// send user's pipe function outputs to zipper
+		// send user's pipe function outputs to zipper, until the pipe function returns.
 		go func() {
+			defer close(s.pDone)
 			for {
-				data := <-s.pOut
-				if data != nil {
-					s.client.Logger.Debug("pipe fn send", "payload_frame", data)
-					md, err := metadata.Decode(data.Metadata)
-					if err != nil {
-						s.client.Logger.Error("sfn decode metadata error", "err", err)
-						break
-					}
-
-					// add trace
-					tracer := trace.NewTracer("StreamFunction")
-					span := tracer.Start(md, s.name)
-					defer tracer.End(
-						md,
-						span,
-						attribute.String("sfn_handler_type", "pipe_handler"),
-						attribute.Int("recv_data_tag", int(data.Tag)),
-						attribute.Int("recv_data_len", len(data.Payload)),
-					)
-
-					rawMd, err := md.Encode()
-					if err != nil {
-						s.client.Logger.Error("sfn encode metadata error", "err", err)
-						break
-					}
-					data.Metadata = rawMd
-
-					frame := &frame.DataFrame{
-						Tag:      data.Tag,
-						Metadata: data.Metadata,
-						Payload:  data.Payload,
+				select {
+				case data := <-s.pOut:
+					if data != nil {
+						s.writePipeOutput(data)
 					}
-
-					s.client.WriteFrame(frame)
+				case <-pfnDone:
+					return
 				}
 			}
 		}()
@@ -211,14 +274,64 @@ func (s *streamFunction) Connect() error {
 	return err
 }
 
+// runCron invokes the user's cron handler.
+func (s *streamFunction) runCron() {
+	md := core.NewMetadata(s.client.ClientID(), id.New())
+	// add trace
+	tracer := trace.NewTracer("StreamFunction")
+	span := tracer.Start(md, s.name)
+	defer tracer.End(md, span, attribute.String("sfn_handler_type", "corn_handler"))
+
+	ctx, cancel := s.handlerContext(md)
+	defer cancel()
+
+	cronCtx := serverless.NewCronContext(ctx, s.client, s.state, md)
+	if err := s.invoke(func() error { s.cronFn(cronCtx); return nil }); err != nil {
+		s.client.Logger.Error("sfn cron handler failed", "err", err)
+	}
+}
+
+// onCronTrigger runs the cron handler triggered by the zipper, it does not block reading the frames.
+func (s *streamFunction) onCronTrigger(tick time.Time) {
+	if s.ctx.Err() != nil {
+		s.client.Logger.Debug("sfn is closed, the cron trigger is skipped", "tick", tick)
+		return
+	}
+	if !s.inflight.add() {
+		s.client.Logger.Debug("sfn is shutting down, the cron trigger is skipped", "tick", tick)
+		return
+	}
+	s.client.Logger.Debug("cron handler triggered", "tick", tick)
+
+	go func() {
+		defer s.inflight.done()
+		s.runCron()
+	}()
+}
+
 // Close will close the connection.
 func (s *streamFunction) Close() error {
+	s.cancel()
+
 	if s.cron != nil {
 		s.cron.Stop()
 	}
 
 	_ = s.client.Close()
 
+	if s.pool != nil {
+		s.pool.close()
+	}
+	for _, r := range s.routes {
+		if r.pool != nil {
+			r.pool.close()
+		}
+	}
+
+	if s.ownState {
+		_ = s.state.(state.Store).Close()
+	}
+
 	trace.ShutdownTracerProvider()
 
 	s.client.Logger.Debug("the sfn is closed")
@@ -226,44 +339,397 @@ func (s *streamFunction) Close() error {
 	return nil
 }
 
+// Shutdown gracefully closes the sfn, the handlers still running when the ctx is done are canceled.
+func (s *streamFunction) Shutdown(ctx context.Context) error {
+	if err := s.client.Unsubscribe(ctx); err != nil {
+		s.client.Logger.Warn("sfn failed to unsubscribe", "err", err)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+
+		if s.cron != nil {
+			select {
+			case <-s.cron.Stop().Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		if s.pIn != nil {
+			s.pMu.Lock()
+			s.pClosed = true
+			close(s.pIn)
+			s.pMu.Unlock()
+			select {
+			case <-s.pDone:
+			case <-ctx.Done():
+				return
+			}
+		}
+		// no data frame or cron trigger is accepted after closing, so the in-flight work only decreases.
+		select {
+		case <-s.inflight.close():
+		case <-ctx.Done():
+		}
+	}()
+
+	var err error
+	<-drained
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.client.Logger.Warn("sfn shutdown timeout, the in-flight handlers are canceled", "err", err)
+	default:
+		// the zipper acks after it handles the data written before, so the writes are flushed.
+		err = s.client.Unsubscribe(ctx)
+	}
+
+	_ = s.Close()
+
+	return err
+}
+
 // Wait waits sfn to finish.
 func (s *streamFunction) Wait() {
 	s.client.Wait()
 }
 
+// ConnectionState returns the state of the connection to the zipper.
+func (s *streamFunction) ConnectionState() ConnectionState {
+	return s.client.ConnectionState()
+}
+
 // when DataFrame we observed arrived, invoke the user's function
 // func (s *streamFunction) onDataFrame(data []byte, metaFrame *frame.MetaFrame) {
 func (s *streamFunction) onDataFrame(dataFrame *frame.DataFrame) {
+	if s.fn == nil && s.pfn == nil && len(s.routes) == 0 {
+		s.client.Logger.Warn("sfn does not have a handler")
+		return
+	}
+
+	md, err := metadata.Decode(dataFrame.Metadata)
+	if err != nil {
+		s.client.Logger.Error("sfn decode metadata error", "err", err)
+		return
+	}
+	if s.expired(dataFrame, md) {
+		return
+	}
+
+	if _, ok := md.Get(metadata.BatchKey); ok {
+		s.onBatch(dataFrame, md)
+		return
+	}
+	s.dispatch(dataFrame, md)
+}
+
+// onBatch unpacks the batch data frame, the data are dispatched one by one as the data frames without batching.
+func (s *streamFunction) onBatch(dataFrame *frame.DataFrame, md metadata.M) {
+	batch, err := core.DecodeBatch(dataFrame.Payload)
+	if err != nil {
+		s.client.Logger.Error("sfn decode batch error", "err", err, "tag", dataFrame.Tag)
+		return
+	}
+	delete(md, metadata.BatchKey)
+
+	for _, data := range batch {
+		// the metadata is cloned, because the handlers of the data run concurrently and the tracing writes it.
+		s.dispatch(&frame.DataFrame{Tag: dataFrame.Tag, Metadata: dataFrame.Metadata, Payload: data}, md.Clone())
+	}
+}
+
+// dispatch passes the data frame to the handler of its tag, the handler set by SetHandler or the pipe handler.
+func (s *streamFunction) dispatch(dataFrame *frame.DataFrame, md metadata.M) {
+	if r, ok := s.routes[dataFrame.Tag]; ok {
+		s.submit(r, dataFrame, md)
+		return
+	}
 	if s.fn != nil {
-		go func(dataFrame *frame.DataFrame) {
-			md, err := metadata.Decode(dataFrame.Metadata)
-			if err != nil {
-				s.client.Logger.Error("sfn decode metadata error", "err", err)
-				return
-			}
+		s.submit(&route{fn: s.fn, pool: s.pool}, dataFrame, md)
+		return
+	}
+	if s.pfn == nil {
+		s.client.Logger.Warn("sfn does not have a handler of the tag, the data frame is discarded", "tag", dataFrame.Tag)
+		return
+	}
 
-			// add trace
-			tracer := trace.NewTracer("StreamFunction", s.client.DisableOtelTrace())
-			span := tracer.Start(md, s.name)
-			defer tracer.End(
-				md,
-				span,
-				attribute.String("sfn_handler_type", "async_handler"),
-				attribute.Int("recv_data_tag", int(dataFrame.Tag)),
-				attribute.Int("recv_data_len", len(dataFrame.Payload)),
-			)
-
-			serverlessCtx := serverless.NewContext(s.client, dataFrame.Tag, md, dataFrame.Payload)
-			s.fn(serverlessCtx)
-			checkLLMFunctionCall(s.client.Logger, serverlessCtx)
-		}(dataFrame)
-	} else if s.pfn != nil {
-		data := dataFrame.Payload
-		s.client.Logger.Debug("pipe sfn receive", "data_len", len(data), "data", data)
-		s.pIn <- data
-	} else {
-		s.client.Logger.Warn("sfn does not have a handler")
+	s.pMu.RLock()
+	defer s.pMu.RUnlock()
+
+	if s.pClosed {
+		s.client.Logger.Warn("sfn is shutting down, the data frame is discarded", "tag", dataFrame.Tag)
+		return
+	}
+	data := dataFrame.Payload
+	s.client.Logger.Debug("pipe sfn receive", "data_len", len(data), "data", data)
+	s.pIn <- data
+}
+
+// submit runs the handler with the data frame on the workers of the handler, or a new goroutine without workers.
+func (s *streamFunction) submit(r *route, dataFrame *frame.DataFrame, md metadata.M) {
+	if !s.inflight.add() {
+		s.client.Logger.Warn("sfn is shutting down, the data frame is discarded", "tag", dataFrame.Tag)
+		return
+	}
+	job := func() {
+		defer s.inflight.done()
+		s.handle(r, dataFrame, md)
+	}
+	if r.pool == nil {
+		go job()
+		return
+	}
+
+	var key string
+	if fn := s.client.SerialKey(); fn != nil {
+		key = fn(serverless.NewContext(s.ctx, s.client, s.state, dataFrame.Tag, md, dataFrame.Payload))
+	}
+	// it blocks while the queue is full, so the zipper is slowed down by the backpressure.
+	if !r.pool.submit(key, job) {
+		s.inflight.done()
+		s.client.Logger.Warn("sfn is closed, the data frame is discarded", "tag", dataFrame.Tag)
+	}
+}
+
+// tags returns the observed tags and the tags of the registered handlers.
+func (s *streamFunction) tags() []uint32 {
+	tags := slices.Clone(s.observeDataTags)
+	for tag := range s.routes {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// startPools starts the workers of the handlers, the handlers without their own concurrency share
+// the workers set by WithSfnConcurrency. If any handler has its own workers, every pool is dispatched
+// from its own bounded queue, so a handler with the busy workers does not block the other handlers
+// until its queue is full.
+func (s *streamFunction) startPools() {
+	serial := s.client.SerialKey() != nil
+	workers, queueSize := s.client.Concurrency()
+	// the serial key needs the workers, the data of a key would run concurrently in their own goroutines.
+	if serial && workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	shared, own := s.fn != nil, false
+	for _, r := range s.routes {
+		if r.opts.concurrency > 0 {
+			own = true
+		} else {
+			shared = true
+		}
+	}
+	newPool := func(workers, queueSize int) jobQueue {
+		if queueSize <= 0 {
+			queueSize = workers
+		}
+		p := newHandlerPool(workers, queueSize, serial)
+		if own {
+			return newDispatcher(p, queueSize)
+		}
+		return p
+	}
+
+	for _, r := range s.routes {
+		if r.opts.concurrency > 0 {
+			r.pool = newPool(r.opts.concurrency, r.opts.queueSize)
+		}
+	}
+	if workers <= 0 || !shared {
+		return
+	}
+
+	s.pool = newPool(workers, queueSize)
+	for _, r := range s.routes {
+		if r.pool == nil {
+			r.pool = s.pool
+		}
+	}
+}
+
+// writePipeOutput writes the output of the pipe handler to the zipper.
+func (s *streamFunction) writePipeOutput(data *frame.DataFrame) {
+	s.client.Logger.Debug("pipe fn send", "payload_frame", data)
+	md, err := metadata.Decode(data.Metadata)
+	if err != nil {
+		s.client.Logger.Error("sfn decode metadata error", "err", err)
+		return
+	}
+
+	// add trace
+	tracer := trace.NewTracer("StreamFunction")
+	span := tracer.Start(md, s.name)
+	defer tracer.End(
+		md,
+		span,
+		attribute.String("sfn_handler_type", "pipe_handler"),
+		attribute.Int("recv_data_tag", int(data.Tag)),
+		attribute.Int("recv_data_len", len(data.Payload)),
+	)
+
+	rawMd, err := md.Encode()
+	if err != nil {
+		s.client.Logger.Error("sfn encode metadata error", "err", err)
+		return
+	}
+
+	s.client.WriteFrame(&frame.DataFrame{
+		Tag:      data.Tag,
+		Metadata: rawMd,
+		Payload:  data.Payload,
+	})
+}
+
+// handle invokes the user's function with the data frame.
+func (s *streamFunction) handle(r *route, dataFrame *frame.DataFrame, md metadata.M) {
+	// the data frame may expire while it is waiting for a worker.
+	if s.expired(dataFrame, md) {
+		return
+	}
+
+	ctx, cancel := s.handlerContext(md)
+	defer cancel()
+	if r.opts.serializer != nil {
+		ctx = context.WithValue(ctx, handlerSerializerKey{}, r.opts.serializer)
+	}
+
+	// add trace
+	tracer := trace.NewTracer("StreamFunction", s.client.DisableOtelTrace())
+	span := tracer.Start(md, s.name)
+	defer tracer.End(
+		md,
+		span,
+		attribute.String("sfn_handler_type", "async_handler"),
+		attribute.Int("recv_data_tag", int(dataFrame.Tag)),
+		attribute.Int("recv_data_len", len(dataFrame.Payload)),
+	)
+
+	serverlessCtx := serverless.NewContext(ctx, s.client, s.state, dataFrame.Tag, md, dataFrame.Payload)
+
+	var (
+		policy  = s.client.RetryPolicy()
+		b       = policy.BackOff()
+		attempt int
+		err     error
+	)
+	for attempt = 1; ; attempt++ {
+		err = s.invoke(func() error { return r.fn(serverlessCtx) })
+		if err == nil || errors.Is(err, ErrHandlerPanic) {
+			break
+		}
+		delay, ok := policy.NextDelay(b, attempt, err)
+		if !ok {
+			break
+		}
+		s.client.Logger.Warn("sfn handler failed, retrying", "err", err, "tag", dataFrame.Tag, "attempt", attempt, "delay", delay)
+		if !sleep(ctx, delay) {
+			break
+		}
+	}
+
+	if err != nil {
+		s.client.Logger.Error("sfn handler failed", "err", err, "tag", dataFrame.Tag, "tid", core.GetTIDFromMetadata(md), "attempts", attempt)
+		s.deadLetter(dataFrame, md, err, attempt)
+		return
 	}
+	checkLLMFunctionCall(s.client.Logger, serverlessCtx)
+}
+
+// invoke calls the handler, the panic of the handler is recovered and returned as an error wrapping ErrHandlerPanic.
+func (s *streamFunction) invoke(fn func() error) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+
+			s.client.Logger.Error("sfn handler panic", "err", e, "stack", string(buf))
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, e)
+		}
+	}()
+	return fn()
+}
+
+// deadLetter writes the data that the handler failed to the dead-letter tag, the metadata carries the error details.
+func (s *streamFunction) deadLetter(dataFrame *frame.DataFrame, md metadata.M, handlerErr error, attempts int) {
+	tag := s.client.DeadLetterTag()
+	if tag == 0 {
+		return
+	}
+
+	md = md.Clone()
+	// the dead letter is handled later by any consumer of the tag, so it carries neither the deadline
+	// nor the target of the failed data.
+	delete(md, metadata.DeadlineKey)
+	delete(md, metadata.TargetKey)
+	md.Set(metadata.DeadLetterErrorKey, handlerErr.Error())
+	md.Set(metadata.DeadLetterTagKey, strconv.FormatUint(uint64(dataFrame.Tag), 10))
+	md.Set(metadata.DeadLetterSfnKey, s.name)
+	md.Set(metadata.DeadLetterAttemptsKey, strconv.Itoa(attempts))
+
+	mdBytes, err := md.Encode()
+	if err != nil {
+		s.client.Logger.Error("sfn encode metadata error", "err", err)
+		return
+	}
+	err = s.client.WriteFrame(&frame.DataFrame{Tag: tag, Metadata: mdBytes, Payload: dataFrame.Payload})
+	if err != nil {
+		s.client.Logger.Error("sfn failed to write dead-letter data", "err", err, "dead_letter_tag", tag)
+	}
+}
+
+// handlerContext returns the context of the handler, it is done when the sfn is closed,
+// the handler timeout is reached or the deadline in the metadata is exceeded.
+func (s *streamFunction) handlerContext(md metadata.M) (context.Context, context.CancelFunc) {
+	ctx, cancel := s.ctx, context.CancelFunc(func() {})
+	if timeout := s.client.HandlerTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	if deadline, ok := core.GetDeadlineFromMetadata(md); ok {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		cancelTimeout := cancel
+		cancel = func() {
+			cancelDeadline()
+			cancelTimeout()
+		}
+	}
+	return ctx, cancel
+}
+
+// expired reports whether the data frame should be skipped, because the deadline is exceeded or the sfn is closed.
+func (s *streamFunction) expired(dataFrame *frame.DataFrame, md metadata.M) bool {
+	if s.ctx.Err() != nil {
+		s.client.Logger.Debug("sfn is closed, the data frame is skipped", "tag", dataFrame.Tag)
+		return true
+	}
+	if deadline, ok := core.GetDeadlineFromMetadata(md); ok && !time.Now().Before(deadline) {
+		s.client.Logger.Warn("the deadline of data frame is exceeded, the data frame is skipped",
+			"tag", dataFrame.Tag, "tid", core.GetTIDFromMetadata(md), "deadline", deadline)
+		return true
+	}
+	return false
+}
+
+// route is the handler registered by Handle.
+type route struct {
+	fn   core.AsyncHandlerE
+	opts handlerOptions
+	pool jobQueue // the workers of the handler, nil means a goroutine per data frame
+}
+
+// handlerSerializerKey is the context key of the serializer set by WithHandlerSerializer.
+type handlerSerializerKey struct{}
+
+// handlerSerializer returns the serializer of the handler set by WithHandlerSerializer, it is nil if not set.
+func handlerSerializer(ctx yserverless.Context) serializer.Serializer {
+	s, _ := ctx.Context().Value(handlerSerializerKey{}).(serializer.Serializer)
+	return s
 }
 
 // SetErrorHandler set the error handler function when server error occurs
@@ -276,6 +742,19 @@ func (s *streamFunction) Init(fn func() error) error {
 	return fn()
 }
 
+// sleep waits for the duration, it returns false if the context is done before.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func checkLLMFunctionCall(logger *slog.Logger, serverlessCtx yserverless.Context) {
 	fc, err := serverlessCtx.LLMFunctionCall()
 	if err != nil {
@@ -0,0 +1,209 @@
+package yomo
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// handlerPool runs the handlers of the stream function on a fixed number of workers.
+// Without serial keys the workers share one queue, with serial keys every worker has its own queue,
+// and the jobs of one key always go to the same worker, so they run in order.
+type handlerPool struct {
+	queues []chan func()
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+func newHandlerPool(workers, queueSize int, serial bool) *handlerPool {
+	if queueSize <= 0 {
+		queueSize = workers
+	}
+
+	p := &handlerPool{done: make(chan struct{})}
+
+	if serial {
+		size := queueSize / workers
+		if size < 1 {
+			size = 1
+		}
+		for i := 0; i < workers; i++ {
+			q := make(chan func(), size)
+			p.queues = append(p.queues, q)
+			p.start(q)
+		}
+	} else {
+		q := make(chan func(), queueSize)
+		p.queues = append(p.queues, q)
+		for i := 0; i < workers; i++ {
+			p.start(q)
+		}
+	}
+
+	return p
+}
+
+func (p *handlerPool) start(q chan func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			select {
+			case job := <-q:
+				job()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// submit queues the job, it blocks while the queue is full, and returns false if the pool is closed.
+func (p *handlerPool) submit(key string, job func()) bool {
+	q := p.queues[0]
+	if len(p.queues) > 1 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		q = p.queues[h.Sum32()%uint32(len(p.queues))]
+	}
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	select {
+	case q <- job:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// close stops the workers after their running jobs, the queued jobs are discarded.
+func (p *handlerPool) close() {
+	p.once.Do(func() { close(p.done) })
+	p.wg.Wait()
+}
+
+// jobQueue runs the jobs of the handlers, it is a handlerPool or a dispatcher in front of it.
+type jobQueue interface {
+	submit(key string, job func()) bool
+	close()
+}
+
+// dispatcher queues the jobs in its own bounded queue, and submits them to its pool in order in its own goroutine,
+// so the jobs of one pool waiting for the workers do not block the jobs of the other pools until the queue is full.
+type dispatcher struct {
+	pool   *handlerPool
+	queue  chan dispatchJob
+	closed chan struct{}
+	once   sync.Once
+	done   chan struct{}
+}
+
+type dispatchJob struct {
+	key string
+	job func()
+}
+
+func newDispatcher(pool *handlerPool, queueSize int) *dispatcher {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	d := &dispatcher{
+		pool:   pool,
+		queue:  make(chan dispatchJob, queueSize),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+
+	return d
+}
+
+func (d *dispatcher) run() {
+	defer close(d.done)
+	for {
+		select {
+		case j := <-d.queue:
+			// it blocks while the queue of the pool is full.
+			if !d.pool.submit(j.key, j.job) {
+				return
+			}
+		case <-d.closed:
+			return
+		}
+	}
+}
+
+// submit queues the job, it blocks while the queue is full, and returns false if the dispatcher is closed.
+func (d *dispatcher) submit(key string, job func()) bool {
+	select {
+	case <-d.closed:
+		return false
+	default:
+	}
+
+	select {
+	case d.queue <- dispatchJob{key: key, job: job}:
+		return true
+	case <-d.closed:
+		return false
+	}
+}
+
+// close stops dispatching and closes the pool, the queued jobs are discarded.
+func (d *dispatcher) close() {
+	d.once.Do(func() { close(d.closed) })
+	d.pool.close()
+	<-d.done
+}
+
+// inflight counts the data frames being handled or waiting for a worker and the running cron handlers,
+// it stops accepting new work once it is closed, so the work can be drained.
+type inflight struct {
+	mu     sync.Mutex
+	n      int
+	closed bool
+	idle   chan struct{}
+}
+
+// add adds a work, it returns false if the inflight is closed.
+func (f *inflight) add() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return false
+	}
+	f.n++
+	return true
+}
+
+// done finishes a work added before.
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.n--
+	if f.closed && f.n == 0 {
+		close(f.idle)
+	}
+}
+
+// close stops accepting new work, the returned channel is closed after the added work is done.
+func (f *inflight) close() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		f.idle = make(chan struct{})
+		if f.n == 0 {
+			close(f.idle)
+		}
+	}
+	return f.idle
+}
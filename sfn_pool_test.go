@@ -0,0 +1,155 @@
+package yomo
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerPool(t *testing.T) {
+	t.Run("bounded", func(t *testing.T) {
+		pool := newHandlerPool(4, 8, false)
+		defer pool.close()
+
+		var (
+			running atomic.Int32
+			max     atomic.Int32
+			wg      sync.WaitGroup
+		)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			ok := pool.submit("", func() {
+				defer wg.Done()
+				n := running.Add(1)
+				for {
+					m := max.Load()
+					if n <= m || max.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+			})
+			assert.True(t, ok)
+		}
+		wg.Wait()
+
+		assert.LessOrEqual(t, max.Load(), int32(4))
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+		pool := newHandlerPool(1, 1, false)
+		defer pool.close()
+
+		release := make(chan struct{})
+		assert.True(t, pool.submit("", func() { <-release }))
+		// wait for the worker taking the first job.
+		time.Sleep(50 * time.Millisecond)
+		assert.True(t, pool.submit("", func() {}))
+
+		submitted := make(chan struct{})
+		go func() {
+			pool.submit("", func() {})
+			close(submitted)
+		}()
+
+		select {
+		case <-submitted:
+			t.Fatal("submit should block while the queue is full")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(release)
+		select {
+		case <-submitted:
+		case <-time.After(time.Second):
+			t.Fatal("submit should continue after the queue is drained")
+		}
+	})
+
+	t.Run("serial", func(t *testing.T) {
+		pool := newHandlerPool(4, 16, true)
+		defer pool.close()
+
+		var (
+			mu     sync.Mutex
+			result = map[string][]int{}
+			wg     sync.WaitGroup
+		)
+		for i := 0; i < 200; i++ {
+			key := "device-" + strconv.Itoa(i%5)
+			seq := i
+			wg.Add(1)
+			pool.submit(key, func() {
+				defer wg.Done()
+				mu.Lock()
+				result[key] = append(result[key], seq)
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+
+		assert.Len(t, result, 5)
+		for key, seqs := range result {
+			assert.Len(t, seqs, 40, key)
+			assert.IsIncreasing(t, seqs, key)
+		}
+	})
+
+	t.Run("dispatcher", func(t *testing.T) {
+		d := newDispatcher(newHandlerPool(1, 1, false), 1)
+		defer d.close()
+
+		release := make(chan struct{})
+		var submitted, done atomic.Int32
+		go func() {
+			for i := 0; i < 10; i++ {
+				d.submit("", func() {
+					<-release
+					done.Add(1)
+				})
+				submitted.Add(1)
+			}
+		}()
+
+		// the running job, the queue of the pool, the dispatching job and the queue of the dispatcher.
+		assert.Eventually(t, func() bool { return submitted.Load() == 4 }, time.Second, time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(4), submitted.Load(), "it blocks while the queue is full")
+
+		close(release)
+		assert.Eventually(t, func() bool { return done.Load() == 10 }, time.Second, time.Millisecond)
+
+		d.close()
+		assert.False(t, d.submit("", func() {}))
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		pool := newHandlerPool(1, 1, false)
+		pool.close()
+
+		assert.False(t, pool.submit("", func() {}))
+	})
+}
+
+func TestInflight(t *testing.T) {
+	var f inflight
+	assert.True(t, f.add())
+
+	idle := f.close()
+	assert.False(t, f.add(), "no work should be accepted after closing")
+
+	select {
+	case <-idle:
+		t.Fatal("the inflight should not be idle before the work is done")
+	default:
+	}
+
+	f.done()
+	<-idle
+	<-f.close()
+}
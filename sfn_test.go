@@ -1,13 +1,22 @@
 package yomo
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/yomorun/yomo/core"
 	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
 	"github.com/yomorun/yomo/core/ylog"
+	"github.com/yomorun/yomo/pkg/serializer"
 	"github.com/yomorun/yomo/serverless"
 )
 
@@ -25,6 +34,9 @@ func TestStreamFunction(t *testing.T) {
 		WithSfnLogger(ylog.Default()),
 		WithSfnQuicConfig(core.DefaultClientQuicConfig),
 		WithSfnTLSConfig(nil),
+		WithSfnConcurrency(4),
+		WithSfnQueueSize(16),
+		WithSfnSerialKey(func(ctx serverless.Context) string { return string(ctx.Data()) }),
 	)
 	sfn.SetObserveDataTags(0x21)
 
@@ -40,6 +52,7 @@ func TestStreamFunction(t *testing.T) {
 		t.Logf("unittest sfn receive <- (%d)", len(ctx.Data()))
 		assert.Equal(t, uint32(0x21), ctx.Tag())
 		assert.Equal(t, []byte("test"), ctx.Data())
+		assert.NoError(t, ctx.State().Put("last", ctx.Data(), 0))
 
 		err := ctx.WriteWithTarget(0x22, []byte("message from sfn"), mockTargetString)
 		assert.Nil(t, err)
@@ -136,6 +149,7 @@ func TestSfnCron(t *testing.T) {
 	// set cron handler
 	sfn.SetCronHandler("@every 200ms", func(ctx serverless.CronContext) {
 		t.Log("unittest cron sfn, time reached")
+		assert.NoError(t, ctx.Context().Err())
 		ctx.Write(0x22, []byte("message from cron sfn"))
 		ctx.WriteWithTarget(0x22, []byte("message from cron sfn"), mockTargetString)
 	})
@@ -145,3 +159,550 @@ func TestSfnCron(t *testing.T) {
 
 	sfn.Wait()
 }
+
+func TestSfnClusterCron(t *testing.T) {
+	t.Parallel()
+
+	const zipperAddr = "127.0.0.1:19982"
+	server := core.NewServer("zipper")
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	var triggered [2]atomic.Int32
+	sfns := make([]StreamFunction, 2)
+	for i := range sfns {
+		n := &triggered[i]
+		sfns[i] = NewStreamFunction("sfn-cluster-cron", zipperAddr, WithSfnClusterCron())
+		sfns[i].SetCronHandler("@every 1s", func(ctx serverless.CronContext) {
+			n.Add(1)
+		})
+		assert.NoError(t, sfns[i].Connect())
+		defer sfns[i].Close()
+	}
+
+	// only the first instance is triggered.
+	time.Sleep(2500 * time.Millisecond)
+	assert.GreaterOrEqual(t, triggered[0].Load(), int32(1))
+	assert.Equal(t, int32(0), triggered[1].Load())
+
+	// the second instance takes over after the first one is closed.
+	sfns[0].Close()
+	assert.Eventually(t, func() bool { return triggered[1].Load() > 0 }, 3*time.Second, 100*time.Millisecond)
+}
+
+func TestSfnDeadline(t *testing.T) {
+	t.Parallel()
+
+	sfn := NewStreamFunction("sfn-deadline", "localhost:9000", WithSfnHandlerTimeout(time.Minute))
+
+	deadlines := make(chan time.Time, 1)
+	sfn.SetHandler(func(ctx serverless.Context) {
+		deadline, ok := ctx.Context().Deadline()
+		assert.True(t, ok)
+		deadlines <- deadline
+	})
+	s := sfn.(*streamFunction)
+
+	newFrame := func(deadline time.Time) *frame.DataFrame {
+		md := core.NewMetadata("source", "tid")
+		if !deadline.IsZero() {
+			core.SetMetadataDeadline(md, deadline)
+		}
+		mdBytes, _ := md.Encode()
+		return &frame.DataFrame{Tag: 0x21, Metadata: mdBytes, Payload: []byte("test")}
+	}
+
+	t.Run("handler timeout", func(t *testing.T) {
+		s.onDataFrame(newFrame(time.Time{}))
+		deadline := <-deadlines
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+	})
+
+	t.Run("deadline in metadata", func(t *testing.T) {
+		want := time.UnixMilli(time.Now().Add(time.Second).UnixMilli())
+		s.onDataFrame(newFrame(want))
+		assert.Equal(t, want, <-deadlines)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		s.onDataFrame(newFrame(time.Now().Add(-time.Second)))
+		select {
+		case <-deadlines:
+			t.Fatal("the expired data frame should be skipped")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		ctx, cancel := s.handlerContext(core.NewMetadata("source", "tid"))
+		defer cancel()
+
+		// the sfn is not connected, so only the handler contexts are canceled.
+		s.cancel()
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+		s.onDataFrame(newFrame(time.Time{}))
+		select {
+		case <-deadlines:
+			t.Fatal("the data frame should be skipped after the sfn is closed")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+func TestSfnBatch(t *testing.T) {
+	t.Parallel()
+
+	sfn := NewStreamFunction("sfn-batch", "localhost:9000")
+
+	received := make(chan string, 3)
+	sfn.SetHandler(func(ctx serverless.Context) {
+		_, ok := ctx.Metadata(metadata.BatchKey)
+		assert.False(t, ok)
+		received <- string(ctx.Data())
+	})
+	s := sfn.(*streamFunction)
+
+	md := core.NewMetadata("source", "tid")
+	md.Set(metadata.BatchKey, "3")
+	mdBytes, _ := md.Encode()
+
+	batch := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	s.onDataFrame(&frame.DataFrame{Tag: 0x21, Metadata: mdBytes, Payload: core.EncodeBatch(batch)})
+
+	got := []string{}
+	for i := 0; i < len(batch); i++ {
+		select {
+		case data := <-received:
+			got = append(got, data)
+		case <-time.After(time.Second):
+			t.Fatal("the batch should be unpacked")
+		}
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, got)
+}
+
+func TestSfnHandle(t *testing.T) {
+	t.Parallel()
+
+	sfn := NewStreamFunction("sfn-handle", "localhost:9000", WithSfnConcurrency(2))
+	s := sfn.(*streamFunction)
+
+	received := make(chan string, 1)
+	assert.NoError(t, sfn.Handle(0x31, func(ctx serverless.Context) error {
+		received <- "0x31: " + string(ctx.Data())
+		return nil
+	}, WithHandlerConcurrency(4)))
+	assert.NoError(t, sfn.Handle(0x33, func(ctx serverless.Context) error {
+		v, err := Decode[noise](ctx, nil)
+		received <- fmt.Sprintf("0x33: %v %v", v.Noise, err)
+		return err
+	}, WithHandlerSerializer(serializer.Msgpack)))
+	assert.Error(t, sfn.Handle(0x31, func(ctx serverless.Context) error { return nil }))
+	assert.Error(t, sfn.Handle(0x35, nil))
+
+	newFrame := func(tag uint32, data []byte) *frame.DataFrame {
+		mdBytes, _ := core.NewMetadata("source", "tid").Encode()
+		return &frame.DataFrame{Tag: tag, Metadata: mdBytes, Payload: data}
+	}
+	receive := func() string {
+		select {
+		case data := <-received:
+			return data
+		case <-time.After(time.Second):
+			t.Fatal("the data should be handled")
+			return ""
+		}
+	}
+
+	t.Run("observed tags", func(t *testing.T) {
+		sfn.SetObserveDataTags(0x32, 0x31)
+		assert.Equal(t, []uint32{0x31, 0x32, 0x33}, s.tags())
+	})
+
+	t.Run("without default handler", func(t *testing.T) {
+		// the data of the tag without a handler is discarded rather than blocked.
+		s.onDataFrame(newFrame(0x32, []byte("discarded")))
+		select {
+		case data := <-received:
+			t.Fatalf("the data should be discarded, got %s", data)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	sfn.SetHandler(func(ctx serverless.Context) {
+		received <- "default: " + string(ctx.Data())
+	})
+
+	t.Run("routing", func(t *testing.T) {
+		s.onDataFrame(newFrame(0x31, []byte("a")))
+		assert.Equal(t, "0x31: a", receive())
+
+		s.onDataFrame(newFrame(0x32, []byte("b")))
+		assert.Equal(t, "default: b", receive())
+	})
+
+	t.Run("handler serializer", func(t *testing.T) {
+		data, _ := serializer.Msgpack.Marshal(noise{Noise: 42})
+		s.onDataFrame(newFrame(0x33, data))
+		assert.Equal(t, "0x33: 42 <nil>", receive())
+	})
+
+	t.Run("handler concurrency", func(t *testing.T) {
+		s.startPools()
+		defer func() {
+			s.routes[0x31].pool.close()
+			s.pool.close()
+		}()
+
+		assert.NotNil(t, s.pool)
+		assert.NotSame(t, s.pool, s.routes[0x31].pool)
+		assert.Same(t, s.pool, s.routes[0x33].pool)
+
+		s.onDataFrame(newFrame(0x31, []byte("c")))
+		assert.Equal(t, "0x31: c", receive())
+
+		// the data of the handler with its own workers are not blocked by the busy shared workers.
+		for i := 0; i < 4; i++ {
+			s.onDataFrame(newFrame(0x32, []byte("blocked")))
+		}
+		s.onDataFrame(newFrame(0x31, []byte("d")))
+
+		got := []string{}
+		for i := 0; i < 5; i++ {
+			got = append(got, receive())
+		}
+		assert.Contains(t, got, "0x31: d")
+	})
+
+	t.Run("after connect", func(t *testing.T) {
+		s.connected.Store(true)
+		assert.Error(t, sfn.Handle(0x36, func(ctx serverless.Context) error { return nil }))
+	})
+}
+
+func TestSfnHandlerBackpressure(t *testing.T) {
+	t.Parallel()
+
+	sfn := NewStreamFunction("sfn-backpressure", "localhost:9000")
+	s := sfn.(*streamFunction)
+
+	release := make(chan struct{})
+	var handled atomic.Int32
+	assert.NoError(t, sfn.Handle(0x31, func(ctx serverless.Context) error {
+		<-release
+		handled.Add(1)
+		return nil
+	}, WithHandlerConcurrency(1), WithHandlerQueueSize(1)))
+
+	s.startPools()
+	defer s.routes[0x31].pool.close()
+
+	mdBytes, _ := core.NewMetadata("source", "tid").Encode()
+	var read atomic.Int32
+	go func() {
+		for i := 0; i < 10; i++ {
+			s.onDataFrame(&frame.DataFrame{Tag: 0x31, Metadata: mdBytes, Payload: []byte("slow")})
+			read.Add(1)
+		}
+	}()
+
+	// the slow handler stops reading once its queues are full, rather than queuing the data without a bound.
+	assert.Eventually(t, func() bool { return read.Load() == 4 }, time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(4), read.Load())
+
+	close(release)
+	assert.Eventually(t, func() bool { return handled.Load() == 10 }, time.Second, time.Millisecond)
+}
+
+func TestSfnSerialKeyWithoutConcurrency(t *testing.T) {
+	t.Parallel()
+
+	sfn := NewStreamFunction("sfn-serial", "localhost:9000", WithSfnSerialKey(func(ctx serverless.Context) string { return "key" }))
+	s := sfn.(*streamFunction)
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	sfn.SetHandler(func(ctx serverless.Context) {
+		// the later data would overtake the earlier data if they ran concurrently.
+		if string(ctx.Data()) == "0" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mu.Lock()
+		got = append(got, string(ctx.Data()))
+		mu.Unlock()
+	})
+
+	s.startPools()
+	defer s.pool.close()
+	assert.NotNil(t, s.pool)
+
+	mdBytes, _ := core.NewMetadata("source", "tid").Encode()
+	for i := 0; i < 3; i++ {
+		s.onDataFrame(&frame.DataFrame{Tag: 0x31, Metadata: mdBytes, Payload: []byte(strconv.Itoa(i))})
+	}
+	<-s.inflight.close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"0", "1", "2"}, got)
+}
+
+func TestSfnShutdown(t *testing.T) {
+	t.Parallel()
+
+	const zipperAddr = "127.0.0.1:19983"
+	server := core.NewServer("zipper")
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	results := make(chan string, 1)
+	sink := NewStreamFunction("sfn-shutdown-sink", zipperAddr)
+	sink.SetObserveDataTags(0x52)
+	sink.SetHandler(func(ctx serverless.Context) { results <- string(ctx.Data()) })
+	assert.NoError(t, sink.Connect())
+	defer sink.Close()
+
+	started := make(chan struct{})
+	sfn := NewStreamFunction("sfn-shutdown", zipperAddr)
+	sfn.SetObserveDataTags(0x51)
+	sfn.SetHandler(func(ctx serverless.Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.Write(0x52, append([]byte("done: "), ctx.Data()...))
+	})
+	assert.NoError(t, sfn.Connect())
+
+	source := NewSource("source-shutdown", zipperAddr)
+	assert.NoError(t, source.Connect())
+	defer source.Close()
+
+	assert.NoError(t, source.Write(0x51, []byte("in-flight")))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	assert.NoError(t, sfn.Shutdown(ctx))
+	assert.Equal(t, core.StateClosed, sfn.ConnectionState())
+
+	select {
+	case result := <-results:
+		assert.Equal(t, "done: in-flight", result)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the write of the in-flight handler should be flushed")
+	}
+}
+
+func TestSfnShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	const zipperAddr = "127.0.0.1:19974"
+	server := core.NewServer("zipper")
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	sfn := NewStreamFunction("sfn-shutdown-timeout", zipperAddr)
+	sfn.SetObserveDataTags(0x53)
+	sfn.SetHandler(func(ctx serverless.Context) {
+		close(started)
+		<-release
+	})
+	assert.NoError(t, sfn.Connect())
+	defer close(release)
+
+	source := NewSource("source-shutdown-timeout", zipperAddr)
+	assert.NoError(t, source.Connect())
+	defer source.Close()
+
+	assert.NoError(t, source.Write(0x53, []byte("blocked")))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sfn.Shutdown(ctx), context.DeadlineExceeded)
+
+	// the work arriving late is rejected instead of racing with the drain.
+	assert.False(t, sfn.(*streamFunction).inflight.add())
+}
+
+func TestSfnRetryAndDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	// a zipper without mesh, so the data frames are not slowed down by the downstreams.
+	const zipperAddr = "127.0.0.1:19986"
+	server := core.NewServer("zipper")
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	deadLetters := make(chan map[string]string, 2)
+	sink := NewStreamFunction("sfn-dead-letter-sink", zipperAddr)
+	sink.SetObserveDataTags(0x42)
+	sink.SetHandler(func(ctx serverless.Context) {
+		md := map[string]string{"data": string(ctx.Data())}
+		for _, k := range []string{metadata.DeadLetterErrorKey, metadata.DeadLetterTagKey, metadata.DeadLetterSfnKey, metadata.DeadLetterAttemptsKey} {
+			md[k], _ = ctx.Metadata(k)
+		}
+		deadLetters <- md
+	})
+	assert.NoError(t, sink.Connect())
+	defer sink.Close()
+
+	var (
+		mu       sync.Mutex
+		attempts = map[string]int{}
+		done     = make(chan string, 3)
+	)
+	sfn := NewStreamFunction(
+		"sfn-retry",
+		zipperAddr,
+		WithSfnRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			NewBackOff:  func() backoff.BackOff { return &backoff.ZeroBackOff{} },
+		}),
+		WithSfnDeadLetterTag(0x42),
+	)
+	sfn.SetObserveDataTags(0x41)
+	sfn.SetHandlerE(func(ctx serverless.Context) error {
+		data := string(ctx.Data())
+
+		mu.Lock()
+		attempts[data]++
+		n := attempts[data]
+		mu.Unlock()
+
+		switch data {
+		case "flaky":
+			if n < 2 {
+				return errors.New("flaky error")
+			}
+			done <- data
+			return nil
+		case "poison":
+			panic("poison data")
+		default:
+			return errors.New("always error")
+		}
+	})
+	assert.NoError(t, sfn.Connect())
+	defer sfn.Close()
+
+	source := NewSource("source-retry", zipperAddr)
+	assert.NoError(t, source.Connect())
+	defer source.Close()
+
+	for _, data := range []string{"flaky", "poison", "fail"} {
+		assert.NoError(t, source.Write(0x41, []byte(data)))
+	}
+
+	select {
+	case data := <-done:
+		assert.Equal(t, "flaky", data)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the flaky data should succeed after retrying")
+	}
+
+	got := map[string]map[string]string{}
+	for i := 0; i < 2; i++ {
+		select {
+		case md := <-deadLetters:
+			got[md["data"]] = md
+		case <-time.After(3 * time.Second):
+			t.Fatal("the failed data should be written to the dead-letter tag")
+		}
+	}
+
+	assert.Equal(t, "yomo: sfn handler panic: poison data", got["poison"][metadata.DeadLetterErrorKey])
+	assert.Equal(t, "1", got["poison"][metadata.DeadLetterAttemptsKey])
+	assert.Equal(t, "always error", got["fail"][metadata.DeadLetterErrorKey])
+	assert.Equal(t, "3", got["fail"][metadata.DeadLetterAttemptsKey])
+	assert.Equal(t, "65", got["fail"][metadata.DeadLetterTagKey])
+	assert.Equal(t, "sfn-retry", got["fail"][metadata.DeadLetterSfnKey])
+
+	mu.Lock()
+	assert.Equal(t, map[string]int{"flaky": 2, "poison": 1, "fail": 3}, attempts)
+	mu.Unlock()
+}
+
+func TestSfnDeadLetterExpired(t *testing.T) {
+	t.Parallel()
+
+	const zipperAddr = "127.0.0.1:19980"
+	server := core.NewServer("zipper")
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	// the sink has no wanted target, and it handles the dead letter after the deadline of the data.
+	deadLetters := make(chan string, 1)
+	sink := NewStreamFunction("sfn-dead-letter-expired-sink", zipperAddr)
+	sink.SetObserveDataTags(0x44)
+	sink.SetHandler(func(ctx serverless.Context) {
+		_, ok := ctx.Metadata(metadata.DeadlineKey)
+		assert.False(t, ok)
+		deadLetters <- string(ctx.Data())
+	})
+	assert.NoError(t, sink.Connect())
+	defer sink.Close()
+
+	sfn := NewStreamFunction("sfn-dead-letter-expired", zipperAddr, WithSfnDeadLetterTag(0x44))
+	sfn.SetObserveDataTags(0x43)
+	sfn.SetWantedTarget("worker")
+	sfn.SetHandlerE(func(ctx serverless.Context) error {
+		<-ctx.Context().Done()
+		return ctx.Context().Err()
+	})
+	assert.NoError(t, sfn.Connect())
+	defer sfn.Close()
+
+	source := NewSource("source-dead-letter-expired", zipperAddr, WithSourceFrameTimeout(100*time.Millisecond))
+	assert.NoError(t, source.Connect())
+	defer source.Close()
+
+	assert.NoError(t, source.WriteWithTarget(0x43, []byte("expired"), "worker"))
+
+	select {
+	case data := <-deadLetters:
+		assert.Equal(t, "expired", data)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the expired data should be handled by the dead-letter consumer")
+	}
+}
+
+func TestSfnConcurrencyWrite(t *testing.T) {
+	t.Parallel()
+
+	const zipperAddr = "127.0.0.1:19981"
+	server := core.NewServer("zipper")
+	go server.ListenAndServe(context.TODO(), zipperAddr)
+	defer server.Close()
+
+	var received atomic.Int32
+	sink := NewStreamFunction("sfn-concurrency-sink", zipperAddr)
+	sink.SetObserveDataTags(0x62)
+	sink.SetHandler(func(ctx serverless.Context) { received.Add(1) })
+	assert.NoError(t, sink.Connect())
+	defer sink.Close()
+
+	// the handlers write while the queue is full, the writes must not wait for the queue.
+	sfn := NewStreamFunction("sfn-concurrency", zipperAddr, WithSfnConcurrency(1), WithSfnQueueSize(1))
+	sfn.SetObserveDataTags(0x61)
+	sfn.SetHandler(func(ctx serverless.Context) {
+		assert.NoError(t, ctx.Write(0x62, ctx.Data()))
+	})
+	assert.NoError(t, sfn.Connect())
+	defer sfn.Close()
+
+	source := NewSource("source-concurrency", zipperAddr)
+	assert.NoError(t, source.Connect())
+	defer source.Close()
+
+	const n = 300
+	for i := 0; i < n; i++ {
+		assert.NoError(t, source.Write(0x61, []byte("data")))
+	}
+	assert.Eventually(t, func() bool { return received.Load() == n }, 5*time.Second, 50*time.Millisecond)
+}
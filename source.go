@@ -2,9 +2,12 @@ package yomo
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/yomorun/yomo/core"
 	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/metadata"
 	"github.com/yomorun/yomo/pkg/id"
 )
 
@@ -20,6 +23,8 @@ type Source interface {
 	WriteWithTarget(tag uint32, data []byte, target string) error
 	// SetErrorHandler set the error handler function when server error occurs
 	SetErrorHandler(fn func(err error))
+	// ConnectionState returns the state of the connection to YoMo-Zipper.
+	ConnectionState() ConnectionState
 }
 
 // YoMo-Source
@@ -27,12 +32,18 @@ type yomoSource struct {
 	name       string
 	zipperAddr string
 	client     *core.Client
+	batcher    *batcher // packs the data into batch data frames, nil means no batching
 }
 
 var _ Source = &yomoSource{}
 
-// NewSource create a yomo-source
+// NewSource create a yomo-source.
+// The zipperAddr can be a comma-separated list of zipper endpoints, the source fails over between them.
 func NewSource(name, zipperAddr string, opts ...SourceOption) Source {
+	return newSource(name, zipperAddr, opts...)
+}
+
+func newSource(name, zipperAddr string, opts ...SourceOption) *yomoSource {
 	clientOpts := make([]core.ClientOption, len(opts))
 	for k, v := range opts {
 		clientOpts[k] = core.ClientOption(v)
@@ -47,15 +58,22 @@ func NewSource(name, zipperAddr string, opts ...SourceOption) Source {
 		"zipper_addr", zipperAddr,
 	)
 
-	return &yomoSource{
+	s := &yomoSource{
 		name:       name,
 		zipperAddr: zipperAddr,
 		client:     client,
 	}
+	if size, linger := client.Batch(); size > 0 {
+		s.batcher = newBatcher(size, linger, s.writeBatch, client.Logger)
+	}
+	return s
 }
 
 // Close will close the connection to YoMo-Zipper.
 func (s *yomoSource) Close() error {
+	if s.batcher != nil {
+		s.batcher.close()
+	}
 	_ = s.client.Close()
 	s.client.Logger.Debug("the source is closed")
 	return nil
@@ -71,7 +89,10 @@ func (s *yomoSource) Write(tag uint32, data []byte) error {
 	if err := frame.IsReservedTag(tag); err != nil {
 		return err
 	}
-	md := core.NewMetadata(s.client.ClientID(), id.New())
+	if s.batcher != nil {
+		return s.batcher.add(tag, "", nil, data)
+	}
+	md := s.newMetadata()
 
 	mdBytes, err := md.Encode()
 	// metadata
@@ -92,7 +113,10 @@ func (s *yomoSource) WriteWithTarget(tag uint32, data []byte, target string) err
 	if err := frame.IsReservedTag(tag); err != nil {
 		return err
 	}
-	md := core.NewMetadata(s.client.ClientID(), id.New())
+	if s.batcher != nil {
+		return s.batcher.add(tag, target, nil, data)
+	}
+	md := s.newMetadata()
 	if target != "" {
 		core.SetMetadataTarget(md, target)
 	}
@@ -110,7 +134,77 @@ func (s *yomoSource) WriteWithTarget(tag uint32, data []byte, target string) err
 	return s.client.WriteFrame(f)
 }
 
+// writeWithMetadata writes data with specified tag and target, the metadata is added to the metadata of the data frame.
+func (s *yomoSource) writeWithMetadata(tag uint32, data []byte, target string, kv map[string]string) error {
+	if err := frame.IsReservedTag(tag); err != nil {
+		return err
+	}
+	if s.batcher != nil {
+		return s.batcher.add(tag, target, kv, data)
+	}
+	md := s.newMetadata()
+	if target != "" {
+		core.SetMetadataTarget(md, target)
+	}
+	for k, v := range kv {
+		md.Set(k, v)
+	}
+
+	mdBytes, err := md.Encode()
+	if err != nil {
+		return err
+	}
+	f := &frame.DataFrame{
+		Tag:      tag,
+		Metadata: mdBytes,
+		Payload:  data,
+	}
+	s.client.Logger.Debug("source write with metadata", "tag", tag, "dataLen", len(data), "target", target)
+	return s.client.WriteFrame(f)
+}
+
+// writeBatch writes the batch data frame, the deadline of the batch starts from the time when the first data is packed.
+func (s *yomoSource) writeBatch(b *batch) error {
+	md := s.newMetadata()
+	if timeout := s.client.FrameTimeout(); timeout > 0 {
+		core.SetMetadataDeadline(md, b.created.Add(timeout))
+	}
+	if b.target != "" {
+		core.SetMetadataTarget(md, b.target)
+	}
+	for k, v := range b.kv {
+		md.Set(k, v)
+	}
+	md.Set(metadata.BatchKey, strconv.Itoa(len(b.data)))
+
+	mdBytes, err := md.Encode()
+	if err != nil {
+		return err
+	}
+	f := &frame.DataFrame{
+		Tag:      b.tag,
+		Metadata: mdBytes,
+		Payload:  core.EncodeBatch(b.data),
+	}
+	s.client.Logger.Debug("source write batch", "tag", b.tag, "batchLen", len(b.data), "dataLen", len(f.Payload))
+	return s.client.WriteFrame(f)
+}
+
+// newMetadata returns the metadata of the data frame, it carries the deadline if the frame timeout is set.
+func (s *yomoSource) newMetadata() metadata.M {
+	md := core.NewMetadata(s.client.ClientID(), id.New())
+	if timeout := s.client.FrameTimeout(); timeout > 0 {
+		core.SetMetadataDeadline(md, time.Now().Add(timeout))
+	}
+	return md
+}
+
 // SetErrorHandler set the error handler function when server error occurs
 func (s *yomoSource) SetErrorHandler(fn func(err error)) {
 	s.client.SetErrorHandler(fn)
 }
+
+// ConnectionState returns the state of the connection to YoMo-Zipper.
+func (s *yomoSource) ConnectionState() ConnectionState {
+	return s.client.ConnectionState()
+}
@@ -0,0 +1,115 @@
+package yomo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var errSourceClosed = errors.New("yomo: the source is closed")
+
+// batch is the data packed into one data frame, the data of a batch have the same tag, target and metadata.
+type batch struct {
+	tag     uint32
+	target  string
+	kv      map[string]string
+	data    [][]byte
+	size    int
+	created time.Time
+	timer   *time.Timer
+}
+
+// batchKey identifies the batch that the data is packed into.
+type batchKey struct {
+	tag    uint32
+	target string
+	kv     string
+}
+
+// batcher packs the data written by the source into batches, a batch is written when its size is reached,
+// or the linger time passes since it is created. The batches are written in the order they are filled.
+type batcher struct {
+	size    int
+	linger  time.Duration
+	write   func(*batch) error
+	logger  *slog.Logger
+	mu      sync.Mutex // it is held while writing, so the batches of a key keep in order
+	batches map[batchKey]*batch
+	closed  bool
+}
+
+func newBatcher(size int, linger time.Duration, write func(*batch) error, logger *slog.Logger) *batcher {
+	return &batcher{
+		size:    size,
+		linger:  linger,
+		write:   write,
+		logger:  logger,
+		batches: make(map[batchKey]*batch),
+	}
+}
+
+// add packs the data into the batch, the batch is written if its size is reached.
+func (b *batcher) add(tag uint32, target string, kv map[string]string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errSourceClosed
+	}
+
+	key := batchKey{tag: tag, target: target}
+	if len(kv) > 0 {
+		key.kv = fmt.Sprint(kv) // the map is printed in key-sorted order.
+	}
+
+	bt, ok := b.batches[key]
+	if !ok {
+		bt = &batch{tag: tag, target: target, kv: kv, created: time.Now()}
+		bt.timer = time.AfterFunc(b.linger, func() { b.expire(key, bt) })
+		b.batches[key] = bt
+	}
+	// the data is retained until the batch is written, so it is copied.
+	bt.data = append(bt.data, bytes.Clone(data))
+	bt.size += len(data)
+
+	if bt.size < b.size {
+		return nil
+	}
+	return b.flush(key, bt)
+}
+
+// expire writes the batch after the linger time, unless it has been written because of its size.
+func (b *batcher) expire(key batchKey, bt *batch) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.batches[key] != bt {
+		return
+	}
+	if err := b.flush(key, bt); err != nil {
+		b.logger.Error("source failed to write batch", "err", err, "tag", bt.tag, "batchLen", len(bt.data))
+	}
+}
+
+// flush writes the batch and removes it, it must be called with the lock held.
+func (b *batcher) flush(key batchKey, bt *batch) error {
+	bt.timer.Stop()
+	delete(b.batches, key)
+	return b.write(bt)
+}
+
+// close writes all the batches, the data added after closing is rejected.
+func (b *batcher) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for key, bt := range b.batches {
+		if err := b.flush(key, bt); err != nil {
+			b.logger.Error("source failed to write batch", "err", err, "tag", bt.tag, "batchLen", len(bt.data))
+		}
+	}
+}
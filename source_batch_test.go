@@ -0,0 +1,64 @@
+package yomo
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/ylog"
+)
+
+func TestBatcher(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		written []*batch
+	)
+	b := newBatcher(10, 50*time.Millisecond, func(bt *batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		written = append(written, bt)
+		return nil
+	}, ylog.Default())
+
+	batches := func() []*batch {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]*batch(nil), written...)
+	}
+
+	t.Run("size", func(t *testing.T) {
+		assert.NoError(t, b.add(0x21, "", nil, []byte("hello")))
+		assert.Empty(t, batches())
+
+		assert.NoError(t, b.add(0x21, "", nil, []byte("world")))
+		got := batches()
+		assert.Len(t, got, 1)
+		assert.Equal(t, [][]byte{[]byte("hello"), []byte("world")}, got[0].data)
+	})
+
+	t.Run("linger", func(t *testing.T) {
+		assert.NoError(t, b.add(0x22, "target", nil, []byte("a")))
+		assert.NoError(t, b.add(0x22, "", nil, []byte("b")))
+		assert.NoError(t, b.add(0x22, "", map[string]string{"serializer": "json"}, []byte("c")))
+
+		time.Sleep(150 * time.Millisecond)
+		got := batches()[1:]
+		assert.Len(t, got, 3)
+		for _, bt := range got {
+			assert.Len(t, bt.data, 1)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		assert.NoError(t, b.add(0x23, "", nil, []byte("a")))
+		b.close()
+
+		got := batches()
+		assert.Len(t, got, 5)
+		assert.Equal(t, uint32(0x23), got[4].tag)
+		assert.ErrorIs(t, b.add(0x23, "", nil, []byte("b")), errSourceClosed)
+	})
+}
@@ -0,0 +1,158 @@
+package yomo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/pkg/serializer"
+	"github.com/yomorun/yomo/serverless"
+)
+
+// ErrDecode is returned when the typed data cannot be decoded, e.g. the data is encoded by another serializer.
+// It is not worth retrying, use it in `RetryPolicy.Retryable` to send the data to the dead-letter tag at once.
+var ErrDecode = errors.New("yomo: cannot decode the data")
+
+// TypedSource is the Source that writes the typed data, the data is encoded by the serializer,
+// the name of the serializer is carried in the metadata, so the stream functions decode the data by it.
+type TypedSource[T any] interface {
+	// Close will close the connection to YoMo-Zipper.
+	Close() error
+	// Connect to YoMo-Zipper.
+	Connect() error
+	// Write encodes the value and writes it to directed downstream.
+	Write(tag uint32, v T) error
+	// WriteWithTarget encodes the value and writes it to sfn instance with specified target.
+	WriteWithTarget(tag uint32, v T, target string) error
+	// SetErrorHandler set the error handler function when server error occurs
+	SetErrorHandler(fn func(err error))
+	// ConnectionState returns the state of the connection to YoMo-Zipper.
+	ConnectionState() ConnectionState
+}
+
+type typedSource[T any] struct {
+	source     *yomoSource
+	serializer serializer.Serializer
+}
+
+var _ TypedSource[any] = &typedSource[any]{}
+
+// NewTypedSource create a yomo-source that writes the values of T encoded by the serializer,
+// the serializer is serializer.JSON if it is nil.
+func NewTypedSource[T any](name, zipperAddr string, s serializer.Serializer, opts ...SourceOption) TypedSource[T] {
+	if s == nil {
+		s = serializer.JSON
+	}
+	return &typedSource[T]{
+		source:     newSource(name, zipperAddr, opts...),
+		serializer: s,
+	}
+}
+
+// Close will close the connection to YoMo-Zipper.
+func (s *typedSource[T]) Close() error {
+	return s.source.Close()
+}
+
+// Connect to YoMo-Zipper.
+func (s *typedSource[T]) Connect() error {
+	return s.source.Connect()
+}
+
+// Write encodes the value and writes it with specified tag.
+func (s *typedSource[T]) Write(tag uint32, v T) error {
+	return s.WriteWithTarget(tag, v, "")
+}
+
+// WriteWithTarget encodes the value and writes it with specified tag and target.
+func (s *typedSource[T]) WriteWithTarget(tag uint32, v T, target string) error {
+	data, err := s.serializer.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.source.writeWithMetadata(tag, data, target, map[string]string{metadata.SerializerKey: s.serializer.Name()})
+}
+
+// SetErrorHandler set the error handler function when server error occurs
+func (s *typedSource[T]) SetErrorHandler(fn func(err error)) {
+	s.source.SetErrorHandler(fn)
+}
+
+// ConnectionState returns the state of the connection to YoMo-Zipper.
+func (s *typedSource[T]) ConnectionState() ConnectionState {
+	return s.source.ConnectionState()
+}
+
+// Decode decodes the incoming data to T by the serializer named in the metadata, the data without
+// the serializer name is decoded by s. If s is nil, it is the serializer set by WithHandlerSerializer, or serializer.JSON.
+// When s is not nil, the data must be encoded by s. The returned error wraps ErrDecode.
+func Decode[T any](ctx serverless.Context, s serializer.Serializer) (T, error) {
+	var v T
+	s, err := resolveSerializer(ctx, s)
+	if err != nil {
+		return v, err
+	}
+	if err := s.Unmarshal(ctx.Data(), &v); err != nil {
+		return v, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return v, nil
+}
+
+// WriteTyped encodes the value by the serializer and writes it with the serializer name in the metadata,
+// the serializer is the one set by WithHandlerSerializer, or serializer.JSON if it is nil.
+func WriteTyped[T any](ctx serverless.Context, tag uint32, v T, s serializer.Serializer) error {
+	if s == nil {
+		s = handlerSerializer(ctx)
+	}
+	if s == nil {
+		s = serializer.JSON
+	}
+	data, err := s.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ctx.WriteWithMetadata(tag, data, map[string]string{metadata.SerializerKey: s.Name()})
+}
+
+// TypedHandler is the sfn handler of the typed data, the returned value is written to the tag of the handler.
+//
+//	sfn.SetHandlerE(yomo.TypedHandler[Noise, Alert](func(ctx serverless.Context, in Noise) (Alert, error) {
+//		return Alert{Level: in.Noise / 10}, nil
+//	}).Handler(0x34, serializer.Msgpack))
+type TypedHandler[In, Out any] func(ctx serverless.Context, in In) (Out, error)
+
+// Handler returns the handler that decodes the incoming data to In as Decode does, calls the typed handler,
+// and writes the result to the tag. The result is encoded by s, or the serializer of the incoming data if s is nil.
+// It can be registered by StreamFunction.Handle with the tag of the incoming data.
+func (fn TypedHandler[In, Out]) Handler(tag uint32, s serializer.Serializer) core.AsyncHandlerE {
+	return func(ctx serverless.Context) error {
+		in, err := Decode[In](ctx, s)
+		if err != nil {
+			return err
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			return err
+		}
+		ws := s
+		if ws == nil {
+			// the serializer has been resolved by Decode.
+			ws, _ = resolveSerializer(ctx, nil)
+		}
+		return WriteTyped(ctx, tag, out, ws)
+	}
+}
+
+// resolveSerializer returns the serializer of the incoming data, the returned error wraps ErrDecode.
+func resolveSerializer(ctx serverless.Context, s serializer.Serializer) (serializer.Serializer, error) {
+	if s == nil {
+		s = handlerSerializer(ctx)
+	}
+	name, _ := ctx.Metadata(metadata.SerializerKey)
+	rs, err := serializer.Resolve(name, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w of tag %#x: %w", ErrDecode, ctx.Tag(), err)
+	}
+	return rs, nil
+}
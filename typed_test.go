@@ -0,0 +1,85 @@
+package yomo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yomorun/yomo/core/metadata"
+	"github.com/yomorun/yomo/pkg/serializer"
+	"github.com/yomorun/yomo/serverless"
+	"github.com/yomorun/yomo/serverless/mock"
+)
+
+type noise struct {
+	Noise float32 `json:"noise" msgpack:"noise"`
+}
+
+type alert struct {
+	Level int `json:"level" msgpack:"level"`
+}
+
+var noiseHandler = TypedHandler[noise, alert](func(ctx serverless.Context, in noise) (alert, error) {
+	if in.Noise < 0 {
+		return alert{}, errors.New("negative noise")
+	}
+	return alert{Level: int(in.Noise / 10)}, nil
+})
+
+func TestTypedHandler(t *testing.T) {
+	t.Run("decode by the serializer in metadata", func(t *testing.T) {
+		data, _ := serializer.Msgpack.Marshal(noise{Noise: 42})
+		ctx := mock.NewMockContext(data, 0x33)
+		ctx.SetMetadata(metadata.SerializerKey, "msgpack")
+
+		assert.NoError(t, noiseHandler.Handler(0x34, nil)(ctx))
+
+		records := ctx.RecordsWritten()
+		assert.Len(t, records, 1)
+		assert.Equal(t, uint32(0x34), records[0].Tag)
+		assert.Equal(t, map[string]string{metadata.SerializerKey: "msgpack"}, records[0].Metadata)
+
+		var out alert
+		assert.NoError(t, serializer.Msgpack.Unmarshal(records[0].Data, &out))
+		assert.Equal(t, alert{Level: 4}, out)
+	})
+
+	t.Run("decode the data without serializer name", func(t *testing.T) {
+		ctx := mock.NewMockContext([]byte(`{"noise":12}`), 0x33)
+
+		assert.NoError(t, noiseHandler.Handler(0x34, nil)(ctx))
+
+		records := ctx.RecordsWritten()
+		assert.Len(t, records, 1)
+		assert.Equal(t, []byte(`{"level":1}`), records[0].Data)
+		assert.Equal(t, map[string]string{metadata.SerializerKey: "json"}, records[0].Metadata)
+	})
+
+	t.Run("serializer mismatch", func(t *testing.T) {
+		ctx := mock.NewMockContext([]byte(`{"noise":12}`), 0x33)
+		ctx.SetMetadata(metadata.SerializerKey, "json")
+
+		err := noiseHandler.Handler(0x34, serializer.Msgpack)(ctx)
+		assert.ErrorIs(t, err, ErrDecode)
+		assert.ErrorIs(t, err, serializer.ErrMismatch)
+		assert.Empty(t, ctx.RecordsWritten())
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		ctx := mock.NewMockContext([]byte(`{"noise":-1}`), 0x33)
+
+		assert.EqualError(t, noiseHandler.Handler(0x34, nil)(ctx), "negative noise")
+		assert.Empty(t, ctx.RecordsWritten())
+	})
+
+	t.Run("bad data", func(t *testing.T) {
+		ctx := mock.NewMockContext([]byte(`{noise}`), 0x33)
+
+		assert.ErrorIs(t, noiseHandler.Handler(0x34, nil)(ctx), ErrDecode)
+	})
+}
+
+func TestTypedSource(t *testing.T) {
+	source := NewTypedSource[noise]("test-typed-source", "localhost:9000", nil)
+	assert.Equal(t, serializer.JSON, source.(*typedSource[noise]).serializer)
+}
@@ -2,12 +2,22 @@ package yomo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 
 	"github.com/yomorun/yomo/core"
+	"github.com/yomorun/yomo/core/acl"
 	"github.com/yomorun/yomo/core/frame"
+	"github.com/yomorun/yomo/core/ylog"
+	"github.com/yomorun/yomo/pkg/admin"
+	"github.com/yomorun/yomo/pkg/auth"
 	"github.com/yomorun/yomo/pkg/config"
+	"github.com/yomorun/yomo/pkg/metrics"
+	"github.com/yomorun/yomo/pkg/ratelimit"
+	"github.com/yomorun/yomo/pkg/recording"
+	"github.com/yomorun/yomo/pkg/telemetry"
 )
 
 // Zipper is the orchestrator of yomo. There are two types of zipper:
@@ -35,11 +45,9 @@ func RunZipper(ctx context.Context, configPath string) error {
 	// listening address.
 	listenAddr := fmt.Sprintf("%s:%d", conf.Host, conf.Port)
 
-	options := []ZipperOption{}
-	if _, ok := conf.Auth["type"]; ok {
-		if tokenString, ok := conf.Auth["token"]; ok {
-			options = append(options, WithAuth("token", tokenString))
-		}
+	options, err := OptionsFromConfig(conf)
+	if err != nil {
+		return err
 	}
 
 	zipper, err := NewZipper(conf.Name, conf.Mesh, options...)
@@ -51,6 +59,64 @@ func RunZipper(ctx context.Context, configPath string) error {
 	return zipper.ListenAndServe(ctx, listenAddr)
 }
 
+// OptionsFromConfig returns the zipper options declared in the config, such as auth, acl, rate limit, metrics, admin, recording and telemetry.
+func OptionsFromConfig(conf config.Config) ([]ZipperOption, error) {
+	options := []ZipperOption{}
+
+	if authType, args := conf.AuthArgs(); authType != "" {
+		options = append(options, WithAuth(authType, args...))
+	}
+
+	if conf.ACL != nil {
+		a, err := acl.New(conf.ACL.Rules...)
+		if err != nil {
+			return nil, err
+		}
+		action, err := acl.ParseAction(string(conf.ACL.Action))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithACL(a, action))
+	}
+
+	if conf.Cron != nil {
+		policy, err := core.ParseCronMissedPolicy(conf.Cron.MissedTicks)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithCronMissedPolicy(policy))
+	}
+
+	if conf.RateLimit != nil {
+		limiter, err := ratelimit.New(*conf.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithRateLimiter(limiter))
+	}
+
+	if conf.Metrics != nil && conf.Metrics.Listen != "" {
+		options = append(options, WithMetrics(conf.Metrics.Listen))
+	}
+
+	if conf.Admin != nil && conf.Admin.Listen != "" {
+		if conf.Admin.Credential == "" {
+			return nil, errors.New("the credential of admin api is required")
+		}
+		options = append(options, WithAdmin(conf.Admin.Listen, conf.Admin.Credential))
+	}
+
+	if conf.Telemetry != nil {
+		options = append(options, WithTelemetry(*conf.Telemetry))
+	}
+
+	if conf.Recording != nil && conf.Recording.File != "" {
+		options = append(options, WithRecording(conf.Recording.File, conf.Recording.Tags...))
+	}
+
+	return options, nil
+}
+
 // NewZipper returns a zipper.
 func NewZipper(name string, meshConfig map[string]config.Mesh, options ...ZipperOption) (Zipper, error) {
 	opts := &zipperOptions{}
@@ -59,7 +125,77 @@ func NewZipper(name string, meshConfig map[string]config.Mesh, options ...Zipper
 		o(opts)
 	}
 
+	var (
+		observers []core.Observer
+		prom      *metrics.Prometheus
+		otelm     *metrics.OTel
+		tel       *telemetry.Telemetry
+	)
+	if opts.metricsAddr != "" {
+		prom = metrics.NewPrometheus()
+		observers = append(observers, prom)
+	}
+	if opts.telemetry != nil {
+		var err error
+		if tel, err = telemetry.New(context.Background(), *opts.telemetry); err != nil {
+			return nil, err
+		}
+		if opts.telemetry.Metrics {
+			if otelm, err = metrics.NewOTel(tel.MeterProvider()); err != nil {
+				return nil, err
+			}
+			observers = append(observers, otelm)
+		}
+		logger := opts.logger
+		if logger == nil {
+			logger = ylog.Default()
+		}
+		opts.serverOption = append(opts.serverOption, core.WithServerLogger(tel.Logger(logger)))
+	}
+	if len(observers) > 0 {
+		opts.serverOption = append(opts.serverOption, core.WithObserver(core.MultiObserver(observers...)))
+	}
+
 	server := core.NewServer(name, opts.serverOption...)
+	for _, c := range opts.closers {
+		server.AddCloser(c)
+	}
+
+	if otelm != nil {
+		otelm.WatchDownstreams(server.DownstreamStatus)
+	}
+
+	if opts.recordFile != "" {
+		recorder, err := recording.NewRecorder(opts.recordFile, opts.recordTags, server.Logger())
+		if err != nil {
+			return nil, err
+		}
+		server.Tap(recorder.Tap)
+		server.AddCloser(recorder)
+		server.Logger().Info("recording data frames", "file", opts.recordFile, "tags", opts.recordTags)
+	}
+
+	if prom != nil {
+		prom.WatchDownstreams(server.DownstreamStatus)
+		server.AddCloser(prom)
+		go func() {
+			server.Logger().Info("serving metrics", "addr", opts.metricsAddr)
+			if err := prom.ListenAndServe(opts.metricsAddr); err != nil {
+				server.Logger().Error("failed to serve metrics", "addr", opts.metricsAddr, "err", err)
+			}
+		}()
+	}
+
+	if opts.adminAddr != "" {
+		adminServer := admin.NewServer(opts.adminAddr, server, opts.adminCred)
+		server.AddCloser(adminServer)
+		go func() {
+			server.Logger().Info("serving admin api", "addr", opts.adminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				server.Logger().Error("failed to serve admin api", "addr", opts.adminAddr, "err", err)
+			}
+		}()
+	}
 
 	// add downstreams to server.
 	for meshName, meshConf := range meshConfig {
@@ -70,6 +206,7 @@ func NewZipper(name string, meshConfig map[string]config.Mesh, options ...Zipper
 
 		clientOptions := []core.ClientOption{
 			core.WithCredential(meshConf.Credential),
+			core.WithCredentialFunc(meshCredentialFunc(meshConf)),
 			core.WithNonBlockWrite(),
 			core.WithReConnect(),
 			core.WithLogger(server.Logger().With("downstream_name", meshName, "downstream_addr", addr)),
@@ -86,12 +223,32 @@ func NewZipper(name string, meshConfig map[string]config.Mesh, options ...Zipper
 		server.AddDownstreamServer(downstream)
 	}
 
+	// the telemetry is closed last, so the logs of the other closers are exported.
+	if tel != nil {
+		server.AddCloser(tel)
+	}
+
 	// watch signal.
 	go waitSignalForShutdownServer(server)
 
 	return server, nil
 }
 
+// meshCredentialFunc returns the function that reads the mesh credential from the credentials file,
+// it returns nil if the credentials file is not set.
+func meshCredentialFunc(meshConf config.Mesh) func() (string, error) {
+	if meshConf.CredentialFile == "" {
+		return nil
+	}
+	return func() (string, error) {
+		token, err := auth.LookupCredential(meshConf.CredentialFile, meshConf.CredentialName)
+		if err != nil {
+			return "", err
+		}
+		return "token:" + token, nil
+	}
+}
+
 func statsToLogger(server *core.Server) {
 	logger := server.Logger()
 
@@ -111,6 +268,7 @@ type downstream struct {
 
 func (d *downstream) Close() error                      { return d.client.Close() }
 func (d *downstream) Connect(ctx context.Context) error { return d.client.Connect(ctx) }
+func (d *downstream) Connected() bool                   { return d.client.Connected() }
 func (d *downstream) ID() string                        { return d.client.ClientID() }
 func (d *downstream) LocalName() string                 { return d.localName }
 func (d *downstream) RemoteName() string                { return d.client.Name() }
@@ -2,6 +2,10 @@ package yomo
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -32,3 +36,66 @@ func TestZipperRun(t *testing.T) {
 	time.Sleep(time.Second)
 	assert.Nil(t, err)
 }
+
+func TestZipperCloseAdmin(t *testing.T) {
+	const adminAddr = "127.0.0.1:19975"
+
+	zipper, err := NewZipper(
+		"zipper-admin",
+		map[string]config.Mesh{},
+		WithZipperLogger(ylog.Default()),
+		WithAdmin(adminAddr, "admin-credential"),
+	)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", adminAddr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 3*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, zipper.Close())
+
+	// the admin port is released after the zipper is closed.
+	assert.Eventually(t, func() bool {
+		ln, err := net.Listen("tcp", adminAddr)
+		if err != nil {
+			return false
+		}
+		ln.Close()
+		return true
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestZipperCloseTelemetry(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths = map[string]int{}
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths[r.URL.Path]++
+	}))
+	defer collector.Close()
+
+	zipper, err := NewZipper(
+		"zipper-telemetry",
+		map[string]config.Mesh{},
+		WithZipperLogger(ylog.Default()),
+		WithTelemetry(config.Telemetry{Endpoint: collector.URL, Metrics: true, Logs: true}),
+	)
+	assert.NoError(t, err)
+	zipper.Logger().Info("closing")
+
+	// the metrics and logs are flushed when the zipper is closed.
+	assert.NoError(t, zipper.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, paths["/v1/metrics"])
+	assert.Equal(t, 1, paths["/v1/logs"])
+}